@@ -4,11 +4,11 @@ package main
 import (
 	"flag"
 	"fmt"
+	"go-tower-defense/internal/app"
 	"go-tower-defense/internal/config"
 	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/state"
 	"log"
-	"math/rand"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
@@ -163,7 +163,17 @@ func main() {
 	// --- Флаги командной строки ---
 	devMode := flag.Bool("dev", false, "Start directly in the game state for development")
 	exportModels := flag.Bool("export-models", false, "Export game models to .obj files and exit")
+	seed := flag.Int64("seed", 0, "Seed for all game randomness (0 picks a random seed)")
+	recordPath := flag.String("record", "replays/last.json", "File to write the command log of the current run to (empty disables recording)")
+	replayPath := flag.String("replay", "", "Play back a recorded replay file with a fixed timestep")
+	savePath := flag.String("save", "saves/quicksave.sav", "Save file used by the pause menu and the main menu continue option")
+	level := flag.String("level", defs.DefaultLevelID, "Level from levels.json that sets the ore economy of new games")
+	mapPath := flag.String("map", "", "Start new games on a hand-authored map file instead of a generated map")
 	flag.Parse()
+	state.ReplayRecordPath = *recordPath
+	state.SaveFilePath = *savePath
+	state.LevelID = *level
+	state.MapPath = *mapPath
 
 	// --- Инициализация Raylib ---
 	rl.InitWindow(config.ScreenWidth, config.ScreenHeight, "Go Tower Defense")
@@ -200,7 +210,6 @@ func main() {
 	defer rl.UnloadFont(font)
 
 	// --- Инициализация игры ---
-	rand.Seed(time.Now().UnixNano())
 	sm := state.NewStateMachine()
 
 	// --- Настройка 3D камеры ---
@@ -210,18 +219,32 @@ func main() {
 	camera.Fovy = config.CameraFovyDefault
 
 	// --- Выбор начального состояния ---
-	if *devMode {
+	if *replayPath != "" {
+		replay, err := app.LoadReplay(*replayPath)
+		if err != nil {
+			log.Fatalf("Failed to load replay: %v", err)
+		}
+		log.Printf("--- REPLAY: %s (seed %d, %d commands) ---", *replayPath, replay.Seed, len(replay.Commands))
+		towerDefPtrs := make(map[string]*defs.TowerDefinition)
+		for id, def := range defs.TowerDefs {
+			d := def
+			towerDefPtrs[id] = &d
+		}
+		gs := state.NewReplayGameState(sm, defs.RecipeLibrary, towerDefPtrs, &camera, replay)
+		gs.SetCamera(&camera)
+		sm.SetState(gs)
+	} else if *devMode {
 		log.Println("---" + "DEV MODE: Starting game directly" + "---")
 		towerDefPtrs := make(map[string]*defs.TowerDefinition)
 		for id, def := range defs.TowerDefs {
 			d := def
 			towerDefPtrs[id] = &d
 		}
-		gs := state.NewGameState(sm, defs.RecipeLibrary, towerDefPtrs, &camera)
+		gs := state.NewGameState(sm, defs.RecipeLibrary, towerDefPtrs, &camera, *seed)
 		gs.SetCamera(&camera)
 		sm.SetState(gs)
 	} else {
-		sm.SetState(state.NewMenuState(sm, font))
+		sm.SetState(state.NewMenuState(sm, font, *seed))
 	}
 
 	// Позиции и цели для интерполяции
@@ -0,0 +1,416 @@
+package main
+
+import (
+	"fmt"
+	"go-tower-defense/pkg/hexmap"
+	"slices"
+)
+
+// editorTool — инструмент редактора, выбирается цифровыми клавишами.
+type editorTool int
+
+const (
+	toolTiles       editorTool = iota // ЛКМ — добавить тайл, ПКМ — удалить
+	toolPassable                      // ЛКМ — сделать непроходимым, ПКМ — проходимым
+	toolBuildable                     // ЛКМ — запретить строительство, ПКМ — разрешить
+	toolEntry                         // ЛКМ — перенести вход выбранного маршрута
+	toolExit                          // ЛКМ — перенести выход выбранного маршрута
+	toolCheckpoints                   // ЛКМ — добавить или выбрать, ПКМ — удалить, [ ] — сдвинуть выбранный
+	toolOre                           // ЛКМ — задать запас в текущей жиле, ПКМ — убрать руду
+	toolWalls                         // ЛКМ — поставить стену, ПКМ — убрать
+	toolTerrain                       // ЛКМ — положить местность кисти, ПКМ — вернуть обычную землю
+	toolCount
+)
+
+// toolNames — подписи инструментов в интерфейсе (шрифт raylib по умолчанию без кириллицы).
+var toolNames = [toolCount]string{
+	"Tiles", "Passable", "Buildable", "Entry", "Exit", "Checkpoints", "Ore", "Walls", "Terrain",
+}
+
+// orePowerStep — шаг, с которым клавиши +/- меняют запас кисти руды (в процентах).
+const orePowerStep = 5.0
+
+// mapEditor хранит редактируемую карту и состояние инструментов.
+// Все правки идут через его методы, после каждой изменившей карту правки
+// маршруты врагов пересчитываются, чтобы редактор сразу показывал, проходима ли карта.
+type mapEditor struct {
+	mapFile  *hexmap.MapFile
+	path     string // Файл, в который сохраняется карта
+	tool     editorTool
+	gate     int            // Выбранный вход (HexMap.Gates); его правят инструменты входа, выхода и чекпоинтов
+	vein     int            // Жила, в которую рисует инструмент руды
+	orePower float64        // Запас гекса, который ставит кисть руды
+	terrain  hexmap.Terrain // Местность, которую кладет кисть местности
+	selected int            // Выбранный чекпоинт маршрута выбранного входа; -1 — нет
+	dirty    bool
+	status   string
+
+	routes   [][]hexmap.Hex // Маршруты входов (вход → чекпоинты → выход) с учетом стен; nil — перекрыт
+	routeErr error          // Результат MapFile.Validate
+}
+
+func newMapEditor(mapFile *hexmap.MapFile, path string) *mapEditor {
+	e := &mapEditor{mapFile: mapFile, path: path, orePower: 25, selected: -1, terrain: hexmap.TerrainMud}
+	e.revalidate()
+	return e
+}
+
+// revalidate пересчитывает маршрут и проверку карты после правки.
+func (e *mapEditor) revalidate() {
+	walled := e.mapFile.Map.Clone()
+	for _, wall := range e.mapFile.Walls {
+		walled.SetPassable(wall, false)
+	}
+	e.routes = e.routes[:0]
+	for _, gate := range walled.Gates() {
+		e.routes = append(e.routes, walled.GateRoute(gate))
+	}
+	e.routeErr = e.mapFile.Validate()
+}
+
+func (e *mapEditor) changed() {
+	e.dirty = true
+	e.revalidate()
+}
+
+// apply выполняет действие текущего инструмента над гексом.
+// primary — левая кнопка мыши, иначе правая. pressed — кнопка только что нажата;
+// инструменты, которые нельзя "размазывать" протягиванием, реагируют только на нажатие.
+func (e *mapEditor) apply(hex hexmap.Hex, primary, pressed bool) {
+	hm := e.mapFile.Map
+	switch e.tool {
+	case toolTiles:
+		if primary {
+			if !hm.Contains(hex) {
+				hm.Tiles[hex] = hexmap.Tile{Passable: true, CanPlaceTower: true}
+				e.changed()
+			}
+		} else {
+			e.removeTile(hex)
+		}
+	case toolPassable:
+		e.setTile(hex, func(t *hexmap.Tile) { t.Passable = !primary })
+	case toolBuildable:
+		e.setTile(hex, func(t *hexmap.Tile) { t.CanPlaceTower = !primary })
+	case toolEntry, toolExit:
+		if pressed && primary {
+			e.moveGate(hex, e.tool == toolEntry)
+		}
+	case toolCheckpoints:
+		if pressed {
+			e.editCheckpoint(hex, primary)
+		}
+	case toolOre:
+		if primary {
+			e.setOre(hex, e.vein, e.orePower)
+		} else {
+			e.setOre(hex, -1, 0)
+		}
+	case toolWalls:
+		e.setWall(hex, primary)
+	case toolTerrain:
+		if primary {
+			e.setTerrain(hex, e.terrain)
+		} else {
+			e.setTerrain(hex, hexmap.TerrainPlain)
+		}
+	}
+}
+
+// gateRef возвращает вход, выход и чекпоинты выбранного входа по указателям,
+// чтобы инструменты одинаково правили основной маршрут и ExtraGates.
+func (e *mapEditor) gateRef() (entry, exit *hexmap.Hex, checkpoints *[]hexmap.Hex) {
+	hm := e.mapFile.Map
+	if e.gate > 0 && e.gate <= len(hm.ExtraGates) {
+		gate := &hm.ExtraGates[e.gate-1]
+		return &gate.Entry, &gate.Exit, &gate.Checkpoints
+	}
+	return &hm.Entry, &hm.Exit, &hm.Checkpoints
+}
+
+func (e *mapEditor) isEntry(hex hexmap.Hex) bool {
+	for _, gate := range e.mapFile.Map.Gates() {
+		if gate.Entry == hex {
+			return true
+		}
+	}
+	return false
+}
+
+// setTile меняет флаги существующего тайла; входы и выходы всегда остаются
+// проходимыми и без строительства, вода — непроходимой и без строительства.
+func (e *mapEditor) setTile(hex hexmap.Hex, edit func(*hexmap.Tile)) {
+	hm := e.mapFile.Map
+	tile, ok := hm.Tiles[hex]
+	if !ok || hm.IsGate(hex) || tile.Terrain == hexmap.TerrainWater {
+		return
+	}
+	before := tile
+	edit(&tile)
+	if tile != before {
+		if !tile.Passable {
+			e.setWall(hex, false) // Стена стоит только на проходимом тайле
+		}
+		hm.Tiles[hex] = tile
+		e.changed()
+	}
+}
+
+// removeTile удаляет тайл вместе со стоящими на нем чекпоинтами всех маршрутов,
+// стеной и рудой.
+func (e *mapEditor) removeTile(hex hexmap.Hex) {
+	hm := e.mapFile.Map
+	if !hm.Contains(hex) || hm.IsGate(hex) {
+		return
+	}
+	delete(hm.Tiles, hex)
+	if i := e.checkpointIndex(hex); i >= 0 {
+		e.removeCheckpoint(i)
+	}
+	onHex := func(cp hexmap.Hex) bool { return cp == hex }
+	hm.Checkpoints = slices.DeleteFunc(hm.Checkpoints, onHex)
+	for i := range hm.ExtraGates {
+		hm.ExtraGates[i].Checkpoints = slices.DeleteFunc(hm.ExtraGates[i].Checkpoints, onHex)
+	}
+	e.setWall(hex, false)
+	e.setOre(hex, -1, 0)
+	e.changed()
+}
+
+// moveGate переносит вход или выход выбранного маршрута на гекс, создавая тайл
+// при необходимости. Выход можно сделать общим с другим маршрутом, вход — нет.
+// Старый гекс, если им больше не пользуется ни один маршрут, остается обычным тайлом.
+func (e *mapEditor) moveGate(hex hexmap.Hex, entry bool) {
+	hm := e.mapFile.Map
+	entryHex, exitHex, _ := e.gateRef()
+	if hex == *entryHex || hex == *exitHex || hm.IsCheckpoint(hex) || e.isEntry(hex) || (entry && hm.IsGate(hex)) {
+		return
+	}
+	target := exitHex
+	if entry {
+		target = entryHex
+	}
+	old := *target
+	*target = hex
+	if !hm.IsGate(old) {
+		hm.Tiles[old] = hexmap.Tile{Passable: true, CanPlaceTower: true}
+	}
+	e.setWall(hex, false)
+	e.setOre(hex, -1, 0)
+	hm.Tiles[hex] = hexmap.Tile{Passable: true, CanPlaceTower: false}
+	e.changed()
+}
+
+// nextGate переключает инструменты входа, выхода и чекпоинтов на следующий маршрут.
+func (e *mapEditor) nextGate() {
+	e.gate = (e.gate + 1) % len(e.mapFile.Map.Gates())
+	e.selected = -1
+}
+
+// addGate добавляет вход на гексе hex с выходом основного маршрута и выбирает его.
+func (e *mapEditor) addGate(hex hexmap.Hex) {
+	hm := e.mapFile.Map
+	if hm.IsGate(hex) || hm.IsCheckpoint(hex) {
+		return
+	}
+	e.setWall(hex, false)
+	e.setOre(hex, -1, 0)
+	hm.Tiles[hex] = hexmap.Tile{Passable: true, CanPlaceTower: false}
+	hm.ExtraGates = append(hm.ExtraGates, hexmap.Gate{Entry: hex, Exit: hm.Exit, Checkpoints: []hexmap.Hex{}})
+	e.gate = len(hm.ExtraGates)
+	e.selected = -1
+	e.changed()
+}
+
+// removeGate удаляет выбранный дополнительный вход; основной удалить нельзя.
+func (e *mapEditor) removeGate() {
+	hm := e.mapFile.Map
+	if e.gate == 0 || e.gate > len(hm.ExtraGates) {
+		return
+	}
+	gate := hm.ExtraGates[e.gate-1]
+	hm.ExtraGates = append(hm.ExtraGates[:e.gate-1], hm.ExtraGates[e.gate:]...)
+	for _, hex := range []hexmap.Hex{gate.Entry, gate.Exit} {
+		if !hm.IsGate(hex) {
+			hm.Tiles[hex] = hexmap.Tile{Passable: true, CanPlaceTower: true}
+		}
+	}
+	e.gate = 0
+	e.selected = -1
+	e.changed()
+}
+
+func (e *mapEditor) checkpointIndex(hex hexmap.Hex) int {
+	_, _, checkpoints := e.gateRef()
+	for i, cp := range *checkpoints {
+		if cp == hex {
+			return i
+		}
+	}
+	return -1
+}
+
+// editCheckpoint: левая кнопка выбирает существующий чекпоинт или добавляет
+// новый в конец маршрута выбранного входа, правая удаляет чекпоинт.
+func (e *mapEditor) editCheckpoint(hex hexmap.Hex, primary bool) {
+	hm := e.mapFile.Map
+	_, _, checkpoints := e.gateRef()
+	i := e.checkpointIndex(hex)
+	switch {
+	case !primary && i >= 0:
+		e.removeCheckpoint(i)
+		e.changed()
+	case primary && i >= 0:
+		e.selected = i
+	case primary && hm.IsPassable(hex) && !hm.IsGate(hex):
+		e.setWall(hex, false)
+		*checkpoints = append(*checkpoints, hex)
+		e.selected = len(*checkpoints) - 1
+		e.changed()
+	}
+}
+
+func (e *mapEditor) removeCheckpoint(i int) {
+	_, _, checkpoints := e.gateRef()
+	*checkpoints = append((*checkpoints)[:i], (*checkpoints)[i+1:]...)
+	switch {
+	case e.selected == i:
+		e.selected = -1
+	case e.selected > i:
+		e.selected--
+	}
+}
+
+// moveSelectedCheckpoint сдвигает выбранный чекпоинт по порядку обхода на delta.
+func (e *mapEditor) moveSelectedCheckpoint(delta int) {
+	_, _, checkpoints := e.gateRef()
+	cps := *checkpoints
+	j := e.selected + delta
+	if e.selected < 0 || j < 0 || j >= len(cps) {
+		return
+	}
+	cps[e.selected], cps[j] = cps[j], cps[e.selected]
+	e.selected = j
+	e.changed()
+}
+
+// setWall ставит или убирает стену; стены не ставятся на входы, выходы, чекпоинты
+// и непроходимые тайлы.
+func (e *mapEditor) setWall(hex hexmap.Hex, place bool) {
+	hm := e.mapFile.Map
+	for i, wall := range e.mapFile.Walls {
+		if wall == hex {
+			if !place {
+				e.mapFile.Walls = append(e.mapFile.Walls[:i], e.mapFile.Walls[i+1:]...)
+				e.changed()
+			}
+			return
+		}
+	}
+	if place && hm.IsPassable(hex) && !hm.IsGate(hex) && !hm.IsCheckpoint(hex) {
+		e.mapFile.Walls = append(e.mapFile.Walls, hex)
+		e.changed()
+	}
+}
+
+// setOre кладет руду с запасом power в жилу vein; vein < 0 убирает руду с гекса.
+// Гекс принадлежит не более чем одной жиле, поэтому из прежней жилы он уходит.
+func (e *mapEditor) setOre(hex hexmap.Hex, vein int, power float64) {
+	if vein >= 0 && !e.mapFile.Map.Contains(hex) {
+		return
+	}
+	veins := e.mapFile.OreVeins
+	changed := false
+	for i, ores := range veins {
+		for j, ore := range ores {
+			if ore.Hex != hex {
+				continue
+			}
+			if i == vein && ore.Power == power {
+				return
+			}
+			veins[i] = append(ores[:j], ores[j+1:]...)
+			changed = true
+			break
+		}
+	}
+	if vein >= 0 {
+		for len(veins) <= vein {
+			veins = append(veins, nil)
+		}
+		veins[vein] = append(veins[vein], hexmap.OreHex{Hex: hex, Power: power})
+		changed = true
+	}
+	e.mapFile.OreVeins = veins
+	if changed {
+		e.changed()
+	}
+}
+
+// oreAt возвращает жилу и запас руды на гексе.
+func (e *mapEditor) oreAt(hex hexmap.Hex) (vein int, power float64, ok bool) {
+	for i, ores := range e.mapFile.OreVeins {
+		for _, ore := range ores {
+			if ore.Hex == hex {
+				return i, ore.Power, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+func (e *mapEditor) isWall(hex hexmap.Hex) bool {
+	for _, wall := range e.mapFile.Walls {
+		if wall == hex {
+			return true
+		}
+	}
+	return false
+}
+
+// setTerrain кладет местность на тайл. Вода не ложится на входы, выходы и
+// чекпоинты и убирает стоящую на тайле стену.
+func (e *mapEditor) setTerrain(hex hexmap.Hex, terrain hexmap.Terrain) {
+	hm := e.mapFile.Map
+	if !hm.Contains(hex) || hm.TerrainAt(hex) == terrain {
+		return
+	}
+	if terrain == hexmap.TerrainWater {
+		if hm.IsGate(hex) || hm.IsCheckpoint(hex) {
+			return
+		}
+		e.setWall(hex, false)
+	}
+	hm.SetTerrain(hex, terrain)
+	e.changed()
+}
+
+// nextTerrain переключает кисть местности; обычную землю кладет ПКМ.
+func (e *mapEditor) nextTerrain() {
+	e.terrain = e.terrain%(hexmap.TerrainCount-1) + 1
+}
+
+// nextVein переключает кисть руды на следующую жилу; после последней можно
+// начать новую.
+func (e *mapEditor) nextVein() {
+	e.vein = (e.vein + 1) % (len(e.mapFile.OreVeins) + 1)
+}
+
+func (e *mapEditor) changeOrePower(delta float64) {
+	e.orePower = max(orePowerStep, e.orePower+delta)
+}
+
+// save записывает карту. Незаконченную карту тоже можно сохранить, но
+// статус предупреждает, что игра ее не загрузит.
+func (e *mapEditor) save() {
+	if err := e.mapFile.Save(e.path); err != nil {
+		e.status = fmt.Sprintf("Save failed: %v", err)
+		return
+	}
+	e.dirty = false
+	if e.routeErr != nil {
+		e.status = fmt.Sprintf("Saved to %s, but the game will reject it: %v", e.path, e.routeErr)
+		return
+	}
+	e.status = fmt.Sprintf("Saved to %s", e.path)
+}
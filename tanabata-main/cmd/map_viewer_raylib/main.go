@@ -1,10 +1,14 @@
 package main
 
 import (
+	"errors"
+	"flag"
+	"fmt"
+	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
-	"math"
-	"math/rand"
-	"time"
+	"io/fs"
+	"log"
+	"os"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -26,24 +30,162 @@ func ColorLerp(c1, c2 rl.Color, t float32) rl.Color {
 
 // hexFromWorldPoint преобразует мировые координаты в гексагональные
 func hexFromWorldPoint(point rl.Vector3, hexSize, scale float32) hexmap.Hex {
-	x := point.X / scale
-	z := point.Z / scale
-	q := (2.0 / 3.0 * x) / hexSize
-	r := (-1.0/3.0*x + float32(math.Sqrt(3))/3.0*z) / hexSize
-	return hexmap.Hex{Q: int(round(q)), R: int(round(r))}
+	return hexmap.PixelToHex(float64(point.X/scale), float64(point.Z/scale), float64(hexSize))
 }
 
-func round(f float32) float32 {
-	return float32(math.Floor(float64(f) + 0.5))
+// veinColors — цвета рудных жил по номеру; номера сверх списка берут цвета по кругу.
+var veinColors = []rl.Color{
+	rl.NewColor(70, 130, 180, 255), rl.NewColor(150, 90, 220, 255), rl.NewColor(230, 110, 40, 255),
+	rl.NewColor(60, 190, 170, 255), rl.NewColor(220, 200, 60, 255),
+}
+
+func veinColor(vein int) rl.Color {
+	return veinColors[vein%len(veinColors)]
+}
+
+// terrainColors — цвета тайлов с местностью.
+var terrainColors = map[hexmap.Terrain]rl.Color{
+	hexmap.TerrainMud:        rl.NewColor(120, 90, 55, 255),
+	hexmap.TerrainRoad:       rl.NewColor(170, 165, 145, 255),
+	hexmap.TerrainWater:      rl.NewColor(40, 90, 190, 255),
+	hexmap.TerrainHighGround: rl.NewColor(70, 170, 80, 255),
+}
+
+// loadOrGenerateMap открывает файл карты для правки. Если файла нет,
+// карта генерируется из сида так же, как в игре.
+func loadOrGenerateMap(path string, seed int64) *hexmap.MapFile {
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err == nil {
+			mapFile, err := hexmap.UnmarshalMapFile(data)
+			if err != nil {
+				log.Fatalf("Failed to open map %s: %v", path, err)
+			}
+			return mapFile
+		}
+		if !errors.Is(err, fs.ErrNotExist) {
+			log.Fatalf("Failed to read map %s: %v", path, err)
+		}
+	}
+	// Та же ветка "map", что и в игре: один сид дает одинаковую карту
+	rng := utils.NewPRNGService(seed)
+	log.Printf("[SEED] %d", rng.Seed())
+	return &hexmap.MapFile{
+		Name: fmt.Sprintf("seed %d", rng.Seed()),
+		Map:  hexmap.NewHexMap(rng.Fork("map")),
+	}
+}
+
+// handleEditorKeys переключает инструменты, маршруты и настройки кистей редактора.
+// Новый вход ставится на гекс под курсором.
+func handleEditorKeys(editor *mapEditor, hoveredHex hexmap.Hex, hovered bool) {
+	for tool := editorTool(0); tool < toolCount; tool++ {
+		if rl.IsKeyPressed(rl.KeyOne + int32(tool)) {
+			editor.tool = tool
+		}
+	}
+	if rl.IsKeyPressed(rl.KeyTab) {
+		editor.nextVein()
+	}
+	if rl.IsKeyPressed(rl.KeyT) {
+		editor.nextTerrain()
+	}
+	if rl.IsKeyPressed(rl.KeyG) {
+		editor.nextGate()
+	}
+	if rl.IsKeyPressed(rl.KeyN) && hovered {
+		editor.addGate(hoveredHex)
+	}
+	if rl.IsKeyPressed(rl.KeyX) {
+		editor.removeGate()
+	}
+	if rl.IsKeyPressed(rl.KeyEqual) || rl.IsKeyPressed(rl.KeyKpAdd) {
+		editor.changeOrePower(orePowerStep)
+	}
+	if rl.IsKeyPressed(rl.KeyMinus) || rl.IsKeyPressed(rl.KeyKpSubtract) {
+		editor.changeOrePower(-orePowerStep)
+	}
+	if rl.IsKeyPressed(rl.KeyLeftBracket) {
+		editor.moveSelectedCheckpoint(-1)
+	}
+	if rl.IsKeyPressed(rl.KeyRightBracket) {
+		editor.moveSelectedCheckpoint(1)
+	}
+	ctrl := rl.IsKeyDown(rl.KeyLeftControl) || rl.IsKeyDown(rl.KeyRightControl)
+	if ctrl && rl.IsKeyPressed(rl.KeyS) {
+		editor.save()
+	}
+}
+
+// drawEditorUI выводит инструменты, кисти, состояние маршрута и статус сохранения.
+func drawEditorUI(editor *mapEditor) {
+	y := int32(10)
+	line := func(text string, color rl.Color) {
+		rl.DrawText(text, 10, y, 20, color)
+		y += 24
+	}
+	line("Q/E - rotate, Mouse Wheel - angle, LMB/RMB - apply tool, Ctrl+S - save", rl.White)
+	for tool := editorTool(0); tool < toolCount; tool++ {
+		color := rl.LightGray
+		if tool == editor.tool {
+			color = rl.Yellow
+		}
+		rl.DrawText(fmt.Sprintf("%d %s", tool+1, toolNames[tool]), 10+int32(tool)*140, y, 20, color)
+	}
+	y += 24
+	line(fmt.Sprintf("Gate %d of %d (G - next, N - add at cursor, X - remove)", editor.gate, len(editor.routes)), rl.SkyBlue)
+	switch editor.tool {
+	case toolOre:
+		line(fmt.Sprintf("Vein %d (Tab), power %.0f%% (+/-)", editor.vein, editor.orePower), veinColor(editor.vein))
+	case toolTerrain:
+		line(fmt.Sprintf("Terrain %s (T - next), RMB - plain", editor.terrain), terrainColors[editor.terrain])
+	case toolCheckpoints:
+		if editor.selected >= 0 {
+			line(fmt.Sprintf("Checkpoint %d selected, [ ] - move in order", editor.selected+1), rl.Gold)
+		} else {
+			line("LMB - add or select checkpoint, RMB - remove", rl.Gold)
+		}
+	}
+	for i, route := range editor.routes {
+		if route != nil {
+			gate := editor.mapFile.Map.Gate(i)
+			line(fmt.Sprintf("Gate %d route open: %d hexes through %d checkpoints", i, len(route), len(gate.Checkpoints)), rl.Green)
+		} else {
+			line(fmt.Sprintf("Gate %d route blocked", i), rl.Red)
+		}
+	}
+	if editor.routeErr != nil {
+		line(editor.routeErr.Error(), rl.Red)
+	}
+	saveState := ""
+	if editor.dirty {
+		saveState = " (unsaved changes)"
+	}
+	line(fmt.Sprintf("File: %s%s", editor.path, saveState), rl.White)
+	if editor.status != "" {
+		line(editor.status, rl.SkyBlue)
+	}
+	rl.DrawFPS(10, y)
 }
 
 func main() {
+	seed := flag.Int64("seed", 0, "Seed for map generation (0 picks a random seed)")
+	mapPath := flag.String("map", "", "Map file to edit; a new map is generated from -seed when it does not exist")
+	outPath := flag.String("out", "", "File to save the map to (defaults to -map, or assets/maps/new_map.json)")
+	flag.Parse()
+	if *outPath == "" {
+		*outPath = *mapPath
+	}
+	if *outPath == "" {
+		*outPath = "assets/maps/new_map.json"
+	}
+
 	// --- Инициализация ---
 	const screenWidth = 1280
 	const screenHeight = 720
 	backgroundColor := rl.NewColor(10, 10, 20, 255)
 
-	rl.InitWindow(screenWidth, screenHeight, "Raylib Map Viewer | Q/E - Rotate, Mouse Wheel - Change Angle")
+	rl.InitWindow(screenWidth, screenHeight, "Raylib Map Editor | Q/E - Rotate, Mouse Wheel - Change Angle")
 	rl.SetTargetFPS(60)
 
 	// --- Настройка 3D камеры ---
@@ -60,18 +202,12 @@ func main() {
 	topDownFovy := float32(35.0)
 	cameraAngleT := float32(0.5)
 
-	// --- Генерация карты ---
-	rand.Seed(time.Now().UnixNano())
-	gameMap := hexmap.NewHexMap()
+	// --- Карта для правки ---
+	editor := newMapEditor(loadOrGenerateMap(*mapPath, *seed), *outPath)
+	gameMap := editor.mapFile.Map
 	const coordScale = 0.5
 	const hexSizeRender = 10.0
 
-	// Создаем мапу для быстрой проверки чекпоинтов
-	checkpointsMap := make(map[hexmap.Hex]struct{})
-	for _, cp := range gameMap.Checkpoints {
-		checkpointsMap[cp] = struct{}{}
-	}
-
 	// --- Главный цикл ---
 	for !rl.WindowShouldClose() {
 		// --- Обновление (логика) ---
@@ -100,6 +236,29 @@ func main() {
 		camera.Target = Vector3Lerp(isoTarget, topDownTarget, cameraAngleT)
 		camera.Fovy = isoFovy + (topDownFovy-isoFovy)*cameraAngleT
 
+		// --- Редактирование: гекс под курсором (поверхность крышек на Y=1) ---
+		hoveredHex, hovered := hexmap.Hex{}, false
+		mouseRay := rl.GetMouseRay(rl.GetMousePosition(), camera)
+		if mouseRay.Direction.Y != 0 {
+			if t := (1 - mouseRay.Position.Y) / mouseRay.Direction.Y; t > 0 {
+				hoveredHex = hexFromWorldPoint(rl.Vector3Add(mouseRay.Position, rl.Vector3Scale(mouseRay.Direction, t)), hexSizeRender, coordScale)
+				hovered = true
+			}
+		}
+		handleEditorKeys(editor, hoveredHex, hovered)
+		if hovered {
+			if rl.IsMouseButtonDown(rl.MouseLeftButton) {
+				editor.apply(hoveredHex, true, rl.IsMouseButtonPressed(rl.MouseLeftButton))
+			} else if rl.IsMouseButtonDown(rl.MouseRightButton) {
+				editor.apply(hoveredHex, false, rl.IsMouseButtonPressed(rl.MouseRightButton))
+			}
+		}
+		gate := gameMap.Gate(editor.gate)
+		checkpointsMap := make(map[hexmap.Hex]int)
+		for i, cp := range gate.Checkpoints {
+			checkpointsMap[cp] = i
+		}
+
 		// --- Оптимизация: Определение видимых гексов ---
 		visibleHexes := make(map[hexmap.Hex]struct{})
 		for x := -50; x <= screenWidth+50; x += 100 {
@@ -128,14 +287,30 @@ func main() {
 			pixelX, pixelY := h.ToPixel(hexSizeRender)
 
 			var baseColor rl.Color
-			if h == gameMap.Entry {
+			cpIndex, isSelectedCheckpoint := checkpointsMap[h]
+			vein, power, isOre := editor.oreAt(h)
+			if editor.isEntry(h) {
 				baseColor = rl.SkyBlue
-			} else if h == gameMap.Exit {
+			} else if gameMap.IsGate(h) {
 				baseColor = rl.Red
-			} else if _, isCheckpoint := checkpointsMap[h]; isCheckpoint {
+			} else if isSelectedCheckpoint && cpIndex == editor.selected {
+				baseColor = rl.Orange
+			} else if isSelectedCheckpoint {
 				baseColor = rl.Gold
+			} else if gameMap.IsCheckpoint(h) {
+				baseColor = rl.NewColor(180, 160, 90, 255)
+			} else if editor.isWall(h) {
+				baseColor = rl.Brown
+			} else if tile.Terrain == hexmap.TerrainWater {
+				baseColor = terrainColors[hexmap.TerrainWater]
 			} else if !tile.Passable {
 				baseColor = rl.Gray
+			} else if isOre {
+				baseColor = ColorLerp(rl.NewColor(100, 140, 110, 255), veinColor(vein), float32(min(1, 0.3+power/60)))
+			} else if terrainColor, ok := terrainColors[tile.Terrain]; ok {
+				baseColor = terrainColor
+			} else if !tile.CanPlaceTower {
+				baseColor = rl.NewColor(70, 95, 80, 255)
 			} else {
 				baseColor = rl.NewColor(100, 140, 110, 255)
 			}
@@ -156,11 +331,10 @@ func main() {
 			if fogFactor > 1 {
 				fogFactor = 1
 			}
-			
+
 			finalColor := ColorLerp(baseColor, backgroundColor, fogFactor)
 			finalColumnColor := ColorLerp(rl.DarkGray, backgroundColor, fogFactor)
 
-
 			// Рисуем крышку всегда
 			capHeight := float32(2.0)
 			capBottomPos := rl.NewVector3(x, -1.0, z)
@@ -175,11 +349,44 @@ func main() {
 			}
 		}
 
+		// Маршрут врагов и гекс под курсором
+		worldPoint := func(h hexmap.Hex, y float32) rl.Vector3 {
+			px, py := h.ToPixel(hexSizeRender)
+			return rl.NewVector3(float32(px)*coordScale, y, float32(py)*coordScale)
+		}
+		for g, route := range editor.routes {
+			color := rl.DarkGreen
+			if g == editor.gate {
+				color = rl.Green
+			}
+			for i := 1; i < len(route); i++ {
+				rl.DrawLine3D(worldPoint(route[i-1], 1.3), worldPoint(route[i], 1.3), color)
+			}
+		}
+		if hovered {
+			rl.DrawCylinderWires(worldPoint(hoveredHex, -1.0), hexSizeRender*0.5, hexSizeRender*0.5, 2.2, 6, rl.Yellow)
+		}
+
 		rl.EndMode3D()
 
+		// --- Подписи: номера входов, чекпоинты выбранного маршрута и запас руды ---
+		for g, other := range gameMap.Gates() {
+			pos := rl.GetWorldToScreen(worldPoint(other.Entry, 1.0), camera)
+			rl.DrawText(fmt.Sprintf("G%d", g), int32(pos.X)-10, int32(pos.Y)-10, 20, rl.Black)
+		}
+		for i, cp := range gate.Checkpoints {
+			pos := rl.GetWorldToScreen(worldPoint(cp, 1.0), camera)
+			rl.DrawText(fmt.Sprint(i+1), int32(pos.X)-5, int32(pos.Y)-10, 20, rl.Black)
+		}
+		for _, vein := range editor.mapFile.OreVeins {
+			for _, ore := range vein {
+				pos := rl.GetWorldToScreen(worldPoint(ore.Hex, 1.0), camera)
+				rl.DrawText(fmt.Sprintf("%.0f", ore.Power), int32(pos.X)-8, int32(pos.Y)-5, 10, rl.White)
+			}
+		}
+
 		// --- UI ---
-		rl.DrawText("Use Q/E to rotate and Mouse Wheel to change angle", 10, 10, 20, rl.White)
-		rl.DrawFPS(10, 40)
+		drawEditorUI(editor)
 
 		rl.EndDrawing()
 	}
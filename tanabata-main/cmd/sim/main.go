@@ -0,0 +1,156 @@
+// cmd/sim/main.go
+package main
+
+import (
+	"flag"
+	"fmt"
+	"go-tower-defense/internal/app"
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/event"
+	"go-tower-defense/internal/utils"
+	"io"
+	"log"
+	"os"
+)
+
+// waveReporter печатает сводку по окончании каждой волны.
+type waveReporter struct {
+	game *app.Game
+	out  *log.Logger
+}
+
+// OnEvent реализует интерфейс event.Listener.
+func (r *waveReporter) OnEvent(e event.Event) {
+	if e.Type != event.WaveEnded {
+		return
+	}
+	health := 0
+	if playerState, ok := r.game.ECS.PlayerState[r.game.PlayerID]; ok {
+		health = playerState.Health
+	}
+	r.out.Printf("wave=%d time=%.1fs health=%d ore=%.1f towers=%d",
+		r.game.Wave-1, r.game.GetGameTime(), health, r.game.GetCurrentOreReserve(), len(r.game.ECS.Towers))
+}
+
+// Безголовый запуск симуляции: без окна, без raylib, с фиксированным шагом.
+// Используется для балансовых прогонов и CI.
+func main() {
+	// --- Флаги командной строки ---
+	dataDir := flag.String("data", "assets/data/", "Directory with definition files")
+	waves := flag.Int("waves", 10, "Number of waves to simulate")
+	step := flag.Float64("dt", 1.0/60.0, "Fixed simulation timestep in seconds")
+	maxTicks := flag.Int("max-ticks", 10_000_000, "Safety limit on the number of simulation ticks")
+	godMode := flag.Bool("god", false, "Enable god mode so the run never ends by player death")
+	verbose := flag.Bool("v", false, "Keep the game log output")
+	seed := flag.Int64("seed", 0, "Seed for all simulation randomness (0 picks a random seed)")
+	level := flag.String("level", defs.DefaultLevelID, "Level from levels.json that sets the ore economy")
+	mapPath := flag.String("map", "", "Play on a hand-authored map file instead of a generated map")
+	exportMapPath := flag.String("export-map", "", "Write the starting map of the run, with its ore and walls, to this map file")
+	recordPath := flag.String("record", "", "Write the command log of the run to this replay file")
+	replayPath := flag.String("replay", "", "Play back a replay file and verify the final state checksum")
+	networkDir := flag.String("network-export", "", "Write the final energy network as DOT and JSON into this directory")
+	flag.Parse()
+
+	out := log.New(os.Stdout, "", 0)
+	if !*verbose {
+		log.SetOutput(io.Discard)
+	}
+
+	// --- Загрузка определений ---
+	if err := defs.LoadAll(*dataDir); err != nil {
+		out.Fatalf("Failed to load definitions: %v", err)
+	}
+	towerDefPtrs := make(map[string]*defs.TowerDefinition)
+	for id, def := range defs.TowerDefs {
+		d := def
+		towerDefPtrs[id] = &d
+	}
+
+	if *replayPath != "" {
+		os.Exit(runReplay(*replayPath, towerDefPtrs, out))
+	}
+
+	// --- Инициализация симуляции ---
+	rng := utils.NewPRNGService(*seed)
+	out.Printf("seed=%d", rng.Seed())
+	game, err := app.NewGameOnMap(*mapPath, towerDefPtrs, rng, *level)
+	if err != nil {
+		out.Fatalf("Failed to start the game: %v", err)
+	}
+	if *exportMapPath != "" {
+		if err := game.ExportMapFile(fmt.Sprintf("seed %d", rng.Seed())).Save(*exportMapPath); err != nil {
+			out.Printf("failed to export map: %v", err)
+		} else {
+			out.Printf("map exported to %s", *exportMapPath)
+		}
+	}
+	if *recordPath != "" {
+		game.EnableRecording(*step)
+		defer func() {
+			if err := app.SaveReplay(*recordPath, game.Recording()); err != nil {
+				out.Printf("failed to save replay: %v", err)
+			}
+		}()
+	}
+	if *godMode {
+		game.ToggleGodMode()
+	}
+	game.EventDispatcher.Subscribe(event.WaveEnded, &waveReporter{game: game, out: out})
+
+	// --- Главный цикл: волны запускаются сразу, как только начинается фаза строительства ---
+	ticks := 0
+	for ; ticks < *maxTicks; ticks++ {
+		if game.IsGameOver() {
+			out.Printf("game over on wave %d after %d ticks", game.Wave-1, ticks)
+			if *recordPath != "" {
+				app.SaveReplay(*recordPath, game.Recording())
+			}
+			os.Exit(1)
+		}
+		if game.ECS.GameState.Phase == component.BuildState {
+			if game.Wave > *waves {
+				break
+			}
+			game.HandleIndicatorClick()
+		}
+		game.Update(*step)
+	}
+	out.Printf("finished %d waves in %d ticks (%.1fs of game time)", game.Wave-1, ticks, game.GetGameTime())
+	if *networkDir != "" {
+		path, err := game.ExportNetwork(*networkDir)
+		if err != nil {
+			out.Printf("failed to export network: %v", err)
+		} else {
+			out.Printf("network exported to %s", path)
+		}
+	}
+}
+
+// runReplay проигрывает файл реплея с его сидом и шагом и сверяет контрольную сумму.
+// Возвращает код выхода процесса.
+func runReplay(path string, towerDefs map[string]*defs.TowerDefinition, out *log.Logger) int {
+	replay, err := app.LoadReplay(path)
+	if err != nil {
+		out.Printf("failed to load replay: %v", err)
+		return 2
+	}
+	rng := utils.NewPRNGService(replay.Seed)
+	game, err := app.NewGameOnMap(replay.Map, towerDefs, rng, replay.Level)
+	if err != nil {
+		out.Printf("failed to start the replay: %v", err)
+		return 2
+	}
+	game.EventDispatcher.Subscribe(event.WaveEnded, &waveReporter{game: game, out: out})
+
+	player := app.NewReplayPlayer(replay)
+	for !player.Done(game) {
+		player.Step(game)
+	}
+	if err := player.Verify(game); err != nil {
+		out.Printf("%v", err)
+		return 1
+	}
+	out.Printf("replay ok: %d commands, %d ticks, checksum %x", len(replay.Commands), game.Tick, replay.Checksum)
+	return 0
+}
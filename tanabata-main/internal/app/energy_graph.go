@@ -0,0 +1,245 @@
+// internal/app/energy_graph.go
+package app
+
+import (
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
+	"go-tower-defense/pkg/hexmap"
+	"sort"
+)
+
+// energyGraph is the incremental model of the energy network. Every non-wall
+// tower is a vertex, every connection allowed by the rules is an edge, and the
+// network is the minimum spanning forest of that graph under energyGraph.less:
+// the same forest a full Kruskal rebuild over all tower pairs would produce.
+//
+// Placing or removing a tower touches only the hexes around it: its direct
+// neighbors, relays on the same line within EnergyTransferRadius, and relay
+// pairs whose connection it blocks or unblocks.
+type energyGraph struct {
+	forest  *utils.DynamicForest
+	towerAt map[hexmap.Hex]types.EntityID
+	towers  map[types.EntityID]energyVertex
+	pinned  map[utils.Edge]bool // Lines dragged by the player: always kept, preferred over any other edge
+	banned  map[utils.Edge]bool // Lines the player dragged away: never built again
+}
+
+// energyVertex is what the graph remembers about a tower in the network.
+type energyVertex struct {
+	Hex  hexmap.Hex
+	Type defs.TowerType
+}
+
+func newEnergyGraph() *energyGraph {
+	eg := &energyGraph{
+		towerAt: make(map[hexmap.Hex]types.EntityID),
+		towers:  make(map[types.EntityID]energyVertex),
+		pinned:  make(map[utils.Edge]bool),
+		banned:  make(map[utils.Edge]bool),
+	}
+	eg.forest = utils.NewDynamicForest(eg.less)
+	return eg
+}
+
+// less orders edges for the spanning forest: pinned edges first, then by
+// calculateEdgeWeight, then by the smaller and the larger tower ID.
+func (eg *energyGraph) less(a, b utils.Edge) bool {
+	if pinA, pinB := eg.pinned[a], eg.pinned[b]; pinA != pinB {
+		return pinA
+	}
+	weightA, weightB := eg.weight(a), eg.weight(b)
+	if weightA != weightB {
+		return weightA < weightB
+	}
+	if a.A != b.A {
+		return a.A < b.A
+	}
+	return a.B < b.B
+}
+
+func (eg *energyGraph) weight(e utils.Edge) float64 {
+	va, vb := eg.towers[e.A], eg.towers[e.B]
+	return calculateEdgeWeight(va.Type, vb.Type, float64(va.Hex.Distance(vb.Hex)))
+}
+
+// insert adds a tower to the graph. Walls never take part in the network.
+func (eg *energyGraph) insert(id types.EntityID, hex hexmap.Hex, towerType defs.TowerType) {
+	if towerType == defs.TowerTypeWall || eg.forest.HasVertex(id) {
+		return
+	}
+	eg.towers[id] = energyVertex{Hex: hex, Type: towerType}
+	eg.towerAt[hex] = id
+	eg.forest.AddVertex(id)
+
+	if towerType.IsEnergyRelay() {
+		eg.refreshPairsThrough(hex) // a relay cuts long connections passing over it
+	}
+	for _, otherID := range eg.candidatesAround(id) {
+		eg.refresh(id, otherID)
+	}
+}
+
+// remove deletes a tower from the graph. Pins and bans are kept, so a tower that
+// is re-inserted after changing its type keeps the player's lines.
+func (eg *energyGraph) remove(id types.EntityID) {
+	vertex, ok := eg.towers[id]
+	if !ok {
+		return
+	}
+	eg.forest.RemoveVertex(id)
+	delete(eg.towers, id)
+	delete(eg.towerAt, vertex.Hex)
+	if vertex.Type.IsEnergyRelay() {
+		eg.refreshPairsThrough(vertex.Hex)
+	}
+}
+
+// forget drops the player's pins and bans involving a tower that left the game.
+func (eg *energyGraph) forget(id types.EntityID) {
+	for edge := range eg.pinned {
+		if edge.A == id || edge.B == id {
+			delete(eg.pinned, edge)
+		}
+	}
+	for edge := range eg.banned {
+		if edge.A == id || edge.B == id {
+			delete(eg.banned, edge)
+		}
+	}
+}
+
+// reconnect moves the line source-oldParent to source-newParent: the new line is
+// pinned and the old one banned. The rest of the forest adapts to keep it minimal.
+func (eg *energyGraph) reconnect(source, newParent, oldParent types.EntityID) {
+	eg.setOverride(utils.MakeEdge(source, newParent), true, false)
+	eg.setOverride(utils.MakeEdge(source, oldParent), false, true)
+}
+
+// setOverride changes the pin and ban flags of an edge. The edge leaves the forest
+// first, so its position in the order never changes while it is stored there.
+func (eg *energyGraph) setOverride(edge utils.Edge, pinned, banned bool) {
+	eg.forest.RemoveEdge(edge.A, edge.B)
+	delete(eg.pinned, edge)
+	delete(eg.banned, edge)
+	if pinned {
+		eg.pinned[edge] = true
+	}
+	if banned {
+		eg.banned[edge] = true
+	}
+	eg.refresh(edge.A, edge.B)
+}
+
+// refresh makes the presence of the edge a-b in the graph match the rules.
+func (eg *energyGraph) refresh(a, b types.EntityID) {
+	allowed := eg.allowed(a, b)
+	switch {
+	case allowed && !eg.forest.HasEdge(a, b):
+		eg.forest.AddEdge(a, b)
+	case !allowed && eg.forest.HasEdge(a, b):
+		eg.forest.RemoveEdge(a, b)
+	}
+}
+
+// allowed applies the player's overrides on top of isNaturalEdge.
+func (eg *energyGraph) allowed(a, b types.EntityID) bool {
+	edge := utils.MakeEdge(a, b)
+	if eg.pinned[edge] {
+		_, okA := eg.towers[a]
+		_, okB := eg.towers[b]
+		return okA && okB && a != b
+	}
+	return !eg.banned[edge] && eg.isNaturalEdge(a, b)
+}
+
+// isNaturalEdge is the connection rule of the energy network: direct neighbors,
+// or two relays on one line within EnergyTransferRadius with no relay between them.
+func (eg *energyGraph) isNaturalEdge(a, b types.EntityID) bool {
+	va, okA := eg.towers[a]
+	vb, okB := eg.towers[b]
+	if !okA || !okB || a == b {
+		return false
+	}
+	distance := va.Hex.Distance(vb.Hex)
+	if distance == 1 {
+		return true
+	}
+	if !va.Type.IsEnergyRelay() || !vb.Type.IsEnergyRelay() ||
+		distance > config.EnergyTransferRadius || !va.Hex.IsOnSameLine(vb.Hex) {
+		return false
+	}
+	line := va.Hex.LineTo(vb.Hex)
+	for _, hex := range line[1 : len(line)-1] {
+		if id, ok := eg.towerAt[hex]; ok && eg.towers[id].Type.IsEnergyRelay() {
+			return false
+		}
+	}
+	return true
+}
+
+// candidatesAround lists the towers that may connect to id: its neighbors, relays
+// on the same line within EnergyTransferRadius for a relay, and pinned partners.
+func (eg *energyGraph) candidatesAround(id types.EntityID) []types.EntityID {
+	vertex := eg.towers[id]
+	var result []types.EntityID
+	for _, dir := range hexmap.NeighborDirections {
+		maxStep := 1
+		if vertex.Type.IsEnergyRelay() {
+			maxStep = config.EnergyTransferRadius
+		}
+		for step := 1; step <= maxStep; step++ {
+			if otherID, ok := eg.towerAt[vertex.Hex.Add(dir.Scale(step))]; ok {
+				result = append(result, otherID)
+			}
+		}
+	}
+	for edge := range eg.pinned {
+		if edge.A == id {
+			result = append(result, edge.B)
+		} else if edge.B == id {
+			result = append(result, edge.A)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i] < result[j] })
+	return result
+}
+
+// refreshPairsThrough re-checks relay pairs whose straight connection passes over hex.
+func (eg *energyGraph) refreshPairsThrough(hex hexmap.Hex) {
+	axes := []hexmap.Hex{{Q: 1, R: 0}, {Q: 0, R: 1}, {Q: 1, R: -1}}
+	for _, dir := range axes {
+		for i := 1; i < config.EnergyTransferRadius; i++ {
+			for j := 1; i+j <= config.EnergyTransferRadius; j++ {
+				a, okA := eg.towerAt[hex.Add(dir.Scale(i))]
+				b, okB := eg.towerAt[hex.Add(dir.Scale(-j))]
+				if okA && okB {
+					eg.refresh(a, b)
+				}
+			}
+		}
+	}
+}
+
+// sortedOverrides returns pinned and banned edges in a stable order for saving.
+func (eg *energyGraph) sortedOverrides() (pinned, banned []utils.Edge) {
+	for edge := range eg.pinned {
+		pinned = append(pinned, edge)
+	}
+	for edge := range eg.banned {
+		banned = append(banned, edge)
+	}
+	sortEdgeList(pinned)
+	sortEdgeList(banned)
+	return pinned, banned
+}
+
+func sortEdgeList(edges []utils.Edge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].A != edges[j].A {
+			return edges[i].A < edges[j].A
+		}
+		return edges[i].B < edges[j].B
+	})
+}
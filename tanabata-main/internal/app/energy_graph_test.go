@@ -0,0 +1,274 @@
+// internal/app/energy_graph_test.go
+package app
+
+import (
+	"fmt"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
+	"go-tower-defense/pkg/hexmap"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// energyTestDefs are the tower kinds the random edits draw from: both relay types,
+// attackers, and walls, which never join the network.
+var energyTestDefs = []string{"TOWER_MINER", "TOWER_MINER", "TOWER_BATTERY", "TOWER_WALL", "TA", "TE", "DE"}
+
+func newEnergyTestGame(t *testing.T, seed int64) *Game {
+	t.Helper()
+	if err := defs.LoadAll("../../assets/data"); err != nil {
+		t.Fatalf("load definitions: %v", err)
+	}
+	towerDefs := make(map[string]*defs.TowerDefinition)
+	for id, def := range defs.TowerDefs {
+		d := def
+		towerDefs[id] = &d
+	}
+	rng := utils.NewPRNGService(seed)
+	return NewGame(hexmap.NewHexMap(rng.Fork("map")), towerDefs, rng)
+}
+
+// TestEnergyGraphMatchesFullRebuild applies random placements, removals, crafts
+// and line drags and checks after every edit that the incremental forest equals a
+// full Kruskal rebuild, and that every line is an edge of that forest.
+func TestEnergyGraphMatchesFullRebuild(t *testing.T) {
+	const (
+		seeds  = 20
+		steps  = 300
+		radius = 4 // 61 hexes: crowded enough for long relay links to be blocked and unblocked
+	)
+	var area []hexmap.Hex
+	for q := -radius; q <= radius; q++ {
+		for r := -radius; r <= radius; r++ {
+			if hex := (hexmap.Hex{Q: q, R: r}); hex.Distance(hexmap.Hex{}) <= radius {
+				area = append(area, hex)
+			}
+		}
+	}
+
+	for seed := int64(1); seed <= seeds; seed++ {
+		g := newEnergyTestGame(t, seed)
+		rng := utils.NewPRNGService(seed)
+		for step := 0; step < steps; step++ {
+			op := applyRandomEnergyEdit(g, rng, area)
+			want := g.fullEnergyForest()
+			got := g.energyGraph.forest.Edges()
+			if len(want) == 0 && len(got) == 0 {
+				continue
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Fatalf("seed %d, step %d (%s): forest differs from full rebuild\n got: %v\nwant: %v", seed, step, op, got, want)
+			}
+			for _, lineID := range entity.SortedIDs(g.ECS.LineRenders) {
+				line := g.ECS.LineRenders[lineID]
+				if !g.energyGraph.forest.InForest(line.Tower1ID, line.Tower2ID) {
+					t.Fatalf("seed %d, step %d (%s): line %d-%d is not a forest edge", seed, step, op, line.Tower1ID, line.Tower2ID)
+				}
+			}
+		}
+	}
+}
+
+// applyRandomEnergyEdit performs one random topology change and describes it.
+func applyRandomEnergyEdit(g *Game, rng *utils.PRNGService, area []hexmap.Hex) string {
+	towerIDs := entity.SortedIDs(g.ECS.Towers)
+	roll := rng.Intn(100)
+	switch {
+	case roll < 45 || len(towerIDs) == 0:
+		hex := area[rng.Intn(len(area))]
+		if _, occupied := g.getTowerAt(hex); occupied {
+			return "place on occupied hex"
+		}
+		defID := energyTestDefs[rng.Intn(len(energyTestDefs))]
+		id := g.createTowerEntity(hex, defID)
+		g.addTowerToEnergyNetwork(id)
+		return fmt.Sprintf("place %s #%d at %v", defID, id, hex)
+	case roll < 65:
+		id := towerIDs[rng.Intn(len(towerIDs))]
+		g.deleteTowerEntity(id)
+		g.syncEnergyLines()
+		return fmt.Sprintf("remove #%d", id)
+	case roll < 80:
+		id := towerIDs[rng.Intn(len(towerIDs))]
+		defID := energyTestDefs[rng.Intn(len(energyTestDefs))]
+		g.ECS.Towers[id].DefID = defID
+		g.retypeEnergyTower(id)
+		g.syncEnergyLines()
+		return fmt.Sprintf("craft #%d into %s", id, defID)
+	default:
+		edges := g.energyGraph.forest.Edges()
+		if len(edges) == 0 {
+			return "drag without lines"
+		}
+		edge := edges[rng.Intn(len(edges))]
+		source, parent := edge.A, edge.B
+		var targets []types.EntityID
+		for _, id := range towerIDs {
+			if id != source && id != parent && g.isValidConnection(g.ECS.Towers[source], g.ECS.Towers[id]) {
+				targets = append(targets, id)
+			}
+		}
+		if len(targets) == 0 {
+			return "drag without targets"
+		}
+		target := targets[rng.Intn(len(targets))]
+		g.reconnectTower(source, target, parent)
+		return fmt.Sprintf("drag #%d from #%d to #%d", source, parent, target)
+	}
+}
+
+// sortEnergyEdges provides a deterministic sort for energy edges.
+// It sorts by weight first, then by the tower IDs to resolve ties.
+func sortEnergyEdges(edges []energyEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		edgeA := edges[i]
+		edgeB := edges[j]
+		weightA := calculateEdgeWeight(edgeA.Type1, edgeA.Type2, edgeA.Distance)
+		weightB := calculateEdgeWeight(edgeB.Type1, edgeB.Type2, edgeB.Distance)
+
+		if weightA != weightB {
+			return weightA < weightB
+		}
+
+		// Tie-breaking logic using tower IDs for determinism.
+		// We sort the IDs within each edge to ensure consistency.
+		minA, maxA := edgeA.Tower1ID, edgeA.Tower2ID
+		if minA > maxA {
+			minA, maxA = maxA, minA
+		}
+		minB, maxB := edgeB.Tower1ID, edgeB.Tower2ID
+		if minB > maxB {
+			minB, maxB = maxB, minB
+		}
+
+		if minA != minB {
+			return minA < minB
+		}
+		return maxA < maxB
+	})
+}
+
+// fullEnergyForest computes the spanning forest from scratch with Kruskal's
+// algorithm over every tower pair, the way the network used to be rebuilt on every
+// change. It is the reference the incremental energy graph must always agree with.
+func (g *Game) fullEnergyForest() []utils.Edge {
+	allTowers := g.getAllTowersByHex()
+	potentiallyActive := g.getPotentiallyActiveTowers(allTowers)
+
+	var pinnedEdges, otherEdges []energyEdge
+	for edge := range g.energyGraph.pinned {
+		if potentiallyActive[edge.A] && potentiallyActive[edge.B] {
+			pinnedEdges = append(pinnedEdges, g.makeEnergyEdge(edge.A, edge.B))
+		}
+	}
+	for _, edge := range g.collectPossibleEdges(allTowers, potentiallyActive) {
+		key := utils.MakeEdge(edge.Tower1ID, edge.Tower2ID)
+		if !g.energyGraph.pinned[key] && !g.energyGraph.banned[key] {
+			otherEdges = append(otherEdges, edge)
+		}
+	}
+	sortEnergyEdges(pinnedEdges)
+	sortEnergyEdges(otherEdges)
+
+	uf := utils.NewUnionFind()
+	var forest []utils.Edge
+	for _, edge := range append(pinnedEdges, otherEdges...) {
+		if uf.Find(edge.Tower1ID) != uf.Find(edge.Tower2ID) {
+			uf.Union(edge.Tower1ID, edge.Tower2ID)
+			forest = append(forest, utils.MakeEdge(edge.Tower1ID, edge.Tower2ID))
+		}
+	}
+	sortEdgeList(forest)
+	return forest
+}
+
+// makeEnergyEdge describes the connection between two existing towers.
+func (g *Game) makeEnergyEdge(id1, id2 types.EntityID) energyEdge {
+	tower1, tower2 := g.ECS.Towers[id1], g.ECS.Towers[id2]
+	return energyEdge{
+		Tower1ID: id1,
+		Tower2ID: id2,
+		Type1:    defs.TowerDefs[tower1.DefID].Type,
+		Type2:    defs.TowerDefs[tower2.DefID].Type,
+		Distance: float64(tower1.Hex.Distance(tower2.Hex)),
+	}
+}
+
+func (g *Game) getPotentiallyActiveTowers(allTowers map[hexmap.Hex]types.EntityID) map[types.EntityID]bool {
+	potentiallyActive := make(map[types.EntityID]bool)
+	for _, id := range allTowers {
+		towerDef, ok := defs.TowerDefs[g.ECS.Towers[id].DefID]
+		if ok && towerDef.Type != defs.TowerTypeWall {
+			potentiallyActive[id] = true
+		}
+	}
+	return potentiallyActive
+}
+
+func (g *Game) collectPossibleEdges(allTowers map[hexmap.Hex]types.EntityID, potentiallyActive map[types.EntityID]bool) []energyEdge {
+	var edges []energyEdge
+	var activeHexes []hexmap.Hex
+	for hex, id := range allTowers {
+		if potentiallyActive[id] {
+			activeHexes = append(activeHexes, hex)
+		}
+	}
+
+	for i := 0; i < len(activeHexes); i++ {
+		for j := i + 1; j < len(activeHexes); j++ {
+			hexA, hexB := activeHexes[i], activeHexes[j]
+			idA, idB := allTowers[hexA], allTowers[hexB]
+			towerA, towerB := g.ECS.Towers[idA], g.ECS.Towers[idB]
+			defA := defs.TowerDefs[towerA.DefID]
+			defB := defs.TowerDefs[towerB.DefID]
+			distance := hexA.Distance(hexB)
+
+			isNeighbor := distance == 1
+			isMinerConnection := defA.Type.IsEnergyRelay() &&
+				defB.Type.IsEnergyRelay() &&
+				distance <= config.EnergyTransferRadius &&
+				hexA.IsOnSameLine(hexB) &&
+				!g.hasActiveTowerBetween(hexA, hexB, allTowers, potentiallyActive)
+
+			if isNeighbor || isMinerConnection {
+				edges = append(edges, energyEdge{
+					Tower1ID: idA,
+					Tower2ID: idB,
+					Type1:    defA.Type,
+					Type2:    defB.Type,
+					Distance: float64(distance),
+				})
+			}
+		}
+	}
+	return edges
+}
+
+func (g *Game) hasActiveTowerBetween(hexA, hexB hexmap.Hex, allTowers map[hexmap.Hex]types.EntityID, potentiallyActive map[types.EntityID]bool) bool {
+	line := hexA.LineTo(hexB)
+	for i := 1; i < len(line)-1; i++ {
+		if id, exists := allTowers[line[i]]; exists && potentiallyActive[id] {
+			// Проверяем тип башни, которая стоит на пути
+			towerOnPath := g.ECS.Towers[id]
+			towerOnPathDef := defs.TowerDefs[towerOnPath.DefID]
+			// Линия блокируется ТОЛЬКО если на пути стоит другая башня типа Б (Miner или Battery)
+			if towerOnPathDef.Type.IsEnergyRelay() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// getAllTowersByHex returns a map of all towers keyed by their hex coordinates.
+func (g *Game) getAllTowersByHex() map[hexmap.Hex]types.EntityID {
+	towers := make(map[hexmap.Hex]types.EntityID, len(g.ECS.Towers))
+	for id, tower := range g.ECS.Towers {
+		towers[tower.Hex] = id
+	}
+	return towers
+}
@@ -2,7 +2,6 @@
 package app
 
 import (
-	"go-tower-defense/internal/assets"
 	"go-tower-defense/internal/component"
 	"go-tower-defense/internal/config"
 	"go-tower-defense/internal/defs"
@@ -10,14 +9,10 @@ import (
 	"go-tower-defense/internal/event"
 	"go-tower-defense/internal/system"
 	"go-tower-defense/internal/types"
-	"go-tower-defense/internal/ui"
 	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
 	"log"
 	"math"
-	"math/rand"
-
-	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 // LineDragDebugInfo holds information for on-screen debugging.
@@ -29,14 +24,14 @@ type LineDragDebugInfo struct {
 }
 
 // Game holds the main game state and logic.
+// Game не зависит от raylib: отрисовка, шрифты, модели и ввод живут в пакете state,
+// поэтому симуляцию можно запускать без графического контекста.
 type Game struct {
 	HexMap                    *hexmap.HexMap
 	Wave                      int
 	BaseHealth                int
 	ECS                       *entity.ECS
 	MovementSystem            *system.MovementSystem
-	RenderSystem              *system.RenderSystemRL
-	ModelManager              *assets.ModelManager
 	WaveSystem                *system.WaveSystem
 	CombatSystem              *system.CombatSystem
 	ProjectileSystem          *system.ProjectileSystem
@@ -51,15 +46,19 @@ type Game struct {
 	AreaAttackSystem          *system.AreaAttackSystem
 	VolcanoSystem             *system.VolcanoSystem
 	BeaconSystem              *system.BeaconSystem
+	BatterySystem             *system.BatterySystem
+	EnemyAbilitySystem        *system.EnemyAbilitySystem
 	EventDispatcher           *event.Dispatcher
-	Font                      rl.Font // Изменено
-	Rng                       *utils.PRNGService
+	Rng                       *utils.PRNGService            // Корневой генератор; подсистемы получают свои ветки через Fork
+	rngStreams                map[string]*utils.PRNGService // Все ветки Rng по именам (для сохранения)
+	lootRng                   *utils.PRNGService
+	debugRng                  *utils.PRNGService
 	towersBuilt               int
-	SpeedButton               *ui.SpeedButtonRL // Изменеено
+	SpeedLevel                int // Текущий уровень ускорения (0..config.SpeedLevelCount-1)
 	SpeedMultiplier           float64
-	PauseButton               *ui.PauseButtonRL // Изменено
 	DebugTowerID              string
 	DebugInfo                 *LineDragDebugInfo
+	Tick                      uint64 // Количество выполненных шагов Update; к нему привязываются команды реплея
 
 	// Game state
 	gameTime               float64
@@ -71,6 +70,10 @@ type Game struct {
 	hiddenLineID           types.EntityID
 	highlightedTower       types.EntityID
 	manuallySelectedTowers []types.EntityID
+	powerNetworks          powerNetworkCache // Кэш компонент энергосети для потребителей руды
+	routeFlows             routeFlowCache    // Кэш полей потока маршрутов врагов
+	energyGraph            *energyGraph      // Инкрементальный граф энергосети, линии — его остовный лес
+	oreForecaster          *oreForecaster    // Прогноз истощения жил и сетей
 
 	// Line dragging state
 	isLineDragging       bool
@@ -79,15 +82,69 @@ type Game struct {
 	PlayerID             types.EntityID // ID сущности игрока
 	ClearedCheckpoints   map[hexmap.Hex]bool
 	FuturePath           []hexmap.Hex
-	OreVeinHexes         [][]hexmap.Hex // Гексы, принадлежащие каждой из трех жил
+	OreVeinHexes         [][]hexmap.Hex // Гексы, принадлежащие каждой жиле уровня
+	LevelID              string         // Уровень партии (см. defs.LevelDefs); задает генерацию руды
+	MapPath              string         // Файл карты, на которой начата партия; пусто — сгенерированная карта
+
+	// Запись действий игрока для реплея
+	recording    *Replay
+	commandDepth int
 }
 
-// NewGame initializes a new game instance.
-func NewGame(hexMap *hexmap.HexMap, font rl.Font, towerDefs map[string]*defs.TowerDefinition, modelManager *assets.ModelManager) *Game {
+// NewGame initializes a new game instance on the default level.
+// Вся случайность симуляции берется из веток rng, поэтому одинаковый сид
+// (и та же карта) при одинаковых действиях игрока дает одинаковый результат.
+func NewGame(hexMap *hexmap.HexMap, towerDefs map[string]*defs.TowerDefinition, rng *utils.PRNGService) *Game {
+	return NewGameForLevel(hexMap, towerDefs, rng, defs.DefaultLevelID)
+}
+
+// NewGameForLevel initializes a new game instance on the given level (see defs.LevelDefs).
+// Неизвестный уровень заменяется стандартным.
+func NewGameForLevel(hexMap *hexmap.HexMap, towerDefs map[string]*defs.TowerDefinition, rng *utils.PRNGService, levelID string) *Game {
+	g := newGameOnLevel(hexMap, towerDefs, rng, levelID)
+	g.generateOre(g.forkRng("ore"))
+	if terrain := g.Level().Terrain; !terrain.Empty() {
+		g.generateTerrain(g.forkRng("terrain"), terrain)
+	}
+	g.placeInitialStones()
+	g.createPlayerEntity()
+
+	return g
+}
+
+// newGameOnLevel создает пустую игру на карте и выбирает уровень партии.
+func newGameOnLevel(hexMap *hexmap.HexMap, towerDefs map[string]*defs.TowerDefinition, rng *utils.PRNGService, levelID string) *Game {
 	if hexMap == nil {
 		panic("hexMap cannot be nil")
 	}
+	if rng == nil {
+		rng = utils.NewPRNGService(0)
+	}
+	log.Printf("[SEED] %d", rng.Seed())
+
+	g := newGame(hexMap, towerDefs, rng)
+	if levelID == "" {
+		levelID = defs.DefaultLevelID // Реплеи и сохранения без уровня
+	}
+	if _, ok := defs.LevelDefs[levelID]; !ok {
+		log.Printf("Unknown level %q, using %q", levelID, defs.DefaultLevelID)
+		levelID = defs.DefaultLevelID
+	}
+	g.LevelID = levelID
+	return g
+}
+
+// Level возвращает определение уровня партии; неизвестный уровень заменяется стандартным.
+func (g *Game) Level() defs.LevelDefinition {
+	if level, ok := defs.LevelDefs[g.LevelID]; ok {
+		return level
+	}
+	return defs.LevelDefs[defs.DefaultLevelID]
+}
 
+// newGame создает игру с пустым миром: системы, ветки генератора и подписки на события.
+// Наполнение мира (руда, камни, игрок) делает NewGame или NewGameFromMapFile, а при загрузке — LoadGame.
+func newGame(hexMap *hexmap.HexMap, towerDefs map[string]*defs.TowerDefinition, rng *utils.PRNGService) *Game {
 	ecs := entity.NewECS()
 	eventDispatcher := event.NewDispatcher()
 	g := &Game{
@@ -97,38 +154,46 @@ func NewGame(hexMap *hexmap.HexMap, font rl.Font, towerDefs map[string]*defs.Tow
 		ECS:             ecs,
 		OreSystem:       system.NewOreSystem(ecs, eventDispatcher),
 		EventDispatcher: eventDispatcher,
-		Font:            font,
-		Rng:             utils.NewPRNGService(0),
+		Rng:             rng,
+		rngStreams:      make(map[string]*utils.PRNGService),
 		towersBuilt:     0,
+		SpeedMultiplier: 1.0,
 		gameTime:        0.0,
 		DebugTowerID:    "",
 		isGodMode:       false,
-		ModelManager:    modelManager, // Сохраняем менеджер
+		energyGraph:     newEnergyGraph(),
+		oreForecaster:   newOreForecaster(),
 	}
-	g.WaveSystem = system.NewWaveSystem(ecs, hexMap, eventDispatcher, g.Rng)
+	g.lootRng = g.forkRng("loot")
+	g.debugRng = g.forkRng("debug")
+	g.WaveSystem = system.NewWaveSystem(ecs, hexMap, eventDispatcher, g.forkRng("waves"))
 	// ВАЖНО: Системы, зависящие от g, создаются после инициализации g
-	g.MovementSystem = system.NewMovementSystem(ecs, g, g.Rng)
-	g.RenderSystem = system.NewRenderSystemRL(ecs, font, modelManager) // Передаем менеджер в рендер
-	g.CombatSystem = system.NewCombatSystem(ecs, eventDispatcher, g.FindPowerSourcesForTower, g.FindPathToPowerSource)
-	g.ProjectileSystem = system.NewProjectileSystem(ecs, eventDispatcher, g.CombatSystem, towerDefs)
+	g.MovementSystem = system.NewMovementSystem(ecs, g, g.forkRng("movement"))
+	oreDrawer := system.NewOreDrawer(ecs, g.powerSourceHops)
+	g.CombatSystem = system.NewCombatSystem(ecs, eventDispatcher, g.FindPowerSourcesForTower, g.FindPathToPowerSource, oreDrawer, g.forkRng("combat"))
+	g.ProjectileSystem = system.NewProjectileSystem(ecs, eventDispatcher, g.CombatSystem, towerDefs, g.forkRng("projectiles"))
 	g.StateSystem = system.NewStateSystem(ecs, g, eventDispatcher)
 	g.AuraSystem = system.NewAuraSystem(ecs)
 	g.StatusEffectSystem = system.NewStatusEffectSystem(ecs)
-	g.EnvironmentalDamageSystem = system.NewEnvironmentalDamageSystem(ecs)
+	g.EnemyAbilitySystem = system.NewEnemyAbilitySystem(ecs, g)
+	g.EnvironmentalDamageSystem = system.NewEnvironmentalDamageSystem(ecs, g.FindPowerSourcesForTower)
 	g.VisualEffectSystem = system.NewVisualEffectSystem(ecs)
 	g.CraftingSystem = system.NewCraftingSystem(ecs)
 	g.PlayerSystem = system.NewPlayerSystem(ecs)
 	g.AreaAttackSystem = system.NewAreaAttackSystem(ecs)
-	g.VolcanoSystem = system.NewVolcanoSystem(ecs, g.FindPowerSourcesForTower)
-	g.BeaconSystem = system.NewBeaconSystem(ecs, g.FindPowerSourcesForTower)
-	g.generateOre()
-	g.initUI()
+	g.VolcanoSystem = system.NewVolcanoSystem(ecs, g.FindPowerSourcesForTower, oreDrawer, g.forkRng("volcano"))
+	g.BeaconSystem = system.NewBeaconSystem(ecs, g.FindPowerSourcesForTower, oreDrawer, g.forkRng("beacon"))
+	g.BatterySystem = system.NewBatterySystem(ecs, eventDispatcher, g.FindPowerSourcesForTower)
 
 	listener := &GameEventListener{game: g}
 	eventDispatcher.Subscribe(event.OreDepleted, listener)
+	eventDispatcher.Subscribe(event.OreRestored, listener)
 	eventDispatcher.Subscribe(event.WaveEnded, listener)
 	eventDispatcher.Subscribe(event.CombineTowersRequest, listener)
 	eventDispatcher.Subscribe(event.ToggleTowerSelectionForSaveRequest, listener)
+	eventDispatcher.Subscribe(event.ToggleBatteryModeRequest, listener)
+	eventDispatcher.Subscribe(event.ToggleTowerEnabledRequest, listener)
+	eventDispatcher.Subscribe(event.CycleOreDrawPolicyRequest, listener)
 
 	eventDispatcher.Subscribe(event.TowerPlaced, g.CraftingSystem)
 	eventDispatcher.Subscribe(event.TowerRemoved, g.CraftingSystem)
@@ -136,12 +201,17 @@ func NewGame(hexMap *hexmap.HexMap, font rl.Font, towerDefs map[string]*defs.Tow
 	eventDispatcher.Subscribe(event.EnemyKilled, g.PlayerSystem)
 	eventDispatcher.Subscribe(event.EnemyKilled, g.ProjectileSystem)
 
-	g.placeInitialStones()
-	g.createPlayerEntity()
-
 	return g
 }
 
+// forkRng создает именованную ветку корневого генератора и запоминает ее,
+// чтобы состояние всех веток попадало в сохранение.
+func (g *Game) forkRng(name string) *utils.PRNGService {
+	stream := g.Rng.Fork(name)
+	g.rngStreams[name] = stream
+	return stream
+}
+
 // GetHexMap возвращает карту гексов (для MovementSystem)
 func (g *Game) GetHexMap() *hexmap.HexMap {
 	return g.HexMap
@@ -157,11 +227,6 @@ func (g *Game) GetEnemies() map[types.EntityID]*component.Enemy {
 	return g.ECS.Enemies
 }
 
-// GetFont возвращает шрифт, используемый в игре.
-func (g *Game) GetFont() rl.Font {
-	return g.Font
-}
-
 // GetGame возвращает сам экземпляр игры для соответствия интерфейсу.
 func (g *Game) GetGame() *Game {
 	return g
@@ -172,14 +237,22 @@ func (g *Game) IsGodMode() bool {
 	return g.isGodMode
 }
 
+// IsGameOver возвращает true, если здоровье игрока исчерпано.
+func (g *Game) IsGameOver() bool {
+	playerState, ok := g.ECS.PlayerState[g.PlayerID]
+	return ok && playerState.Health <= 0
+}
+
 // ToggleGodMode переключает режим бессмертия.
 func (g *Game) ToggleGodMode() {
+	defer g.beginCommand(Command{Type: CmdToggleGodMode})()
 	g.isGodMode = !g.isGodMode
 	log.Printf("God Mode toggled: %v", g.isGodMode)
 }
 
 // CombineTowers выполняет логику объединения башен.
 func (g *Game) CombineTowers(clickedTowerID types.EntityID) {
+	defer g.beginCommand(Command{Type: CmdCombineTowers, TowerID: clickedTowerID})()
 	combinable, ok := g.ECS.Combinables[clickedTowerID]
 	if !ok || len(combinable.PossibleCrafts) == 0 {
 		return
@@ -199,12 +272,12 @@ func (g *Game) CombineTowers(clickedTowerID types.EntityID) {
 			var combatExists bool
 			if combat, combatExists = g.ECS.Combats[clickedTowerID]; combatExists {
 				combat.FireRate = outputDef.Combat.FireRate
-				combat.Range = outputDef.Combat.Range
+				combat.Range = g.towerRange(tower.Hex, outputDef.Combat.Range)
 				combat.ShotCost = outputDef.Combat.ShotCost
 			} else {
 				combat = &component.Combat{
 					FireRate: outputDef.Combat.FireRate,
-					Range:    outputDef.Combat.Range,
+					Range:    g.towerRange(tower.Hex, outputDef.Combat.Range),
 					ShotCost: outputDef.Combat.ShotCost,
 				}
 			}
@@ -223,6 +296,14 @@ func (g *Game) CombineTowers(clickedTowerID types.EntityID) {
 			renderable.Color = outputDef.Visuals.Color
 			renderable.Radius = float32(config.HexSize * outputDef.Visuals.RadiusFactor)
 		}
+
+		if outputDef.Type == defs.TowerTypeBattery {
+			if _, exists := g.ECS.Batteries[clickedTowerID]; !exists {
+				g.ECS.Batteries[clickedTowerID] = &component.Battery{}
+			}
+		} else {
+			delete(g.ECS.Batteries, clickedTowerID)
+		}
 	}
 
 	wallDef := defs.TowerDefs["TOWER_WALL"]
@@ -233,6 +314,7 @@ func (g *Game) CombineTowers(clickedTowerID types.EntityID) {
 		if tower, ok := g.ECS.Towers[id]; ok {
 			delete(g.ECS.Combats, id)
 			delete(g.ECS.Auras, id)
+			delete(g.ECS.Batteries, id)
 			tower.DefID = "TOWER_WALL"
 			if renderable, ok := g.ECS.Renderables[id]; ok {
 				renderable.Color = wallDef.Visuals.Color
@@ -241,19 +323,26 @@ func (g *Game) CombineTowers(clickedTowerID types.EntityID) {
 		}
 	}
 
+	g.retypeEnergyTower(clickedTowerID)
+	for _, id := range combination {
+		if id != clickedTowerID {
+			g.removeTowerFromEnergyGraph(id)
+		}
+	}
+
 	g.CraftingSystem.RecalculateCombinations()
 	g.AuraSystem.RecalculateAuras()
-	g.rebuildEnergyNetwork()
+	g.syncEnergyLines()
 }
 
 // FindPathToPowerSource находит кратчайший путь от атакующей башни до ближайшего
-// источника энергии (башни-добытчика на активной руде).
+// источника энергии (добытчика на активной руде или разряжающейся батареи).
 func (g *Game) FindPathToPowerSource(startNode types.EntityID) []types.EntityID {
 	if _, exists := g.ECS.Towers[startNode]; !exists {
 		return nil
 	}
 
-	adj := g.buildAdjacencyList()
+	adj := g.powerAdjacency()
 	queue := []types.EntityID{startNode}
 	visited := map[types.EntityID]bool{startNode: true}
 	parent := make(map[types.EntityID]types.EntityID)
@@ -266,11 +355,10 @@ func (g *Game) FindPathToPowerSource(startNode types.EntityID) []types.EntityID
 		head++
 
 		tower := g.ECS.Towers[currentID]
-		towerDef, ok := defs.TowerDefs[tower.DefID]
-		if !ok {
+		if _, ok := defs.TowerDefs[tower.DefID]; !ok {
 			continue
 		}
-		if towerDef.Type == defs.TowerTypeMiner && g.isOnOre(tower.Hex) {
+		if g.isPowerRoot(currentID) {
 			pathEnd = currentID
 			break
 		}
@@ -310,15 +398,11 @@ type GameEventListener struct {
 // OnEvent реализует интерфейс event.Listener.
 func (l *GameEventListener) OnEvent(e event.Event) {
 	switch e.Type {
-	case event.OreDepleted:
-		poweredSet := l.game.findPoweredTowers()
-		for id, tower := range l.game.ECS.Towers {
-			_, isPowered := poweredSet[id]
-			tower.IsActive = isPowered
-		}
-		l.game.cleanupOrphanedLines()
-		l.game.updateAllTowerAppearances()
+	case event.OreDepleted, event.OreRestored:
+		l.game.syncEnergyLines()
 	case event.WaveEnded:
+		// Прогноз фиксирует расход жил за волну до того, как фаза строительства их пополнит
+		l.game.finishOreForecastWave()
 		// Логируем состояние руды в конце волны
 		playerState, ok := l.game.ECS.PlayerState[l.game.PlayerID]
 		if ok {
@@ -326,6 +410,10 @@ func (l *GameEventListener) OnEvent(e event.Event) {
 			consumption := l.game.CalculateOreConsumptionRate()
 			log.Printf("[ORE_ANALYSIS] Wave %d Ended | Level: %d, Reserve: %.1f, ConsumptionRate: %.1f/s",
 				l.game.Wave-1, playerState.Level, reserve, consumption)
+			for i, vein := range l.game.OreForecast().Veins {
+				log.Printf("[ORE_ANALYSIS]   Vein %d | Reserve: %.1f, Spent: %.1f, Refill: %.1f, WavesLeft: %.1f",
+					i, vein.Reserve, vein.SpentLastWave, vein.Refill, vein.Waves)
+			}
 		}
 		l.game.StateSystem.SwitchToBuildState()
 	case event.CombineTowersRequest:
@@ -336,6 +424,18 @@ func (l *GameEventListener) OnEvent(e event.Event) {
 		if towerID, ok := e.Data.(types.EntityID); ok {
 			l.game.ToggleTowerSelectionForSave(towerID)
 		}
+	case event.ToggleBatteryModeRequest:
+		if towerID, ok := e.Data.(types.EntityID); ok {
+			l.game.ToggleBatteryMode(towerID)
+		}
+	case event.ToggleTowerEnabledRequest:
+		if towerID, ok := e.Data.(types.EntityID); ok {
+			l.game.ToggleTowerEnabled(towerID)
+		}
+	case event.CycleOreDrawPolicyRequest:
+		if towerID, ok := e.Data.(types.EntityID); ok {
+			l.game.CycleOreDrawPolicy(towerID)
+		}
 	}
 }
 
@@ -363,29 +463,32 @@ func (g *Game) placeInitialStones() {
 	}
 }
 
+// UpcomingWave возвращает определение волны, которая начнется следующей (номер g.Wave).
+func (g *Game) UpcomingWave() (defs.WaveDefinition, bool) {
+	return defs.GetWaveDefinition(g.Wave)
+}
+
 // Update progresses the game state by one frame.
 func (g *Game) Update(deltaTime float64) {
-	// Обработка перезагрузки моделей по F5
-	if rl.IsKeyPressed(rl.KeyF5) {
-		// Создаем карту указателей для передачи в менеджер моделей
-		towerDefPtrs := make(map[string]*defs.TowerDefinition)
-		for id, def := range defs.TowerDefs {
-			d := def
-			towerDefPtrs[id] = &d
-		}
-		g.ModelManager.ReloadTowerModels(towerDefPtrs)
-	}
+	// Всё, что происходит внутри шага симуляции, — не действия игрока и не записывается
+	g.commandDepth++
+	defer func() { g.commandDepth-- }()
+	g.Tick++
+
+	g.checkTowerSelectionComplete()
 
 	dt := deltaTime * g.SpeedMultiplier
 	g.gameTime += dt
 	g.ECS.GameTime = g.gameTime
 
-	g.RenderSystem.Update(dt)
 	g.VisualEffectSystem.Update(dt)
+	g.updatePowerFlow()
 
 	if g.ECS.GameState.Phase == component.WaveState {
 		g.UpdateCheckpointHighlighting() // <-- НОВЫЙ ВЫЗОВ
+		g.EnemyAbilitySystem.Update(dt)
 		g.StatusEffectSystem.Update(dt)
+		g.BatterySystem.Update(dt)
 		g.VolcanoSystem.Update(dt)
 		g.BeaconSystem.Update(dt)
 		g.AreaAttackSystem.Update(dt)
@@ -397,6 +500,26 @@ func (g *Game) Update(deltaTime float64) {
 		g.cleanupDestroyedEntities()
 	}
 	g.OreSystem.Update()
+	g.updateOreForecast(dt)
+}
+
+// checkTowerSelectionComplete завершает фазу выбора, как только игрок
+// отметил нужное количество временных башен, и запускает волну.
+func (g *Game) checkTowerSelectionComplete() {
+	if g.ECS.GameState.Phase != component.TowerSelectionState {
+		return
+	}
+	selectedCount := 0
+	for _, tower := range g.ECS.Towers {
+		if tower.IsTemporary && tower.IsSelected {
+			selectedCount++
+		}
+	}
+	if selectedCount == g.ECS.GameState.TowersToKeep {
+		g.FinalizeTowerSelection()
+		g.ECS.GameState.Phase = component.WaveState
+		g.StartWave()
+	}
 }
 
 // UpdateCheckpointHighlighting динамически обновляет подсветку чекпоинтов.
@@ -423,9 +546,10 @@ func (g *Game) UpdateCheckpointHighlighting() {
 	// 3. Если враг найден, подсветить все пройденные им чекпоинты
 	if lastLivingEnemy != nil {
 		lastIndex := lastLivingEnemy.LastCheckpointIndex
-		if lastIndex >= 0 && lastIndex < len(g.HexMap.Checkpoints) {
+		checkpoints := g.HexMap.Gate(lastLivingEnemy.Gate).Checkpoints
+		if lastIndex >= 0 && lastIndex < len(checkpoints) {
 			for i := 0; i <= lastIndex; i++ {
-				g.ClearedCheckpoints[g.HexMap.Checkpoints[i]] = true
+				g.ClearedCheckpoints[checkpoints[i]] = true
 			}
 		}
 	}
@@ -457,88 +581,31 @@ func (g *Game) CalculateOreConsumptionRate() float64 {
 }
 
 // UpdateFuturePath рассчитывает и сохраняет путь, по которому пойдут следующие враги.
+// На карте с несколькими входами это маршруты всех входов подряд.
 func (g *Game) UpdateFuturePath() {
-	wallHexes, _, _ := g.GetTowerHexesByType()
-	tempMap := g.HexMap.Clone()
-	for _, h := range wallHexes {
-		if tile, ok := tempMap.Tiles[h]; ok {
-			tile.Passable = false
-			tempMap.Tiles[h] = tile
-		}
-	}
-
 	var fullPath []hexmap.Hex
-	currentStart := g.HexMap.Entry
-
-	// Путь от входа до первого чекпоинта
-	allCheckpoints := g.HexMap.Checkpoints
-	if len(allCheckpoints) > 0 {
-		for _, checkpoint := range allCheckpoints {
-			segment := hexmap.AStar(currentStart, checkpoint, tempMap)
-			if segment == nil {
-				g.FuturePath = nil // Если хоть один сегмент не найден, полного пути нет
-				return
-			}
-			// Добавляем сегмент, исключая его первую точку, чтобы избежать дублирования
-			if len(fullPath) > 0 {
-				fullPath = append(fullPath, segment[1:]...)
-			} else {
-				fullPath = append(fullPath, segment...)
-			}
-			currentStart = checkpoint
+	for _, route := range g.ensureRouteFlows().routes {
+		if route == nil {
+			g.FuturePath = nil // Если хоть один маршрут перекрыт, полного пути нет
+			return
 		}
+		fullPath = append(fullPath, route...)
 	}
-
-	// Путь от последнего чекпоинта (или от входа, если чекпоинтов нет) до выхода
-	finalSegment := hexmap.AStar(currentStart, g.HexMap.Exit, tempMap)
-	if finalSegment == nil {
-		g.FuturePath = nil
-		return
-	}
-
-	if len(fullPath) > 0 {
-		fullPath = append(fullPath, finalSegment[1:]...)
-	} else {
-		fullPath = append(fullPath, finalSegment...)
-	}
-
 	g.FuturePath = fullPath
 }
 
 // StartWave begins the enemy wave.
 func (g *Game) StartWave() {
 	g.ClearedCheckpoints = make(map[hexmap.Hex]bool) // Сбрасываем чекпоинты
-	g.ECS.Wave = g.WaveSystem.StartWave(g.Wave)
+	g.ECS.Wave = g.WaveSystem.StartWave(g.Wave, g.RouteFlows())
 	g.WaveSystem.ResetActiveEnemies()
 	g.Wave++
 }
 
 // --- Private Helper Functions ---
 
-func (g *Game) initUI() {
-	// Рассчитываем X-координаты кнопок, между которыми нужно вставить нашу
-	pauseButtonX := float32(config.ScreenWidth - config.IndicatorOffsetX - 90)
-	indicatorX := float32(config.ScreenWidth - config.IndicatorOffsetX)
-
-	// Находим среднюю точку для симметричного расположения и добавляем небольшое смещение вправо
-	speedButtonX := (pauseButtonX+indicatorX)/2 + 2
-
-	g.SpeedButton = ui.NewSpeedButtonRL(
-		speedButtonX, // Новая, вычисленная координата X
-		float32(config.SpeedButtonY),
-		float32(config.SpeedButtonSize),
-	)
-	g.SpeedMultiplier = 1.0
-
-	g.PauseButton = ui.NewPauseButtonRL(
-		float32(config.ScreenWidth-config.IndicatorOffsetX-90),
-		float32(config.IndicatorOffsetX),
-		float32(config.IndicatorRadius),
-	)
-}
-
 func (g *Game) cleanupDestroyedEntities() {
-	for id := range g.ECS.Enemies {
+	for _, id := range entity.SortedIDs(g.ECS.Enemies) {
 		path, hasPath := g.ECS.Paths[id]
 		reachedEnd := hasPath && path.CurrentIndex >= len(path.Hexes)
 
@@ -556,6 +623,7 @@ func (g *Game) cleanupDestroyedEntities() {
 			delete(g.ECS.Healths, id)
 			delete(g.ECS.Renderables, id)
 			delete(g.ECS.Enemies, id)
+			delete(g.ECS.EnemyAbilities, id)
 			g.EventDispatcher.Dispatch(event.Event{Type: event.EnemyRemovedFromGame, Data: id})
 		}
 	}
@@ -571,6 +639,7 @@ func (g *Game) ClearEnemies() {
 		delete(g.ECS.Healths, id)
 		delete(g.ECS.Renderables, id)
 		delete(g.ECS.Enemies, id)
+		delete(g.ECS.EnemyAbilities, id)
 	}
 }
 
@@ -584,6 +653,7 @@ func (g *Game) ClearProjectiles() {
 }
 
 func (g *Game) HandleIndicatorClick() {
+	defer g.beginCommand(Command{Type: CmdIndicatorClick})()
 	if g.ECS.GameState.Phase == component.BuildState {
 		g.StateSystem.SwitchToWaveState()
 		g.ClearAllSelections()
@@ -592,9 +662,11 @@ func (g *Game) HandleIndicatorClick() {
 	}
 }
 
+// HandleSpeedClick переключает уровень ускорения: x1 -> x2 -> x4 -> x1.
 func (g *Game) HandleSpeedClick() {
-	g.SpeedButton.ToggleState()
-	g.SpeedMultiplier = math.Pow(2, float64(g.SpeedButton.CurrentState))
+	defer g.beginCommand(Command{Type: CmdSpeedClick})()
+	g.SpeedLevel = (g.SpeedLevel + 1) % config.SpeedLevelCount
+	g.SpeedMultiplier = math.Pow(2, float64(g.SpeedLevel))
 }
 
 // GetTowerHexesByType возвращает гексы, сгруппированные по типу башни.
@@ -606,8 +678,8 @@ func (g *Game) GetTowerHexesByType() ([]hexmap.Hex, []hexmap.Hex, []hexmap.Hex)
 		if !ok {
 			continue
 		}
-		isTypeA := towerDef.Type != defs.TowerTypeWall && towerDef.Type != defs.TowerTypeMiner
-		isTypeB := towerDef.Type == defs.TowerTypeMiner
+		isTypeA := towerDef.Type != defs.TowerTypeWall && !towerDef.Type.IsEnergyRelay()
+		isTypeB := towerDef.Type.IsEnergyRelay()
 
 		if towerDef.Type == defs.TowerTypeWall {
 			wallHexes = append(wallHexes, tower.Hex)
@@ -629,6 +701,11 @@ func (g *Game) GetAllTowerHexes() []hexmap.Hex {
 	return allHexes
 }
 
+// GetTowerIDAtHex возвращает ID башни на указанном гексе, если она существует.
+func (g *Game) GetTowerIDAtHex(hex hexmap.Hex) (types.EntityID, bool) {
+	return g.getTowerAt(hex)
+}
+
 // GetTowerAtHex возвращает башню на указанном гексе, если она существует.
 func (g *Game) GetTowerAtHex(hex hexmap.Hex) (*component.Tower, bool) {
 	for _, tower := range g.ECS.Towers {
@@ -653,13 +730,74 @@ func (g *Game) GetGameTime() float64 {
 
 // ToggleTowerSelectionForSave инвертирует состояние IsSelected для башни.
 func (g *Game) ToggleTowerSelectionForSave(id types.EntityID) {
+	defer g.beginCommand(Command{Type: CmdToggleTowerSelectionForSave, TowerID: id})()
 	if tower, ok := g.ECS.Towers[id]; ok {
 		tower.IsSelected = !tower.IsSelected
 	}
 }
 
+// ToggleBatteryMode переключает батарею между зарядкой и разрядкой. Разряжающаяся
+// батарея с запасом сама становится источником энергии, поэтому после переключения
+// линии и питание сети пересчитываются.
+func (g *Game) ToggleBatteryMode(id types.EntityID) {
+	defer g.beginCommand(Command{Type: CmdToggleBatteryMode, TowerID: id})()
+	battery, ok := g.ECS.Batteries[id]
+	if !ok {
+		return
+	}
+	if battery.Mode == component.BatteryCharging {
+		battery.Mode = component.BatteryDischarging
+	} else {
+		battery.Mode = component.BatteryCharging
+	}
+	g.syncEnergyLines()
+}
+
+// CycleOreDrawPolicy переключает политику выбора источника руды на следующую.
+// Для башни (towerID != 0) список начинается с OreDrawInherit — "как в игре",
+// для всей игры (towerID == 0) этот вариант пропускается.
+func (g *Game) CycleOreDrawPolicy(towerID types.EntityID) {
+	defer g.beginCommand(Command{Type: CmdCycleOreDrawPolicy, TowerID: towerID})()
+	if towerID == 0 {
+		next := g.ECS.GameState.OreDrawPolicy%(component.OreDrawPolicyCount-1) + 1
+		g.ECS.GameState.OreDrawPolicy = next
+		return
+	}
+	tower, ok := g.ECS.Towers[towerID]
+	if !ok {
+		return
+	}
+	tower.OreDrawPolicy = (tower.OreDrawPolicy + 1) % component.OreDrawPolicyCount
+	tower.OreDrawCursor = 0
+}
+
+// ToggleTowerEnabled включает или выключает башню по команде игрока. Выключенная башня
+// остается в сети и передает энергию дальше, но не стреляет и не тратит руду, а
+// добытчик или батарея перестают быть источником. Топология сети при этом не меняется:
+// линии сети с выключенным источником сохраняются (см. canBePowerRoot), пересчитывается только питание.
+func (g *Game) ToggleTowerEnabled(id types.EntityID) {
+	defer g.beginCommand(Command{Type: CmdToggleTowerEnabled, TowerID: id})()
+	tower, ok := g.ECS.Towers[id]
+	if !ok {
+		return
+	}
+	towerDef, ok := defs.TowerDefs[tower.DefID]
+	if !ok || towerDef.Type == defs.TowerTypeWall {
+		return
+	}
+	tower.IsManuallyDisabled = !tower.IsManuallyDisabled
+	g.invalidatePowerNetworks()
+
+	if !towerDef.Type.IsEnergyRelay() {
+		g.updateTowerAppearance(id)
+		return
+	}
+	g.syncEnergyLines()
+}
+
 // SetHighlightedTower устанавливает башню, которая должна быть подсвечена для UI.
 func (g *Game) SetHighlightedTower(id types.EntityID) {
+	defer g.beginCommand(Command{Type: CmdSetHighlightedTower, TowerID: id})()
 	if g.highlightedTower != 0 {
 		if tower, ok := g.ECS.Towers[g.highlightedTower]; ok {
 			tower.IsHighlighted = false
@@ -677,6 +815,9 @@ func (g *Game) SetHighlightedTower(id types.EntityID) {
 func (g *Game) GetOreHexes() map[hexmap.Hex]float64 {
 	oreHexes := make(map[hexmap.Hex]float64)
 	for _, ore := range g.ECS.Ores {
+		if ore.Depleted {
+			continue
+		}
 		hex := utils.ScreenToHex(ore.Position.X, ore.Position.Y)
 		oreHexes[hex] = ore.Power
 	}
@@ -686,6 +827,7 @@ func (g *Game) GetOreHexes() map[hexmap.Hex]float64 {
 // --- Функции для режима перетаскивания линий ---
 
 func (g *Game) ClearAllSelections() {
+	defer g.beginCommand(Command{Type: CmdClearAllSelections})()
 	g.SetHighlightedTower(0)
 
 	if len(g.manuallySelectedTowers) > 0 {
@@ -705,6 +847,7 @@ func (g *Game) IsInLineDragMode() bool {
 }
 
 func (g *Game) ClearManualSelection() {
+	defer g.beginCommand(Command{Type: CmdClearManualSelection})()
 	if len(g.manuallySelectedTowers) == 0 {
 		return
 	}
@@ -718,6 +861,7 @@ func (g *Game) ClearManualSelection() {
 }
 
 func (g *Game) HandleShiftClick(hex hexmap.Hex, isLeftClick, isRightClick bool) {
+	defer g.beginCommand(Command{Type: CmdShiftClick, Hex: hex, Right: isRightClick && !isLeftClick})()
 	clickedTowerID, clickedOnTower := g.getTowerAt(hex)
 	if !clickedOnTower {
 		return
@@ -761,15 +905,19 @@ func (g *Game) HandleShiftClick(hex hexmap.Hex, isLeftClick, isRightClick bool)
 }
 
 func (g *Game) ToggleLineDragMode() {
+	defer g.beginCommand(Command{Type: CmdToggleLineDragMode})()
 	g.isLineDragging = !g.isLineDragging
 	if !g.isLineDragging {
 		g.CancelLineDrag()
 	}
 }
 
-func (g *Game) HandleLineDragClick(hex hexmap.Hex, hitPoint rl.Vector3) {
+// HandleLineDragClick обрабатывает клик в режиме перетаскивания линий.
+// hitX, hitY — точка клика в пиксельных координатах карты (как у Hex.ToPixel).
+func (g *Game) HandleLineDragClick(hex hexmap.Hex, hitX, hitY float64) {
+	defer g.beginCommand(Command{Type: CmdLineDragClick, Hex: hex, X: hitX, Y: hitY})()
 	if g.dragSourceTowerID == 0 {
-		g.startLineDrag(hex, hitPoint)
+		g.startLineDrag(hex, hitX, hitY)
 		return
 	}
 	g.finishLineDrag(hex)
@@ -835,12 +983,8 @@ func (g *Game) findPoweredTowersWithAdj(adj map[types.EntityID][]types.EntityID)
 	poweredSet := make(map[types.EntityID]struct{})
 	queue := []types.EntityID{}
 
-	for id, tower := range g.ECS.Towers {
-		towerDef, ok := defs.TowerDefs[tower.DefID]
-		if !ok {
-			continue
-		}
-		if towerDef.Type == defs.TowerTypeMiner && g.isOnOre(tower.Hex) {
+	for id := range g.ECS.Towers {
+		if g.isPowerRoot(id) {
 			queue = append(queue, id)
 			poweredSet[id] = struct{}{}
 		}
@@ -864,25 +1008,11 @@ func (g *Game) findPoweredTowersWithAdj(adj map[types.EntityID][]types.EntityID)
 	return poweredSet
 }
 
+// reconnectTower переносит линию source-originalParent на source-target. Новая линия
+// закрепляется в графе энергосети, старая запрещается, остальная сеть остается минимальной.
 func (g *Game) reconnectTower(sourceID, targetID, originalParentID types.EntityID) {
-	if g.hiddenLineID != 0 {
-		delete(g.ECS.LineRenders, g.hiddenLineID)
-	}
-
-	sourceTower := g.ECS.Towers[sourceID]
-	targetTower := g.ECS.Towers[targetID]
-	sourceDef := defs.TowerDefs[sourceTower.DefID]
-	targetDef := defs.TowerDefs[targetTower.DefID]
-	g.createLine(energyEdge{
-		Tower1ID: sourceID,
-		Tower2ID: targetID,
-		Type1:    sourceDef.Type,
-		Type2:    targetDef.Type,
-		Distance: float64(sourceTower.Hex.Distance(targetTower.Hex)),
-	})
-
-	g.cleanupOrphanedLines()
-	g.updateAllTowerAppearances()
+	g.energyGraph.reconnect(sourceID, targetID, originalParentID)
+	g.syncEnergyLines()
 }
 
 func removeElement(slice []types.EntityID, element types.EntityID) []types.EntityID {
@@ -895,7 +1025,7 @@ func removeElement(slice []types.EntityID, element types.EntityID) []types.Entit
 	return result
 }
 
-func (g *Game) startLineDrag(hex hexmap.Hex, hitPoint rl.Vector3) {
+func (g *Game) startLineDrag(hex hexmap.Hex, hitX, hitY float64) {
 	g.DebugInfo = nil
 
 	sourceID, ok := g.getTowerAt(hex)
@@ -904,10 +1034,8 @@ func (g *Game) startLineDrag(hex hexmap.Hex, hitPoint rl.Vector3) {
 	}
 
 	tower := g.ECS.Towers[sourceID]
-	towerDef := defs.TowerDefs[tower.DefID]
-	// Запрещаем перетаскивать линии от корневых майнеров (которые стоят на руде)
-	isRootMiner := towerDef.Type == defs.TowerTypeMiner && g.isOnOre(tower.Hex)
-	if isRootMiner {
+	// Запрещаем перетаскивать линии от корней сети (майнеров на руде и заряженных батарей)
+	if g.isPowerRoot(sourceID) {
 		return
 	}
 
@@ -917,11 +1045,11 @@ func (g *Game) startLineDrag(hex hexmap.Hex, hitPoint rl.Vector3) {
 		return
 	}
 
-	// Получаем 3D позицию исходной башни
-	sourcePos3D := g.hexToWorld(tower.Hex)
+	// Получаем позицию исходной башни на карте
+	sourceX, sourceY := tower.Hex.ToPixel(config.HexSize)
 
-	// Вычисляем угол клика на плоскости XZ
-	clickAngle := math.Atan2(float64(hitPoint.Z-sourcePos3D.Z), float64(hitPoint.X-sourcePos3D.X))
+	// Вычисляем угол клика в плоскости карты
+	clickAngle := math.Atan2(hitY-sourceY, hitX-sourceX)
 
 	var bestMatchID types.EntityID
 	minAngleDiff := math.Pi
@@ -931,11 +1059,11 @@ func (g *Game) startLineDrag(hex hexmap.Hex, hitPoint rl.Vector3) {
 		if !ok {
 			continue
 		}
-		// Получаем 3D позицию соседней башни
-		neighborPos3D := g.hexToWorld(neighborTower.Hex)
+		// Получаем позицию соседней башни на карте
+		neighborX, neighborY := neighborTower.Hex.ToPixel(config.HexSize)
 
-		// Вычисляем угол до соседа на плоскости XZ
-		lineAngle := math.Atan2(float64(neighborPos3D.Z-sourcePos3D.Z), float64(neighborPos3D.X-sourcePos3D.X))
+		// Вычисляем угол до соседа
+		lineAngle := math.Atan2(neighborY-sourceY, neighborX-sourceX)
 		angleDiff := math.Abs(clickAngle - lineAngle)
 		if angleDiff > math.Pi {
 			angleDiff = 2*math.Pi - angleDiff
@@ -962,12 +1090,6 @@ func (g *Game) startLineDrag(hex hexmap.Hex, hitPoint rl.Vector3) {
 	}
 }
 
-// hexToWorld - вспомогательная функция для преобразования гекса в мировые координаты
-func (g *Game) hexToWorld(h hexmap.Hex) rl.Vector3 {
-	x, y := h.ToPixel(float64(config.HexSize))
-	return rl.NewVector3(float32(x*config.CoordScale), 0, float32(y*config.CoordScale))
-}
-
 func (g *Game) getLineBetweenTowers(tower1ID, tower2ID types.EntityID) (types.EntityID, bool) {
 	for id, line := range g.ECS.LineRenders {
 		if (line.Tower1ID == tower1ID && line.Tower2ID == tower2ID) ||
@@ -1000,6 +1122,7 @@ func (g *Game) GetDebugInfo() *LineDragDebugInfo {
 }
 
 func (g *Game) CancelLineDrag() {
+	defer g.beginCommand(Command{Type: CmdCancelLineDrag})()
 	g.isLineDragging = false
 	g.dragSourceTowerID = 0
 	g.dragOriginalParentID = 0
@@ -1011,7 +1134,8 @@ func (g *Game) FinalizeTowerSelection() {
 	towersToConvertToWalls := []hexmap.Hex{}
 	idsToRemove := []types.EntityID{}
 
-	for id, tower := range g.ECS.Towers {
+	for _, id := range entity.SortedIDs(g.ECS.Towers) {
+		tower := g.ECS.Towers[id]
 		if !tower.IsTemporary {
 			continue
 		}
@@ -1034,19 +1158,20 @@ func (g *Game) FinalizeTowerSelection() {
 
 	g.towersBuilt = 0
 	g.ClearAllSelections()
-	g.rebuildEnergyNetwork()
+	g.syncEnergyLines()
 	g.AuraSystem.RecalculateAuras()
 	g.CraftingSystem.RecalculateCombinations()
 }
 
 func (g *Game) CreateDebugTower(hex hexmap.Hex, towerDefID string) {
+	defer g.beginCommand(Command{Type: CmdCreateDebugTower, Hex: hex, DefID: towerDefID})()
 	if !g.canPlaceWall(hex) {
 		return
 	}
 
 	if towerDefID == "RANDOM_ATTACK" {
 		attackerIDs := []string{"TA", "TE", "TO", "DE", "NI", "NU", "PO", "PA", "PE"}
-		towerDefID = attackerIDs[rand.Intn(len(attackerIDs))]
+		towerDefID = attackerIDs[g.debugRng.Intn(len(attackerIDs))]
 	}
 
 	id := g.createTowerEntity(hex, towerDefID)
@@ -1058,8 +1183,7 @@ func (g *Game) CreateDebugTower(hex hexmap.Hex, towerDefID string) {
 	tower.IsTemporary = false
 	tower.IsSelected = false
 
-	tile := g.HexMap.Tiles[hex]
-	g.HexMap.Tiles[hex] = hexmap.Tile{Passable: false, CanPlaceTower: tile.CanPlaceTower}
+	g.setPassable(hex, false)
 
 	g.addTowerToEnergyNetwork(id)
 	g.AuraSystem.RecalculateAuras()
@@ -1080,36 +1204,55 @@ func (g *Game) createPlayerEntity() {
 
 // GetOreSectorPercentages вычисляет процент оставшейся руды в каждой из трех жил.
 func (g *Game) GetOreSectorPercentages() [3]float32 {
-	var percentages [3]float32
+	percentages, _ := g.oreSectorLevels(false)
+	return percentages
+}
 
-	if len(g.OreVeinHexes) != 3 {
-		return percentages // Возвращаем нули, если что-то пошло не так
-	}
+// GetOreVeinCount возвращает число рудных жил уровня (vein_count или жилы файла карты).
+func (g *Game) GetOreVeinCount() int {
+	return len(g.OreVeinHexes)
+}
 
-	// Создаем быструю карту для поиска компонента руды по гексу.
+// GetOreSectorProjections возвращает долю руды в каждой жиле после восстановления
+// в начале следующей фазы строительства (см. OreSystem.ProjectedRefill).
+func (g *Game) GetOreSectorProjections() [3]float32 {
+	_, projected := g.oreSectorLevels(true)
+	return projected
+}
+
+// oreSectorLevels считает текущую и, если withRefill, ожидаемую после восстановления
+// долю запаса каждой жилы. Истощенные гексы входят в жилу с нулевым запасом.
+func (g *Game) oreSectorLevels(withRefill bool) (current, projected [3]float32) {
+	// Создаем быструю карту для поиска руды по гексу.
 	// Теперь это намного надежнее, так как мы используем ore.Hex.
-	oreMap := make(map[hexmap.Hex]*component.Ore)
-	for _, ore := range g.ECS.Ores {
-		oreMap[ore.Hex] = ore
+	oreAt := make(map[hexmap.Hex]types.EntityID)
+	for id, ore := range g.ECS.Ores {
+		oreAt[ore.Hex] = id
 	}
 
 	for i, vein := range g.OreVeinHexes {
-		var totalMaxReserve, totalCurrentReserve float64
+		if i >= len(current) {
+			break // Индикатор показывает только три первые жилы
+		}
+		var totalMaxReserve, totalCurrentReserve, totalRefill float64
 
 		for _, hex := range vein {
-			if ore, ok := oreMap[hex]; ok {
+			if id, ok := oreAt[hex]; ok {
+				ore := g.ECS.Ores[id]
 				totalMaxReserve += ore.MaxReserve
 				totalCurrentReserve += ore.CurrentReserve
+				if withRefill {
+					totalRefill += g.OreSystem.ProjectedRefill(id)
+				}
 			}
 		}
 
+		// Если в жиле изначально не было руды, считаем ее пустой.
 		if totalMaxReserve > 0 {
-			percentages[i] = float32(totalCurrentReserve / totalMaxReserve)
-		} else {
-			// Если в жиле изначально не было руды, считаем ее пустой.
-			percentages[i] = 0.0
+			current[i] = float32(totalCurrentReserve / totalMaxReserve)
+			projected[i] = float32((totalCurrentReserve + totalRefill) / totalMaxReserve)
 		}
 	}
 
-	return percentages
-}
\ No newline at end of file
+	return current, projected
+}
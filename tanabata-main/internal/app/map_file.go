@@ -0,0 +1,80 @@
+// internal/app/map_file.go
+package app
+
+import (
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/utils"
+	"go-tower-defense/pkg/hexmap"
+)
+
+// NewGameFromMapFile initializes a new game on a hand-authored map (see hexmap.MapFile).
+// Стены и руда берутся из файла, камни у чекпоинтов не расставляются.
+// Если в файле нет жил, руда генерируется по профилю уровня levelID.
+func NewGameFromMapFile(path string, towerDefs map[string]*defs.TowerDefinition, rng *utils.PRNGService, levelID string) (*Game, error) {
+	mapFile, err := hexmap.LoadMapFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	g := newGameOnLevel(mapFile.Map, towerDefs, rng, levelID)
+	g.MapPath = path
+	if len(mapFile.OreVeins) == 0 {
+		g.generateOre(g.forkRng("ore"))
+	} else {
+		g.placeMapOre(mapFile.OreVeins)
+	}
+	for _, wall := range mapFile.Walls {
+		g.createPermanentWall(wall)
+	}
+	g.createPlayerEntity()
+
+	return g, nil
+}
+
+// placeMapOre создает руду жил из файла карты; номер жилы задает ее правила и тип.
+func (g *Game) placeMapOre(veins [][]hexmap.OreHex) {
+	g.OreVeinHexes = make([][]hexmap.Hex, len(veins))
+	for i, vein := range veins {
+		for _, ore := range vein {
+			g.createOreEntity(ore.Hex, ore.Power/100, i)
+			g.OreVeinHexes[i] = append(g.OreVeinHexes[i], ore.Hex)
+		}
+	}
+	g.invalidatePowerNetworks()
+}
+
+// ExportMapFile снимает карту партии в формат файла карты: тайлы, чекпоинты,
+// стены и начальный запас руды. Гексы под башнями снова становятся проходимыми,
+// стены записываются как заранее поставленные. Так сгенерированную карту
+// можно зафиксировать для регрессионных прогонов или доработать в редакторе.
+func (g *Game) ExportMapFile(name string) *hexmap.MapFile {
+	hm := g.HexMap.Clone()
+	mapFile := &hexmap.MapFile{Name: name, Map: hm}
+	for _, id := range entity.SortedIDs(g.ECS.Towers) {
+		tower := g.ECS.Towers[id]
+		hm.SetPassable(tower.Hex, true)
+		if tower.DefID == "TOWER_WALL" {
+			mapFile.Walls = append(mapFile.Walls, tower.Hex)
+		}
+	}
+
+	mapFile.OreVeins = make([][]hexmap.OreHex, len(g.OreVeinHexes))
+	for _, id := range entity.SortedIDs(g.ECS.Ores) {
+		ore := g.ECS.Ores[id]
+		if ore.Vein < 0 || ore.Vein >= len(mapFile.OreVeins) {
+			continue
+		}
+		mapFile.OreVeins[ore.Vein] = append(mapFile.OreVeins[ore.Vein], hexmap.OreHex{Hex: ore.Hex, Power: ore.MaxReserve})
+	}
+	return mapFile
+}
+
+// NewGameOnMap starts a game on the map file at mapPath, or on a map generated
+// from rng when mapPath is empty.
+func NewGameOnMap(mapPath string, towerDefs map[string]*defs.TowerDefinition, rng *utils.PRNGService, levelID string) (*Game, error) {
+	if mapPath == "" {
+		return NewGameForLevel(hexmap.NewHexMap(rng.Fork("map")), towerDefs, rng, levelID), nil
+	}
+	return NewGameFromMapFile(mapPath, towerDefs, rng, levelID)
+}
@@ -0,0 +1,195 @@
+// internal/app/network_inspector.go
+package app
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/pkg/hexmap"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// NetworkSnapshot — снимок энергосети для инспектора (F4) и выгрузки в DOT/JSON.
+// Все списки отсортированы по ID, так что выгрузки одной позиции совпадают побайтно.
+type NetworkSnapshot struct {
+	Tick     uint64             `json:"tick"`
+	Networks []InspectedNetwork `json:"networks"`
+	Towers   []InspectedTower   `json:"towers"`
+	Lines    []InspectedLine    `json:"lines"`
+}
+
+// InspectedNetwork — одна компонента связности сети.
+type InspectedNetwork struct {
+	Index      int              `json:"index"`
+	Members    []types.EntityID `json:"members"`
+	OreSources []types.EntityID `json:"ore_sources"`
+	Batteries  []types.EntityID `json:"batteries"`
+	Supply     float64          `json:"supply"`
+	Demand     float64          `json:"demand"`
+}
+
+// InspectedTower — башня с тем, что о ней знает бой: путь до источника,
+// множитель деградации и источники, из которых она может тратить руду.
+type InspectedTower struct {
+	ID           types.EntityID   `json:"id"`
+	DefID        string           `json:"def_id"`
+	Hex          hexmap.Hex       `json:"hex"`
+	Network      int              `json:"network"`
+	Active       bool             `json:"active"`
+	Disabled     bool             `json:"disabled"`
+	PowerRoot    bool             `json:"power_root"`
+	PathToSource []types.EntityID `json:"path_to_source"`
+	Degradation  float64          `json:"degradation"`
+	Sources      []types.EntityID `json:"sources"`
+	Throttle     float64          `json:"throttle"`
+}
+
+// InspectedLine — линия энергосети.
+type InspectedLine struct {
+	ID     types.EntityID `json:"id"`
+	Tower1 types.EntityID `json:"tower1"`
+	Tower2 types.EntityID `json:"tower2"`
+}
+
+// InspectNetwork собирает снимок текущей энергосети. Номера сетей идут по
+// возрастанию минимального ID башни в компоненте. Стены в сеть не входят и пропускаются.
+func (g *Game) InspectNetwork() NetworkSnapshot {
+	cache := g.ensurePowerNetworks()
+	snapshot := NetworkSnapshot{Tick: g.Tick}
+
+	var towerIDs []types.EntityID
+	for _, id := range entity.SortedIDs(g.ECS.Towers) {
+		if towerDef, ok := defs.TowerDefs[g.ECS.Towers[id].DefID]; ok && towerDef.Type != defs.TowerTypeWall {
+			towerIDs = append(towerIDs, id)
+		}
+	}
+
+	indexOf := make(map[*powerNetwork]int)
+	for _, id := range towerIDs {
+		network := cache.networkOf[id]
+		if network == nil {
+			continue
+		}
+		if _, seen := indexOf[network]; !seen {
+			indexOf[network] = len(snapshot.Networks)
+			flow := g.ECS.Towers[id].Flow
+			snapshot.Networks = append(snapshot.Networks, InspectedNetwork{
+				Index:      len(snapshot.Networks),
+				Members:    network.members,
+				OreSources: network.oreSources,
+				Batteries:  network.batteries,
+				Supply:     flow.Supply,
+				Demand:     flow.Demand,
+			})
+		}
+	}
+
+	for _, id := range towerIDs {
+		tower := g.ECS.Towers[id]
+		path := g.FindPathToPowerSource(id)
+		inspected := InspectedTower{
+			ID:           id,
+			DefID:        tower.DefID,
+			Hex:          tower.Hex,
+			Network:      -1,
+			Active:       tower.IsActive,
+			Disabled:     tower.IsManuallyDisabled,
+			PowerRoot:    g.isPowerRoot(id),
+			PathToSource: path,
+			Degradation:  g.CombatSystem.LineDegradationMultiplier(path),
+			Sources:      g.FindPowerSourcesForTower(id),
+			Throttle:     tower.Flow.Throttle,
+		}
+		if network := cache.networkOf[id]; network != nil {
+			inspected.Network = indexOf[network]
+		}
+		snapshot.Towers = append(snapshot.Towers, inspected)
+	}
+
+	for _, id := range entity.SortedIDs(g.ECS.LineRenders) {
+		line := g.ECS.LineRenders[id]
+		snapshot.Lines = append(snapshot.Lines, InspectedLine{ID: id, Tower1: line.Tower1ID, Tower2: line.Tower2ID})
+	}
+	return snapshot
+}
+
+// NetworkOf возвращает номер сети башни в снимке или -1.
+func (s NetworkSnapshot) NetworkOf(towerID types.EntityID) int {
+	if tower, ok := s.Tower(towerID); ok {
+		return tower.Network
+	}
+	return -1
+}
+
+// Tower возвращает данные башни из снимка.
+func (s NetworkSnapshot) Tower(towerID types.EntityID) (InspectedTower, bool) {
+	for _, tower := range s.Towers {
+		if tower.ID == towerID {
+			return tower, true
+		}
+	}
+	return InspectedTower{}, false
+}
+
+// DOT возвращает граф сети в формате Graphviz. Сети выделены в кластеры,
+// корни (шахтеры на руде, разряжающиеся батареи) — двойным контуром,
+// неактивные и выключенные башни — пунктиром.
+func (s NetworkSnapshot) DOT() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "graph energy_network {\n")
+	fmt.Fprintf(&b, "  label=\"tick %d\";\n  node [shape=circle, fontsize=10];\n", s.Tick)
+	for _, network := range s.Networks {
+		fmt.Fprintf(&b, "  subgraph cluster_%d {\n", network.Index)
+		fmt.Fprintf(&b, "    label=\"net %d: %.2f / %.2f ore/s\";\n", network.Index, network.Supply, network.Demand)
+		for _, id := range network.Members {
+			tower, _ := s.Tower(id)
+			fmt.Fprintf(&b, "    t%d [%s];\n", id, dotNodeAttrs(tower))
+		}
+		fmt.Fprintf(&b, "  }\n")
+	}
+	for _, line := range s.Lines {
+		fmt.Fprintf(&b, "  t%d -- t%d [tooltip=\"line %d\"];\n", line.Tower1, line.Tower2, line.ID)
+	}
+	fmt.Fprintf(&b, "}\n")
+	return b.String()
+}
+
+func dotNodeAttrs(tower InspectedTower) string {
+	label := fmt.Sprintf("%d\\n%s\\nx%.2f", tower.ID, tower.DefID, tower.Degradation)
+	attrs := []string{fmt.Sprintf("label=\"%s\"", label)}
+	if tower.PowerRoot {
+		attrs = append(attrs, "peripheries=2")
+	}
+	if !tower.Active || tower.Disabled {
+		attrs = append(attrs, "style=dashed")
+	}
+	if towerDef, ok := defs.TowerDefs[tower.DefID]; ok && towerDef.Type.IsEnergyRelay() {
+		attrs = append(attrs, "shape=box")
+	}
+	return strings.Join(attrs, ", ")
+}
+
+// ExportNetwork пишет снимок сети в каталог dir как network_<tick>.dot и
+// network_<tick>.json и возвращает путь к DOT-файлу.
+func (g *Game) ExportNetwork(dir string) (string, error) {
+	snapshot := g.InspectNetwork()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	base := filepath.Join(dir, fmt.Sprintf("network_%d", snapshot.Tick))
+	if err := os.WriteFile(base+".dot", []byte(snapshot.DOT()), 0o644); err != nil {
+		return "", err
+	}
+	data, err := json.MarshalIndent(snapshot, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(base+".json", data, 0o644); err != nil {
+		return "", err
+	}
+	return base + ".dot", nil
+}
@@ -0,0 +1,299 @@
+// internal/app/ore_forecast.go
+package app
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/event"
+	"go-tower-defense/internal/types"
+	"math"
+)
+
+// oreForecastSmoothing is the time constant, in game seconds, of the moving
+// average that turns reserve drops into a consumption rate.
+const oreForecastSmoothing = 3.0
+
+// OreForecast estimates when ore runs out at the current consumption.
+type OreForecast struct {
+	Veins    []VeinForecast    // Indexed like Game.OreVeinHexes
+	Networks []NetworkForecast // Networks with at least one power source, by root ID
+}
+
+// VeinForecast is the outlook of one ore vein.
+type VeinForecast struct {
+	Reserve       float64 // Ore left in the live hexes of the vein
+	Rate          float64 // Smoothed drain in ore per second of wave time
+	Seconds       float64 // Wave seconds until the vein is dry; +Inf when it is not drained
+	SpentLastWave float64 // Ore drawn from the vein during the last finished wave
+	Refill        float64 // Ore the vein gets back at the start of the next build phase
+	Waves         float64 // Waves until dry at the last wave's net loss; +Inf when it does not shrink
+	Warning       component.OreWarningLevel
+}
+
+// NetworkForecast is the outlook of one energy network. It is also the payload
+// of event.NetworkRunningDry.
+type NetworkForecast struct {
+	RootID  types.EntityID // Lowest tower ID of the network
+	Towers  int
+	Reserve float64 // Ore and battery charge the network can still draw from
+	Rate    float64 // Smoothed drain of those sources in ore per second
+	Seconds float64 // Wave seconds until the network is dry; +Inf when it is not drained
+}
+
+// oreForecaster samples source reserves every frame and keeps a smoothed drain
+// rate per ore and battery. Rates only move during waves, so in the build phase
+// the forecast shows the pace of the last wave. It only reads the simulation and
+// never changes it, so replays and saves do not depend on it.
+type oreForecaster struct {
+	inWave        bool
+	lastReserve   map[types.EntityID]float64 // Last sampled reserve of every ore and battery
+	drain         map[types.EntityID]float64 // Smoothed drain rate of every ore and battery
+	waveStart     []float64                  // Reserve of every vein when the current wave began
+	spentLastWave []float64                  // Ore drawn from every vein during the last finished wave
+	warned        map[types.EntityID]bool    // Networks (by root) already reported this wave
+}
+
+func newOreForecaster() *oreForecaster {
+	return &oreForecaster{
+		lastReserve: make(map[types.EntityID]float64),
+		drain:       make(map[types.EntityID]float64),
+		warned:      make(map[types.EntityID]bool),
+	}
+}
+
+// updateOreForecast samples reserves after the frame's systems ran and reports
+// networks that will run dry in the middle of the wave.
+func (g *Game) updateOreForecast(dt float64) {
+	f := g.oreForecaster
+	inWave := g.ECS.GameState.Phase == component.WaveState
+	if inWave && !f.inWave {
+		f.waveStart = g.veinReserves()
+		f.warned = make(map[types.EntityID]bool)
+	}
+	f.inWave = inWave
+
+	alpha := dt / (oreForecastSmoothing + dt)
+	sample := func(id types.EntityID, reserve float64) {
+		prev, seen := f.lastReserve[id]
+		f.lastReserve[id] = reserve
+		if inWave && seen && dt > 0 {
+			// Refills and battery charging raise the reserve; only drops count as drain.
+			f.drain[id] += alpha * (max(0, prev-reserve)/dt - f.drain[id])
+		}
+	}
+	for _, id := range entity.SortedIDs(g.ECS.Ores) {
+		sample(id, g.ECS.Ores[id].CurrentReserve)
+	}
+	for _, id := range entity.SortedIDs(g.ECS.Batteries) {
+		sample(id, g.ECS.Batteries[id].Stored)
+	}
+	for id := range f.lastReserve {
+		_, isOre := g.ECS.Ores[id]
+		_, isBattery := g.ECS.Batteries[id]
+		if !isOre && !isBattery {
+			delete(f.lastReserve, id)
+			delete(f.drain, id)
+		}
+	}
+
+	if inWave {
+		waveLeft := -1.0 // Estimated once, for the first candidate network
+		for _, network := range g.networkForecasts() {
+			if network.Rate <= 0 || network.Reserve <= 0 || f.warned[network.RootID] {
+				continue
+			}
+			if waveLeft < 0 {
+				waveLeft = g.remainingWaveSeconds()
+			}
+			if network.Seconds < waveLeft {
+				f.warned[network.RootID] = true
+				g.EventDispatcher.Dispatch(event.Event{Type: event.NetworkRunningDry, Data: network})
+			}
+		}
+	}
+}
+
+// remainingWaveSeconds estimates how long the current wave still lasts: until the
+// last enemy, already on the field or still to spawn, walks the rest of its path
+// at its base speed. Slows, terrain and kills are ignored, so the estimate errs
+// long, which only makes the dry warning come earlier.
+func (g *Game) remainingWaveSeconds() float64 {
+	hexStep := math.Sqrt(3) * config.HexSize // Distance between the centres of neighbouring hexes
+	remaining := 0.0
+	for _, id := range entity.SortedIDs(g.ECS.Enemies) {
+		path, hasPath := g.ECS.Paths[id]
+		velocity, hasVelocity := g.ECS.Velocities[id]
+		if !hasPath || !hasVelocity || velocity.Speed <= 0 {
+			continue
+		}
+		hexes := len(path.Hexes) - path.CurrentIndex
+		remaining = max(remaining, float64(hexes)*hexStep/velocity.Speed)
+	}
+
+	wave := g.ECS.Wave
+	if wave == nil {
+		return remaining
+	}
+	waveDef, ok := defs.GetWaveDefinition(wave.Number)
+	if !ok {
+		return remaining
+	}
+	for _, group := range wave.Groups {
+		if group.Remaining == 0 {
+			continue
+		}
+		// The group's timer counts up to its interval, starting from -delay.
+		lastSpawn := group.Interval - group.Timer + float64(group.Remaining-1)*group.Interval
+		speed := defs.EnemyDefs[group.EnemyID].Speed * waveDef.SpeedMultiplier * waveDef.SpeedMultiplierModifier
+		if speed <= 0 {
+			continue
+		}
+		for _, route := range group.Routes {
+			remaining = max(remaining, lastSpawn+float64(len(route.Path))*hexStep/speed)
+		}
+	}
+	return remaining
+}
+
+// finishOreForecastWave records how much ore every vein lost during the wave
+// that just ended. It runs on WaveEnded, before the build phase refills veins.
+func (g *Game) finishOreForecastWave() {
+	f := g.oreForecaster
+	end := g.veinReserves()
+	f.spentLastWave = make([]float64, len(end))
+	for i := range end {
+		if i < len(f.waveStart) {
+			f.spentLastWave[i] = max(0, f.waveStart[i]-end[i])
+		}
+	}
+	f.inWave = false
+}
+
+// veinReserves returns the ore left in every vein.
+func (g *Game) veinReserves() []float64 {
+	reserves := make([]float64, len(g.OreVeinHexes))
+	for _, ore := range g.ECS.Ores {
+		if ore.Vein < len(reserves) && !ore.Depleted {
+			reserves[ore.Vein] += ore.CurrentReserve
+		}
+	}
+	return reserves
+}
+
+// OreForecast returns the depletion outlook of every vein and network.
+func (g *Game) OreForecast() OreForecast {
+	f := g.oreForecaster
+	veins := make([]VeinForecast, len(g.OreVeinHexes))
+	for _, id := range entity.SortedIDs(g.ECS.Ores) {
+		ore := g.ECS.Ores[id]
+		if ore.Vein >= len(veins) {
+			continue
+		}
+		vein := &veins[ore.Vein]
+		vein.Refill += g.OreSystem.ProjectedRefill(id)
+		if !ore.Depleted {
+			vein.Reserve += ore.CurrentReserve
+			vein.Rate += f.drain[id]
+		}
+	}
+	for i := range veins {
+		vein := &veins[i]
+		vein.Seconds = secondsUntilDry(vein.Reserve, vein.Rate)
+		if i < len(f.spentLastWave) {
+			vein.SpentLastWave = f.spentLastWave[i]
+		}
+		vein.Waves = math.Inf(1)
+		if loss := vein.SpentLastWave - vein.Refill; loss > 0 {
+			vein.Waves = vein.Reserve / loss
+		}
+		switch {
+		case vein.Reserve <= 0:
+			// An empty vein is already drawn as depleted
+		case vein.Seconds <= config.OreDryWarningSeconds:
+			vein.Warning = component.OreWarningCritical
+		case vein.Waves <= config.OreDepletionWarningWaves:
+			vein.Warning = component.OreWarningSoon
+		}
+	}
+	return OreForecast{Veins: veins, Networks: g.networkForecasts()}
+}
+
+// networkForecasts returns the outlook of every network that has a power source.
+func (g *Game) networkForecasts() []NetworkForecast {
+	f := g.oreForecaster
+	cache := g.ensurePowerNetworks()
+	visited := make(map[*powerNetwork]bool)
+	var result []NetworkForecast
+	for _, towerID := range entity.SortedIDs(g.ECS.Towers) {
+		network := cache.networkOf[towerID]
+		if network == nil || visited[network] {
+			continue
+		}
+		visited[network] = true
+		if len(network.oreSources) == 0 && len(network.batteries) == 0 {
+			continue
+		}
+		forecast := NetworkForecast{RootID: network.members[0], Towers: len(network.members)}
+		for _, oreID := range network.oreSources {
+			if ore, ok := g.ECS.Ores[oreID]; ok && !ore.Depleted {
+				forecast.Reserve += ore.CurrentReserve
+				forecast.Rate += f.drain[oreID]
+			}
+		}
+		for _, batteryID := range network.batteries {
+			if !g.isDischargingBattery(batteryID) {
+				continue // A charging battery does not feed the network
+			}
+			forecast.Reserve += g.ECS.Batteries[batteryID].Stored
+			forecast.Rate += f.drain[batteryID]
+		}
+		forecast.Seconds = secondsUntilDry(forecast.Reserve, forecast.Rate)
+		result = append(result, forecast)
+	}
+	return result
+}
+
+// GetOreSectorWarnings returns the warning level of the three veins on the indicator.
+func (g *Game) GetOreSectorWarnings() [3]component.OreWarningLevel {
+	var warnings [3]component.OreWarningLevel
+	for i, vein := range g.OreForecast().Veins {
+		if i >= len(warnings) {
+			break
+		}
+		warnings[i] = vein.Warning
+	}
+	return warnings
+}
+
+// DryNetworkWarning returns the network closest to running dry among those
+// reported during the current wave, while it still runs dry before the wave ends.
+func (g *Game) DryNetworkWarning() (NetworkForecast, bool) {
+	f := g.oreForecaster
+	if !f.inWave || len(f.warned) == 0 {
+		return NetworkForecast{}, false
+	}
+	waveLeft := g.remainingWaveSeconds()
+	var worst NetworkForecast
+	found := false
+	for _, network := range g.networkForecasts() {
+		if !f.warned[network.RootID] || network.Reserve <= 0 || network.Seconds >= waveLeft {
+			continue
+		}
+		if !found || network.Seconds < worst.Seconds {
+			worst, found = network, true
+		}
+	}
+	return worst, found
+}
+
+func secondsUntilDry(reserve, rate float64) float64 {
+	if reserve <= 0 {
+		return 0
+	}
+	if rate <= 1e-9 {
+		return math.Inf(1)
+	}
+	return reserve / rate
+}
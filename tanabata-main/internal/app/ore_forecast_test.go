@@ -0,0 +1,83 @@
+// internal/app/ore_forecast_test.go
+package app
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/event"
+	"go-tower-defense/pkg/hexmap"
+	"math"
+	"testing"
+)
+
+// dryEventCounter collects the NetworkRunningDry events of a test.
+type dryEventCounter struct {
+	events []NetworkForecast
+}
+
+func (c *dryEventCounter) OnEvent(e event.Event) {
+	c.events = append(c.events, e.Data.(NetworkForecast))
+}
+
+// TestNetworkRunningDryComparesWithWaveTime drains a miner's ore during a wave that
+// still has a minute of spawns ahead. A drain that lasts the wave stays silent; a
+// drain that runs dry halfway through fires NetworkRunningDry exactly once, long
+// before the network is within config.OreDryWarningSeconds of running dry.
+func TestNetworkRunningDryComparesWithWaveTime(t *testing.T) {
+	const dt = 1.0 / 60
+	g := newEnergyTestGame(t, 1)
+	ores := entity.SortedIDs(g.ECS.Ores)
+	if len(ores) == 0 {
+		t.Fatal("the generated map has no ore")
+	}
+	oreID := ores[0]
+	ore := g.ECS.Ores[oreID]
+	g.addTowerToEnergyNetwork(g.createTowerEntity(ore.Hex, "TOWER_MINER"))
+
+	counter := &dryEventCounter{}
+	g.EventDispatcher.Subscribe(event.NetworkRunningDry, counter)
+
+	// Ten enemies, one every 6 s, on a 30-hex route: the wave has a minute of spawns left
+	route := make([]hexmap.Hex, 30)
+	g.ECS.Wave = &component.Wave{
+		Number:         1,
+		EnemiesToSpawn: 10,
+		Groups: []component.SpawnGroup{{
+			EnemyID:   "ENEMY_NORMAL",
+			Remaining: 10,
+			Interval:  6,
+			Routes:    []component.SpawnRoute{{Path: route}},
+		}},
+	}
+	g.ECS.GameState.Phase = component.WaveState
+	waveLeft := g.remainingWaveSeconds()
+	walk := 30 * math.Sqrt(3) * config.HexSize / 80
+	if want := 60 + walk; math.Abs(waveLeft-want) > 1e-9 {
+		t.Fatalf("remaining wave time = %.2fs, want %.2fs", waveLeft, want)
+	}
+
+	ore.CurrentReserve = 100
+	drain := func(seconds, rate float64) {
+		for tick := 0; tick < int(seconds/dt); tick++ {
+			ore.CurrentReserve = max(0, ore.CurrentReserve-rate*dt)
+			g.updateOreForecast(dt)
+		}
+	}
+
+	// The network lasts three times longer than the wave
+	drain(20, 100/(3*waveLeft))
+	if len(counter.events) != 0 {
+		t.Fatalf("warned about a network that lasts the wave: %+v", counter.events)
+	}
+
+	// Now it runs dry halfway through the rest of the wave, and then empties completely
+	drain(waveLeft, 2*ore.CurrentReserve/waveLeft)
+	if len(counter.events) != 1 {
+		t.Fatalf("NetworkRunningDry fired %d times, want once: %+v", len(counter.events), counter.events)
+	}
+	if warned := counter.events[0]; warned.Seconds >= waveLeft || warned.Seconds <= config.OreDryWarningSeconds {
+		t.Fatalf("warned %.1fs before running dry, want between %.0fs and the %.1fs left in the wave",
+			warned.Seconds, config.OreDryWarningSeconds, waveLeft)
+	}
+}
@@ -5,10 +5,12 @@ import (
 	"fmt"
 	"go-tower-defense/internal/component"
 	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
 	"image/color"
+	"log"
 	"math"
-	"math/rand"
 )
 
 func findFarthestHex(candidates []hexmap.Hex, existingCenters []hexmap.Hex) hexmap.Hex {
@@ -36,141 +38,105 @@ type EnergyCircle struct {
 	Power   float64
 }
 
-func (g *Game) generateOre() {
-	// Все возможные гексы
-	allHexes := make([]hexmap.Hex, 0, len(g.HexMap.Tiles))
-	for hex := range g.HexMap.Tiles {
-		allHexes = append(allHexes, hex)
-	}
+// oreCenterAttempts ограничивает случайный поиск центра жилы; после него берется
+// самый удаленный подходящий гекс, чтобы тесные карты не зацикливали генерацию.
+const oreCenterAttempts = 10000
+
+// generateOre раскладывает руду по профилю генерации уровня (defs.OreGenerationProfile).
+func (g *Game) generateOre(rng *utils.PRNGService) {
+	profile := g.Level().Ore
 
-	// Центр карты
-	centerHex := hexmap.Hex{Q: 0, R: 0}
+	// Все возможные гексы (в детерминированном порядке, чтобы сид давал одну и ту же руду)
+	allHexes := g.HexMap.SortedHexes()
 
 	// --- Функции для проверки валидности центров жил ---
 	isTooCloseToCritical := func(hex hexmap.Hex) bool {
-		if hex == g.HexMap.Entry || hex == g.HexMap.Exit || g.isCheckpoint(hex) {
-			return true
-		}
-		if g.HexMap.Entry.Distance(hex) < 2 || g.HexMap.Exit.Distance(hex) < 2 {
+		if g.HexMap.IsGate(hex) || g.isCheckpoint(hex) {
 			return true
 		}
-		for _, cp := range g.HexMap.Checkpoints {
-			if cp.Distance(hex) < 2 {
+		for _, gate := range g.HexMap.Gates() {
+			if gate.Entry.Distance(hex) < 2 || gate.Exit.Distance(hex) < 2 {
 				return true
 			}
+			for _, cp := range gate.Checkpoints {
+				if cp.Distance(hex) < 2 {
+					return true
+				}
+			}
 		}
 		return false
 	}
 
-	// --- Поиск трех центров для жил ---
+	// --- Поиск центров жил по кольцам профиля ---
+	veinCenters := make([]hexmap.Hex, profile.VeinCount)
+	hasCenter := make([]bool, profile.VeinCount)
 	var centers []hexmap.Hex
-
-	// Центр 1: В центре (дистанция < 3)
-	for len(centers) < 1 {
-		candidate := allHexes[rand.Intn(len(allHexes))]
-		if !isTooCloseToCritical(candidate) && centerHex.Distance(candidate) < 3 {
-			centers = append(centers, candidate)
-		}
-	}
-
-	// Центр 2: Средний радиус (4-9), подальше от первого
-	for len(centers) < 2 {
-		candidate := allHexes[rand.Intn(len(allHexes))]
-		distFromCenter := centerHex.Distance(candidate)
-		if !isTooCloseToCritical(candidate) && distFromCenter >= 4 && distFromCenter <= 9 {
-			if centers[0].Distance(candidate) > 6 { // Убедимся, что он не слишком близко к первому
-				centers = append(centers, candidate)
-			}
-		}
-	}
-
-	// Центр 3: Далеко (дистанция >= 10), подальше от первых двух (надежн��й метод)
-	var centerCandidates3 []hexmap.Hex
-	for _, hex := range allHexes {
-		if !isTooCloseToCritical(hex) && centerHex.Distance(hex) >= 10 {
-			centerCandidates3 = append(centerCandidates3, hex)
-		}
-	}
-
-	// Если кандидатов на краю не нашлось, это маловероятно, но лучше иметь запасной вариант
-	if len(centerCandidates3) > 0 {
-		center3 := findFarthestHex(centerCandidates3, centers)
-		centers = append(centers, center3)
-	} else {
-		// В крайнем случае, если на расстоянии 10+ ничего нет, ищем на 8+
-		for _, hex := range allHexes {
-			if !isTooCloseToCritical(hex) && centerHex.Distance(hex) >= 8 {
-				centerCandidates3 = append(centerCandidates3, hex)
-			}
-		}
-		if len(centerCandidates3) > 0 {
-			center3 := findFarthestHex(centerCandidates3, centers)
-			centers = append(centers, center3)
+	for i := range veinCenters {
+		center, ok := pickOreCenter(rng, allHexes, profile.Vein(i), centers, profile.MinSeparation, isTooCloseToCritical)
+		if !ok {
+			log.Printf("generateOre: no place for vein %d on level %q", i, g.LevelID)
+			continue
 		}
+		veinCenters[i], hasCenter[i] = center, true
+		centers = append(centers, center)
 	}
 
 	// --- Генерация жил ---
-	// Убедимся, что у нас есть 3 центра перед тем, как продолжить
-	if len(centers) < 3 {
-		// Если по какой-то причине третий центр не был найден, дублируем второй,
-		// чтобы избежать пани��и, но смещаем его.
-		if len(centers) == 2 {
-			shiftedCenter := centers[1].Add(hexmap.Hex{Q: 1, R: 1})
-			centers = append(centers, shiftedCenter)
-		} else {
-			// Этого никогда не должно произойти, но на всякий случай
-			return
+	veinAreas := make([][]hexmap.Hex, profile.VeinCount)
+	for i, center := range veinCenters {
+		if !hasCenter[i] {
+			continue
 		}
-	}
-
-	veinAreas := make([][]hexmap.Hex, 3)
-	for i, center := range centers {
-		if i == 0 {
-			// Новая детерминированная логика для центральной жилы
-			centralVein := []hexmap.Hex{center}
+		vein := profile.Vein(i)
+		if vein.Shape == defs.OreShapeCluster {
+			// Компактная жила: центр и случайные соседи
+			cluster := []hexmap.Hex{center}
 			neighbors := g.HexMap.GetNeighbors(center)
 
 			// Перемешиваем соседей для случайности
-			rand.Shuffle(len(neighbors), func(i, j int) {
+			rng.Shuffle(len(neighbors), func(i, j int) {
 				neighbors[i], neighbors[j] = neighbors[j], neighbors[i]
 			})
 
-			// Добавляем первых 3 валидных соседа, чтобы получить ровно 4 гекса
 			for _, neighbor := range neighbors {
-				if len(centralVein) >= 4 {
+				if len(cluster) >= vein.Size {
 					break
 				}
 				// Дополнительно проверяем, что сосед не является критической точкой
 				if !isTooCloseToCritical(neighbor) {
-					centralVein = append(centralVein, neighbor)
+					cluster = append(cluster, neighbor)
 				}
 			}
-			veinAreas[i] = centralVein
+			veinAreas[i] = cluster
 		} else {
-			// Старая, рабочая логика для остальных жил
-			veinAreas[i] = g.HexMap.GetHexesInRange(center, 2)
+			veinAreas[i] = g.HexMap.GetHexesInRange(center, vein.Size)
 		}
 	}
 
 	energyVeins := make(map[hexmap.Hex]float64)
+	veinOf := make(map[hexmap.Hex]int) // Жила, из которой гекс получил энергию первым
 
 	// --- Динамическая генерация мощности жил ---
 	// 1. Генерируем общую мощность для карты
-	totalMapPower := 240.0 + rand.Float64()*30 // от 240 до 270
-
-	// 2. Определяем доли для жил со случайным разбросом
-	// Центральная жила (самая слабая)
-	centralShare := (0.18 + rand.Float64()*0.04) * (2.5 / 1.5) // 18% - 22%, УМНОЖЕНО НА 2.5 и разделено на 1.5
-	// Средняя жила
-	midShare := 0.27 + rand.Float64()*0.06 // 27% - 33%
-	// Дальняя жила (самая сильная) получает остаток, чтобы сумма была 100%
-	farShare := 1.0 - centralShare - midShare
-
-	// 3. Распределяем общую мощность по долям
-	totalPowers := []float64{
-		totalMapPower * centralShare, // Центральная
-		totalMapPower * midShare,     // Средняя
-		totalMapPower * farShare,     // Дальняя
+	totalMapPower := profile.TotalPower.Min + rng.Float64()*(profile.TotalPower.Max-profile.TotalPower.Min)
+
+	// 2. Определяем доли жил со случайным разбросом; жилы без доли делят остаток поровну
+	shares := make([]float64, profile.VeinCount)
+	remainingShare := 1.0
+	restVeins := 0
+	for i := range shares {
+		share := profile.Vein(i).Share
+		if share == nil {
+			restVeins++
+			continue
+		}
+		shares[i] = share.Min + rng.Float64()*(share.Max-share.Min)
+		remainingShare -= shares[i]
+	}
+	for i := range shares {
+		if profile.Vein(i).Share == nil {
+			shares[i] = max(0, remainingShare) / float64(restVeins)
+		}
 	}
 
 	// Распределение энергии по жилам
@@ -179,10 +145,11 @@ func (g *Game) generateOre() {
 			continue
 		}
 
-		totalVeinPower := totalPowers[i]
+		// 3. Распределяем общую мощность по долям
+		totalVeinPower := totalMapPower * shares[i]
 
-		if i == 0 { // Специальная, детерминированная логика для центральной жилы
-			// Расп��еделяем общую мощность жилы (totalVeinPower) между 4 гексами
+		if profile.Vein(i).Shape == defs.OreShapeCluster {
+			// Распределяем общую мощность жилы (totalVeinPower) между гексами
 			remainingPower := totalVeinPower
 			for j := 0; j < len(area)-1; j++ {
 				hex := area[j]
@@ -190,8 +157,8 @@ func (g *Game) generateOre() {
 				// чтобы не забрать всё сразу и оставить что-то остальным.
 				avgPower := remainingPower / float64(len(area)-j)
 				fluctuation := avgPower * 0.4 // Колебание в пределах 40%
-				power := avgPower + (rand.Float64()*2-1)*fluctuation
-				
+				power := avgPower + (rng.Float64()*2-1)*fluctuation
+
 				if power > remainingPower {
 					power = remainingPower
 				}
@@ -200,16 +167,18 @@ func (g *Game) generateOre() {
 				}
 
 				energyVeins[hex] = power / 100.0 // Конвертируем из процентов
+				veinOf[hex] = i
 				remainingPower -= power
 			}
 			// Последний гекс забирает всё оставшееся
 			if len(area) > 0 {
 				energyVeins[area[len(area)-1]] = remainingPower / 100.0
+				veinOf[area[len(area)-1]] = i
 			}
 
-		} else { // Старая, случайная логика для остальных жил
-			circles := generateEnergyCircles(area, totalVeinPower, config.HexSize)
-			// Привязка энергии к гексам ч��рез круги
+		} else { // Случайные круги мощности поверх области жилы
+			circles := generateEnergyCircles(rng, area, totalVeinPower, config.HexSize)
+			// Привязка энергии к гексам через круги
 			for _, circle := range circles {
 				hexesInCircle := g.getHexesInCircle(circle.CenterX, circle.CenterY, circle.Radius)
 				for _, hex := range hexesInCircle {
@@ -218,6 +187,7 @@ func (g *Game) generateOre() {
 					}
 					if _, exists := energyVeins[hex]; !exists {
 						energyVeins[hex] = 0
+						veinOf[hex] = i
 					}
 					energyVeins[hex] += circle.Power
 				}
@@ -225,30 +195,19 @@ func (g *Game) generateOre() {
 		}
 	}
 
-	for hex, power := range energyVeins {
-		id := g.ECS.NewEntity()
-		px, py := hex.ToPixel(float64(config.HexSize)) // ИСПРАВЛЕНО
-		g.ECS.Positions[id] = &component.Position{X: px, Y: py}
-		g.ECS.Ores[id] = &component.Ore{
-			Power:          power,
-			MaxReserve:     power * 100, // База для расчета процентов
-			CurrentReserve: power * 100,
-			Hex:            hex,
-			Position:       component.Position{X: px, Y: py},
-			Radius:         float32(config.HexSize*0.2 + power*config.HexSize),
-			Color:          color.RGBA{0, 0, 255, 128},
-			PulseRate:      2.0,
-		}
-		g.ECS.Texts[id] = &component.Text{
-			Value:    fmt.Sprintf("%.0f%%", power*100),
-			Position: component.Position{X: px, Y: py},
-			Color:    color.RGBA{R: 50, G: 50, B: 50, A: 255},
-			IsUI:     true,
-		}
+	veinHexes := make([]hexmap.Hex, 0, len(energyVeins))
+	for hex := range energyVeins {
+		veinHexes = append(veinHexes, hex)
+	}
+	hexmap.SortHexes(veinHexes)
+
+	for _, hex := range veinHexes {
+		power := clampOreReserve(energyVeins[hex]*100, profile.ReservePerHex) / 100
+		g.createOreEntity(hex, power, veinOf[hex])
 	}
 
 	// Фильтруем veinAreas, чтобы они содержали только гексы, которые попали в finalEnergyVeins
-	finalVeinAreas := make([][]hexmap.Hex, 3)
+	finalVeinAreas := make([][]hexmap.Hex, len(veinAreas))
 	for i, area := range veinAreas {
 		var finalArea []hexmap.Hex
 		for _, hex := range area {
@@ -259,21 +218,116 @@ func (g *Game) generateOre() {
 		finalVeinAreas[i] = finalArea
 	}
 	g.OreVeinHexes = finalVeinAreas
+	g.invalidatePowerNetworks()
 }
 
-func generateEnergyCircles(area []hexmap.Hex, totalPower float64, hexSize float64) []EnergyCircle {
+// createOreEntity создает руду на гексе. power — доля запаса (1.0 = 100%),
+// тип и цвет руды берутся из правил жилы vein.
+func (g *Game) createOreEntity(hex hexmap.Hex, power float64, vein int) {
+	oreType := defs.OreVeinDefs[vein].OreType
+	oreColor := color.RGBA{0, 0, 255, 128}
+	if typeDef, ok := defs.OreTypeDefs[oreType]; ok {
+		oreColor = typeDef.Color
+	}
+	id := g.ECS.NewEntity()
+	px, py := hex.ToPixel(float64(config.HexSize)) // ИСПРАВЛЕНО
+	g.ECS.Positions[id] = &component.Position{X: px, Y: py}
+	g.ECS.Ores[id] = &component.Ore{
+		Power:          power,
+		MaxReserve:     power * 100, // База для расчета процентов
+		CurrentReserve: power * 100,
+		Hex:            hex,
+		Position:       component.Position{X: px, Y: py},
+		Radius:         float32(config.HexSize*0.2 + power*config.HexSize),
+		Color:          oreColor,
+		PulseRate:      2.0,
+		Vein:           vein,
+		Type:           oreType,
+	}
+	g.ECS.Texts[id] = &component.Text{
+		Value:    fmt.Sprintf("%.0f%%", power*100),
+		Position: component.Position{X: px, Y: py},
+		Color:    color.RGBA{R: 50, G: 50, B: 50, A: 255},
+		IsUI:     true,
+	}
+}
+
+// pickOreCenter выбирает центр жилы в ее кольце вокруг центра карты, не ближе
+// minSeparation к уже выбранным центрам. Для размещения "random" гексы берутся
+// случайно; если попытки кончились, а также для "farthest" берется гекс, наиболее
+// удаленный от выбранных центров. Если кольцо пусто, его нижняя граница
+// опускается на 2, а разнос центров соблюдается только по возможности.
+func pickOreCenter(rng *utils.PRNGService, allHexes []hexmap.Hex, vein defs.OreVeinProfile, centers []hexmap.Hex, minSeparation int, excluded func(hexmap.Hex) bool) (hexmap.Hex, bool) {
+	mapCenter := hexmap.Hex{Q: 0, R: 0}
+	inBand := func(hex hexmap.Hex, minDistance int) bool {
+		d := mapCenter.Distance(hex)
+		return d >= minDistance && (vein.MaxDistance == 0 || d <= vein.MaxDistance)
+	}
+	separated := func(hex hexmap.Hex) bool {
+		for _, center := range centers {
+			if center.Distance(hex) < minSeparation {
+				return false
+			}
+		}
+		return true
+	}
+
+	if vein.Placement == defs.OrePlacementRandom {
+		for attempt := 0; attempt < oreCenterAttempts; attempt++ {
+			candidate := allHexes[rng.Intn(len(allHexes))]
+			if !excluded(candidate) && inBand(candidate, vein.MinDistance) && separated(candidate) {
+				return candidate, true
+			}
+		}
+	}
+
+	for minDistance := vein.MinDistance; ; minDistance -= 2 {
+		var candidates, spaced []hexmap.Hex
+		for _, hex := range allHexes {
+			if excluded(hex) || !inBand(hex, minDistance) {
+				continue
+			}
+			candidates = append(candidates, hex)
+			if separated(hex) {
+				spaced = append(spaced, hex)
+			}
+		}
+		if len(spaced) > 0 {
+			return findFarthestHex(spaced, centers), true
+		}
+		if len(candidates) > 0 {
+			return findFarthestHex(candidates, centers), true
+		}
+		if minDistance <= 0 {
+			return hexmap.Hex{}, false
+		}
+	}
+}
+
+// clampOreReserve ограничивает запас гекса пределами reserve_per_hex (0 — без предела).
+func clampOreReserve(reserve float64, limits defs.FloatRange) float64 {
+	if limits.Min > 0 && reserve < limits.Min {
+		reserve = limits.Min
+	}
+	if limits.Max > 0 && reserve > limits.Max {
+		reserve = limits.Max
+	}
+	return reserve
+}
+
+func generateEnergyCircles(rng *utils.PRNGService, area []hexmap.Hex, totalPower float64, hexSize float64) []EnergyCircle {
 	var circles []EnergyCircle
 	remainingPower := totalPower
 
 	for remainingPower > 0 {
-		hex := area[rand.Intn(len(area))]
+		hex := area[rng.Intn(len(area))]
 		cx, cy := hex.ToPixel(float64(hexSize)) // ИСПРАВЛЕНО
 		// Add random jitter within the hex
-		cx += (rand.Float64()*2 - 1) * hexSize / 2
-		cy += (rand.Float64()*2 - 1) * hexSize / 2
+		cx += (rng.Float64()*2 - 1) * hexSize / 2
+		cy += (rng.Float64()*2 - 1) * hexSize / 2
 
 		// Ограничиваем энергию до 5-20% для большего количества кружков
-		power := float64((rand.Intn(4) + 1) * 5) // 5, 10, 15, 20%
+		power := float64((rng.Intn(4) + 1) * 5) // 5, 10, 15, 20%
 		if power > remainingPower {
 			power = remainingPower
 		}
@@ -319,10 +373,5 @@ func (g *Game) getBorderHexes(borderRadius int) map[hexmap.Hex]struct{} {
 }
 
 func (g *Game) isCheckpoint(hex hexmap.Hex) bool {
-	for _, cp := range g.HexMap.Checkpoints {
-		if cp == hex {
-			return true
-		}
-	}
-	return false
-}
\ No newline at end of file
+	return g.HexMap.IsCheckpoint(hex)
+}
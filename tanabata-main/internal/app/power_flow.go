@@ -0,0 +1,187 @@
+// internal/app/power_flow.go
+package app
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+)
+
+// updatePowerFlow balances ore supply and demand in every energy network and
+// stores the result in Tower.Flow. It runs once per frame, before the combat
+// systems, so CombatSystem can throttle fire rate by Flow.Throttle.
+//
+// The model is a steady-state flow on the network tree:
+//   - every miner (or battery on ore) extracts up to Ore.Power * ExtractionRate
+//     ore per second; a discharging battery adds its DischargeRate;
+//   - every firing tower wants ShotCost * FireRate ore per second; a tower without
+//     targets for config.FlowIdleGrace seconds wants nothing;
+//   - if demand exceeds supply, all consumers of the network get supply/demand;
+//   - each line carries at most config.LineMaxFlow; consumers on the importing
+//     side of an overloaded line are throttled further by cap/flow.
+func (g *Game) updatePowerFlow() {
+	cache := g.ensurePowerNetworks()
+	visited := make(map[*powerNetwork]bool)
+	for _, towerID := range entity.SortedIDs(g.ECS.Towers) {
+		network := cache.networkOf[towerID]
+		if network == nil {
+			g.ECS.Towers[towerID].Flow = component.PowerFlow{} // Вне сети баланса нет
+			continue
+		}
+		if visited[network] {
+			continue
+		}
+		visited[network] = true
+		g.balanceNetworkFlow(cache, network)
+	}
+}
+
+// balanceNetworkFlow computes Tower.Flow for all members of one network.
+func (g *Game) balanceNetworkFlow(cache *powerNetworkCache, network *powerNetwork) {
+	members := network.members
+	oreOf := make(map[types.EntityID]types.EntityID, len(network.supplier))
+	for oreID, towerID := range network.supplier {
+		oreOf[towerID] = oreID
+	}
+	supply := make(map[types.EntityID]float64, len(members))
+	demand := make(map[types.EntityID]float64, len(members))
+	var totalSupply, totalDemand float64
+	for _, id := range members {
+		supply[id] = g.towerOreSupply(id, oreOf[id])
+		demand[id] = g.towerOreDemand(id)
+		totalSupply += supply[id]
+		totalDemand += demand[id]
+	}
+
+	// Share of demand served and share of supply used, network-wide.
+	served, used := 1.0, 1.0
+	if totalDemand > totalSupply {
+		served = totalSupply / totalDemand
+	} else if totalSupply > 0 {
+		used = totalDemand / totalSupply
+	}
+
+	// Spanning tree over the lines, rooted at the lowest ID. Members are visited in
+	// BFS order, so walking the order backwards accumulates subtree balances bottom-up.
+	root := members[0]
+	parent := map[types.EntityID]types.EntityID{root: root}
+	order := []types.EntityID{root}
+	for head := 0; head < len(order); head++ {
+		for _, neighborID := range cache.adj[order[head]] {
+			if _, seen := parent[neighborID]; !seen {
+				parent[neighborID] = order[head]
+				order = append(order, neighborID)
+			}
+		}
+	}
+	balance := make(map[types.EntityID]float64, len(order))
+	for i := len(order) - 1; i > 0; i-- {
+		id := order[i]
+		balance[id] += supply[id]*used - demand[id]*served
+		balance[parent[id]] += balance[id]
+	}
+
+	// Preorder numbering of the same tree: the subtree of id occupies
+	// preorder[enter[id]:exit[id]], so members can be marked by range.
+	children := make(map[types.EntityID][]types.EntityID, len(order))
+	for _, id := range order[1:] {
+		children[parent[id]] = append(children[parent[id]], id)
+	}
+	preorder := make([]types.EntityID, 0, len(order))
+	enter := make(map[types.EntityID]int, len(order))
+	exit := make(map[types.EntityID]int, len(order))
+	stack := []types.EntityID{root}
+	for len(stack) > 0 {
+		id := stack[len(stack)-1]
+		if _, entered := enter[id]; entered {
+			stack = stack[:len(stack)-1]
+			exit[id] = len(preorder)
+			continue
+		}
+		enter[id] = len(preorder)
+		preorder = append(preorder, id)
+		for i := len(children[id]) - 1; i >= 0; i-- {
+			stack = append(stack, children[id][i])
+		}
+	}
+
+	// A line is overloaded when the subtree below it has to import or export more
+	// than LineMaxFlow. The importing side only gets cap/flow of what it needs.
+	lineFactor := make(map[types.EntityID]float64, len(members))
+	for _, id := range members {
+		lineFactor[id] = 1.0
+	}
+	limit := func(ids []types.EntityID, factor float64) {
+		for _, memberID := range ids {
+			lineFactor[memberID] = min(lineFactor[memberID], factor)
+		}
+	}
+	for _, id := range order[1:] {
+		flow := balance[id]
+		if flow < 0 {
+			flow = -flow
+		}
+		if flow <= config.LineMaxFlow {
+			continue
+		}
+		factor := config.LineMaxFlow / flow
+		if balance[id] < 0 {
+			limit(preorder[enter[id]:exit[id]], factor) // The subtree imports
+		} else {
+			limit(preorder[:enter[id]], factor) // The rest of the network imports
+			limit(preorder[exit[id]:], factor)
+		}
+	}
+
+	for _, id := range members {
+		g.ECS.Towers[id].Flow = component.PowerFlow{
+			Computed: true,
+			Supply:   totalSupply,
+			Demand:   totalDemand,
+			Throttle: served * lineFactor[id],
+		}
+	}
+}
+
+// towerOreSupply returns how much ore per second a tower can feed into its network.
+// oreID is the ore under the tower, or 0.
+func (g *Game) towerOreSupply(id, oreID types.EntityID) float64 {
+	tower := g.ECS.Towers[id]
+	towerDef, ok := defs.TowerDefs[tower.DefID]
+	if !ok || towerDef.Energy == nil || tower.IsManuallyDisabled {
+		return 0
+	}
+	var rate float64
+	if ore, onOre := g.ECS.Ores[oreID]; onOre && ore.CurrentReserve >= config.OreDepletionThreshold {
+		rate += ore.Power * towerDef.Energy.ExtractionRate
+	}
+	if g.isDischargingBattery(id) {
+		rate += towerDef.Energy.DischargeRate
+	}
+	return rate
+}
+
+// towerOreDemand returns how much ore per second a firing tower wants at full fire rate.
+// It mirrors the conditions under which CombatSystem spends ore on a shot; a tower
+// that has had nothing to shoot at for config.FlowIdleGrace seconds wants nothing,
+// so idle towers do not throttle the ones that are firing.
+func (g *Game) towerOreDemand(id types.EntityID) float64 {
+	tower := g.ECS.Towers[id]
+	combat, ok := g.ECS.Combats[id]
+	if !ok || !tower.IsActive || tower.IsManuallyDisabled || combat.ShotCost <= 0 {
+		return 0
+	}
+	if combat.IdleTime >= config.FlowIdleGrace {
+		return 0
+	}
+	if combat.Attack.Type == defs.BehaviorAreaOfEffect || combat.Attack.Type == defs.BehaviorNone {
+		return 0
+	}
+	fireRate := combat.FireRate
+	if auraEffect, ok := g.ECS.AuraEffects[id]; ok {
+		fireRate *= auraEffect.SpeedMultiplier
+	}
+	return combat.ShotCost * fireRate
+}
@@ -0,0 +1,170 @@
+// internal/app/power_network.go
+package app
+
+import (
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/pkg/hexmap"
+	"sort"
+)
+
+// powerNetwork is one connected component of the energy network: its member
+// towers and everything in it that can supply power.
+type powerNetwork struct {
+	members    []types.EntityID // Towers of the component, sorted by ID
+	oreSources []types.EntityID // Ore entities under enabled miners and batteries, sorted by ID
+	batteries  []types.EntityID // Enabled batteries of the component, sorted by ID
+	// supplier maps each ore source to the tower standing on it.
+	supplier map[types.EntityID]types.EntityID
+}
+
+// powerNetworkCache is the network model consumers query every shot or tick.
+// It only depends on the topology (towers, lines, tower types, manual toggles),
+// so it is rebuilt lazily after invalidatePowerNetworks instead of on every query.
+// Reserve-dependent checks stay live: an ore or battery that runs dry between
+// invalidations is filtered out when sources are requested.
+type powerNetworkCache struct {
+	valid     bool
+	adj       map[types.EntityID][]types.EntityID
+	networkOf map[types.EntityID]*powerNetwork
+	// hopsFrom memoizes per-consumer hop distances to sources; filled on demand.
+	hopsFrom map[types.EntityID]map[types.EntityID]int
+}
+
+// invalidatePowerNetworks marks the cached network model as stale. It is called
+// on every topology change: lines created or removed, towers placed, removed or
+// crafted, tower and battery toggles, and ore depletion.
+func (g *Game) invalidatePowerNetworks() {
+	g.powerNetworks.valid = false
+}
+
+// removeLine deletes an energy line and invalidates the cached network model.
+func (g *Game) removeLine(lineID types.EntityID) {
+	delete(g.ECS.LineRenders, lineID)
+	g.invalidatePowerNetworks()
+}
+
+// ensurePowerNetworks rebuilds the cached network model if it is stale.
+func (g *Game) ensurePowerNetworks() *powerNetworkCache {
+	cache := &g.powerNetworks
+	if cache.valid {
+		return cache
+	}
+
+	// Lines are walked in ID order so neighbor lists, and therefore the BFS in
+	// FindPathToPowerSource, do not depend on map iteration order.
+	cache.adj = make(map[types.EntityID][]types.EntityID)
+	for _, lineID := range entity.SortedIDs(g.ECS.LineRenders) {
+		line := g.ECS.LineRenders[lineID]
+		if _, ok := g.ECS.Towers[line.Tower1ID]; !ok {
+			continue
+		}
+		if _, ok := g.ECS.Towers[line.Tower2ID]; !ok {
+			continue
+		}
+		cache.adj[line.Tower1ID] = append(cache.adj[line.Tower1ID], line.Tower2ID)
+		cache.adj[line.Tower2ID] = append(cache.adj[line.Tower2ID], line.Tower1ID)
+	}
+
+	oreAt := make(map[hexmap.Hex]types.EntityID, len(g.ECS.Ores))
+	for oreID, ore := range g.ECS.Ores {
+		oreAt[hexmap.PixelToHex(ore.Position.X, ore.Position.Y, float64(config.HexSize))] = oreID
+	}
+
+	cache.hopsFrom = make(map[types.EntityID]map[types.EntityID]int)
+	cache.networkOf = make(map[types.EntityID]*powerNetwork, len(g.ECS.Towers))
+	for _, startID := range entity.SortedIDs(g.ECS.Towers) {
+		if _, seen := cache.networkOf[startID]; seen {
+			continue
+		}
+		network := &powerNetwork{supplier: make(map[types.EntityID]types.EntityID)}
+		cache.networkOf[startID] = network
+		queue := []types.EntityID{startID}
+		for head := 0; head < len(queue); head++ {
+			currentID := queue[head]
+			network.members = append(network.members, currentID)
+			for _, neighborID := range cache.adj[currentID] {
+				if _, seen := cache.networkOf[neighborID]; !seen {
+					cache.networkOf[neighborID] = network
+					queue = append(queue, neighborID)
+				}
+			}
+		}
+		g.collectNetworkSources(network, oreAt)
+	}
+
+	cache.valid = true
+	return cache
+}
+
+// collectNetworkSources fills the ore and battery candidates of a component.
+func (g *Game) collectNetworkSources(network *powerNetwork, oreAt map[hexmap.Hex]types.EntityID) {
+	sort.Slice(network.members, func(i, j int) bool { return network.members[i] < network.members[j] })
+	for _, id := range network.members {
+		tower := g.ECS.Towers[id]
+		if tower.IsManuallyDisabled {
+			continue // A switched-off miner or battery still relays power but supplies none.
+		}
+		if _, isBattery := g.ECS.Batteries[id]; isBattery {
+			network.batteries = append(network.batteries, id)
+		}
+		towerDef, ok := defs.TowerDefs[tower.DefID]
+		if !ok || !towerDef.Type.IsEnergyRelay() {
+			continue
+		}
+		if oreID, onOre := oreAt[tower.Hex]; onOre {
+			network.oreSources = append(network.oreSources, oreID)
+			network.supplier[oreID] = id
+		}
+	}
+	sort.Slice(network.oreSources, func(i, j int) bool { return network.oreSources[i] < network.oreSources[j] })
+}
+
+// powerNetworkOf returns the cached component a tower belongs to, or nil.
+func (g *Game) powerNetworkOf(towerID types.EntityID) *powerNetwork {
+	return g.ensurePowerNetworks().networkOf[towerID]
+}
+
+// powerAdjacency returns the cached adjacency list of the energy network.
+// Callers must not modify it.
+func (g *Game) powerAdjacency() map[types.EntityID][]types.EntityID {
+	return g.ensurePowerNetworks().adj
+}
+
+// powerSourceHops returns, for every source candidate of the tower's network, the
+// number of lines between the tower and the supplying miner or battery. Used by
+// the "nearest" ore draw policy; memoized until the next invalidation.
+func (g *Game) powerSourceHops(towerID types.EntityID) map[types.EntityID]int {
+	cache := g.ensurePowerNetworks()
+	if hops, ok := cache.hopsFrom[towerID]; ok {
+		return hops
+	}
+	network := cache.networkOf[towerID]
+	if network == nil {
+		return nil
+	}
+
+	towerHops := map[types.EntityID]int{towerID: 0}
+	queue := []types.EntityID{towerID}
+	for head := 0; head < len(queue); head++ {
+		currentID := queue[head]
+		for _, neighborID := range cache.adj[currentID] {
+			if _, seen := towerHops[neighborID]; !seen {
+				towerHops[neighborID] = towerHops[currentID] + 1
+				queue = append(queue, neighborID)
+			}
+		}
+	}
+
+	hops := make(map[types.EntityID]int, len(network.oreSources)+len(network.batteries))
+	for _, oreID := range network.oreSources {
+		hops[oreID] = towerHops[network.supplier[oreID]]
+	}
+	for _, batteryID := range network.batteries {
+		hops[batteryID] = towerHops[batteryID]
+	}
+	cache.hopsFrom[towerID] = hops
+	return hops
+}
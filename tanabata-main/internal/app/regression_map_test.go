@@ -0,0 +1,56 @@
+// internal/app/regression_map_test.go
+package app
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/utils"
+	"testing"
+)
+
+// Контрольная сумма и число тиков прогона TestRegressionMapChecksum. Меняются
+// только вместе с намеренной правкой баланса или симуляции; после такой правки
+// новые значения снимаются этим же тестом.
+const (
+	regressionMapTicks    = 10947
+	regressionMapChecksum = 0x1a6ed4ffd06170b3
+)
+
+// TestRegressionMapChecksum проигрывает первые волны на regression_seed7.json с
+// фиксированным сидом так же, как cmd/sim, и сверяет контрольную сумму состояния.
+// Расхождение значит, что симуляция на зафиксированной карте стала вести себя иначе.
+func TestRegressionMapChecksum(t *testing.T) {
+	const (
+		seed  = 7
+		waves = 3
+		dt    = 1.0 / 60.0
+	)
+	if err := defs.LoadAll("../../assets/data"); err != nil {
+		t.Fatalf("load definitions: %v", err)
+	}
+	towerDefs := make(map[string]*defs.TowerDefinition)
+	for id, def := range defs.TowerDefs {
+		d := def
+		towerDefs[id] = &d
+	}
+	g, err := NewGameOnMap("../../assets/maps/regression_seed7.json", towerDefs, utils.NewPRNGService(seed), defs.DefaultLevelID)
+	if err != nil {
+		t.Fatalf("start the game: %v", err)
+	}
+	g.ToggleGodMode()
+
+	ticks := 0
+	for ; ticks < 1_000_000; ticks++ {
+		if g.ECS.GameState.Phase == component.BuildState {
+			if g.Wave > waves {
+				break
+			}
+			g.HandleIndicatorClick()
+		}
+		g.Update(dt)
+	}
+	if ticks != regressionMapTicks || g.StateChecksum() != regressionMapChecksum {
+		t.Fatalf("after %d waves: %d ticks, checksum %#x; want %d ticks, checksum %#x",
+			waves, ticks, g.StateChecksum(), regressionMapTicks, regressionMapChecksum)
+	}
+}
@@ -0,0 +1,294 @@
+// internal/app/replay.go
+package app
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/pkg/hexmap"
+	"hash/fnv"
+	"log"
+	"math"
+	"os"
+	"path/filepath"
+)
+
+// ReplayVersion — версия формата файла реплея. Увеличивается при несовместимых изменениях.
+const ReplayVersion = 1
+
+// CommandType — тип действия игрока, записываемого в журнал.
+type CommandType string
+
+const (
+	CmdPlaceTower                  CommandType = "PlaceTower"
+	CmdRemoveTower                 CommandType = "RemoveTower"
+	CmdCombineTowers               CommandType = "CombineTowers"
+	CmdToggleTowerSelectionForSave CommandType = "ToggleTowerSelectionForSave"
+	CmdIndicatorClick              CommandType = "IndicatorClick" // Переключение фазы строительство/волна
+	CmdSpeedClick                  CommandType = "SpeedClick"
+	CmdToggleGodMode               CommandType = "ToggleGodMode"
+	CmdShiftClick                  CommandType = "ShiftClick"
+	CmdSetHighlightedTower         CommandType = "SetHighlightedTower"
+	CmdClearAllSelections          CommandType = "ClearAllSelections"
+	CmdClearManualSelection        CommandType = "ClearManualSelection"
+	CmdToggleLineDragMode          CommandType = "ToggleLineDragMode"
+	CmdLineDragClick               CommandType = "LineDragClick" // Начало или завершение (finishLineDrag) перетаскивания линии
+	CmdCancelLineDrag              CommandType = "CancelLineDrag"
+	CmdCreateDebugTower            CommandType = "CreateDebugTower"
+	CmdToggleBatteryMode           CommandType = "ToggleBatteryMode"
+	CmdToggleTowerEnabled          CommandType = "ToggleTowerEnabled"
+	CmdCycleOreDrawPolicy          CommandType = "CycleOreDrawPolicy" // TowerID == 0 — политика всей игры
+)
+
+// Command — одно действие игрока, привязанное к тику симуляции.
+// Используются только поля, нужные конкретному типу команды.
+type Command struct {
+	Tick    uint64         `json:"tick"`
+	Type    CommandType    `json:"type"`
+	Hex     hexmap.Hex     `json:"hex"`
+	TowerID types.EntityID `json:"tower_id,omitempty"`
+	X       float64        `json:"x,omitempty"`
+	Y       float64        `json:"y,omitempty"`
+	DefID   string         `json:"def_id,omitempty"`
+	Right   bool           `json:"right,omitempty"` // Для ShiftClick: правая кнопка вместо левой
+}
+
+// Replay — файл реплея: сид, уровень, карта, шаг симуляции и журнал команд.
+// FinalTick и Checksum позволяют проверить, что воспроизведение совпало бит в бит.
+type Replay struct {
+	Version   int       `json:"version"`
+	Seed      int64     `json:"seed"`
+	Level     string    `json:"level,omitempty"` // Уровень партии; пусто — стандартный
+	Map       string    `json:"map,omitempty"`   // Файл карты партии; пусто — карта сгенерирована из сида
+	Timestep  float64   `json:"timestep"`
+	Commands  []Command `json:"commands"`
+	FinalTick uint64    `json:"final_tick"`
+	Checksum  uint64    `json:"checksum"`
+}
+
+// EnableRecording включает запись действий игрока. timestep — фиксированный шаг,
+// с которым вызывается Update; без него воспроизведение не будет точным.
+func (g *Game) EnableRecording(timestep float64) {
+	g.recording = &Replay{
+		Version:  ReplayVersion,
+		Seed:     g.Rng.Seed(),
+		Level:    g.LevelID,
+		Map:      g.MapPath,
+		Timestep: timestep,
+		Commands: []Command{},
+	}
+}
+
+// Recording возвращает текущую запись с проставленными финальным тиком и контрольной суммой,
+// или nil, если запись не включена.
+func (g *Game) Recording() *Replay {
+	if g.recording == nil {
+		return nil
+	}
+	g.recording.FinalTick = g.Tick
+	g.recording.Checksum = g.StateChecksum()
+	return g.recording
+}
+
+// beginCommand заносит команду в журнал и возвращает функцию завершения для defer.
+// Записываются только команды верхнего уровня: вложенные вызовы (например,
+// ClearAllSelections внутри HandleIndicatorClick) и всё, что вызывается из Update,
+// воспроизводятся сами собой и в журнал не попадают.
+func (g *Game) beginCommand(cmd Command) func() {
+	if g.commandDepth == 0 && g.recording != nil {
+		cmd.Tick = g.Tick
+		g.recording.Commands = append(g.recording.Commands, cmd)
+	}
+	g.commandDepth++
+	return func() { g.commandDepth-- }
+}
+
+// ApplyCommand выполняет записанную команду через тот же публичный API, что и ввод игрока.
+func (g *Game) ApplyCommand(cmd Command) {
+	switch cmd.Type {
+	case CmdPlaceTower:
+		g.PlaceTower(cmd.Hex)
+	case CmdRemoveTower:
+		g.RemoveTower(cmd.Hex)
+	case CmdCombineTowers:
+		g.CombineTowers(cmd.TowerID)
+	case CmdToggleTowerSelectionForSave:
+		g.ToggleTowerSelectionForSave(cmd.TowerID)
+	case CmdIndicatorClick:
+		g.HandleIndicatorClick()
+	case CmdSpeedClick:
+		g.HandleSpeedClick()
+	case CmdToggleGodMode:
+		g.ToggleGodMode()
+	case CmdShiftClick:
+		g.HandleShiftClick(cmd.Hex, !cmd.Right, cmd.Right)
+	case CmdSetHighlightedTower:
+		g.SetHighlightedTower(cmd.TowerID)
+	case CmdClearAllSelections:
+		g.ClearAllSelections()
+	case CmdClearManualSelection:
+		g.ClearManualSelection()
+	case CmdToggleLineDragMode:
+		g.ToggleLineDragMode()
+	case CmdLineDragClick:
+		g.HandleLineDragClick(cmd.Hex, cmd.X, cmd.Y)
+	case CmdCancelLineDrag:
+		g.CancelLineDrag()
+	case CmdCreateDebugTower:
+		g.CreateDebugTower(cmd.Hex, cmd.DefID)
+	case CmdToggleBatteryMode:
+		g.ToggleBatteryMode(cmd.TowerID)
+	case CmdToggleTowerEnabled:
+		g.ToggleTowerEnabled(cmd.TowerID)
+	case CmdCycleOreDrawPolicy:
+		g.CycleOreDrawPolicy(cmd.TowerID)
+	default:
+		log.Printf("[REPLAY] Неизвестная команда %q на тике %d", cmd.Type, cmd.Tick)
+	}
+}
+
+// StateChecksum считает хеш значимого состояния симуляции: башни, линии, руда,
+// батареи, враги, игрок, волна и фаза. Обход идет в порядке ID, поэтому хеш детерминирован.
+func (g *Game) StateChecksum() uint64 {
+	h := fnv.New64a()
+	buf := make([]byte, 8)
+	writeU64 := func(v uint64) {
+		binary.LittleEndian.PutUint64(buf, v)
+		h.Write(buf)
+	}
+	writeInt := func(v int) { writeU64(uint64(int64(v))) }
+	writeFloat := func(v float64) { writeU64(math.Float64bits(v)) }
+	writeBool := func(v bool) {
+		if v {
+			writeU64(1)
+		} else {
+			writeU64(0)
+		}
+	}
+
+	writeU64(g.Tick)
+	writeInt(g.Wave)
+	writeInt(int(g.ECS.GameState.Phase))
+	if player, ok := g.ECS.PlayerState[g.PlayerID]; ok {
+		writeInt(player.Level)
+		writeInt(player.CurrentXP)
+		writeInt(player.Health)
+	}
+	for _, id := range entity.SortedIDs(g.ECS.Towers) {
+		tower := g.ECS.Towers[id]
+		writeInt(int(id))
+		h.Write([]byte(tower.DefID))
+		writeInt(tower.Hex.Q)
+		writeInt(tower.Hex.R)
+		writeBool(tower.IsActive)
+	}
+	for _, id := range entity.SortedIDs(g.ECS.LineRenders) {
+		line := g.ECS.LineRenders[id]
+		writeInt(int(line.Tower1ID))
+		writeInt(int(line.Tower2ID))
+	}
+	for _, id := range entity.SortedIDs(g.ECS.Ores) {
+		writeInt(int(id))
+		writeFloat(g.ECS.Ores[id].CurrentReserve)
+	}
+	for _, id := range entity.SortedIDs(g.ECS.Batteries) {
+		writeInt(int(id))
+		writeInt(int(g.ECS.Batteries[id].Mode))
+		writeFloat(g.ECS.Batteries[id].Stored)
+	}
+	for _, id := range entity.SortedIDs(g.ECS.Enemies) {
+		writeInt(int(id))
+		if health, ok := g.ECS.Healths[id]; ok {
+			writeInt(health.Value)
+		}
+		if pos, ok := g.ECS.Positions[id]; ok {
+			writeFloat(pos.X)
+			writeFloat(pos.Y)
+		}
+	}
+	return h.Sum64()
+}
+
+// SaveReplay записывает реплей в JSON-файл, создавая каталог при необходимости.
+func SaveReplay(path string, r *Replay) error {
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// LoadReplay читает файл реплея и проверяет его версию.
+func LoadReplay(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Replay
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, err
+	}
+	if r.Version != ReplayVersion {
+		return nil, fmt.Errorf("unsupported replay version %d (expected %d)", r.Version, ReplayVersion)
+	}
+	if r.Timestep <= 0 {
+		return nil, fmt.Errorf("invalid replay timestep %v", r.Timestep)
+	}
+	return &r, nil
+}
+
+// ReplayPlayer проигрывает журнал команд поверх игры, созданной с тем же сидом.
+type ReplayPlayer struct {
+	replay *Replay
+	next   int
+}
+
+// NewReplayPlayer создает проигрыватель для указанного реплея.
+func NewReplayPlayer(r *Replay) *ReplayPlayer {
+	return &ReplayPlayer{replay: r}
+}
+
+// Replay возвращает проигрываемый реплей.
+func (p *ReplayPlayer) Replay() *Replay {
+	return p.replay
+}
+
+// Done возвращает true, когда игра дошла до финального тика записи.
+func (p *ReplayPlayer) Done(g *Game) bool {
+	return g.Tick >= p.replay.FinalTick
+}
+
+// Step применяет все команды текущего тика и продвигает симуляцию на один фиксированный шаг.
+func (p *ReplayPlayer) Step(g *Game) {
+	p.applyPending(g)
+	g.Update(p.replay.Timestep)
+}
+
+// applyPending выполняет команды, записанные на текущем (или более раннем) тике.
+func (p *ReplayPlayer) applyPending(g *Game) {
+	for p.next < len(p.replay.Commands) && p.replay.Commands[p.next].Tick <= g.Tick {
+		cmd := p.replay.Commands[p.next]
+		if cmd.Tick < g.Tick {
+			log.Printf("[REPLAY] Команда %s опоздала: тик %d, текущий %d", cmd.Type, cmd.Tick, g.Tick)
+		}
+		g.ApplyCommand(cmd)
+		p.next++
+	}
+}
+
+// Verify применяет команды, сделанные после последнего шага, и сравнивает
+// итоговое состояние с контрольной суммой из файла.
+func (p *ReplayPlayer) Verify(g *Game) error {
+	p.applyPending(g)
+	if got := g.StateChecksum(); got != p.replay.Checksum {
+		return fmt.Errorf("replay desync at tick %d: checksum %x, expected %x", g.Tick, got, p.replay.Checksum)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+// internal/app/route_flows.go
+package app
+
+import (
+	"go-tower-defense/pkg/hexmap"
+)
+
+// routeFlowCache — поля потока маршрутов врагов (см. hexmap.RouteFlows),
+// построенные по ним маршруты и гексы, критичные для маршрутов. Все это зависит
+// только от проходимости карты, поэтому строится лениво после invalidateRouteFlows,
+// а не для каждого врага, превью пути и проверки размещения.
+type routeFlowCache struct {
+	valid    bool
+	flows    *hexmap.RouteFlows
+	routes   [][]hexmap.Hex      // Маршрут каждого входа; nil — маршрут перекрыт
+	open     bool                // Открыты ли маршруты всех входов
+	critical map[hexmap.Hex]bool // Гексы, препятствие на которых перекроет маршрут
+	blocking []hexmap.Hex        // Свободные гексы, где постройку запрещает маршрут (см. BlockingHexes)
+}
+
+// invalidateRouteFlows помечает поля потока устаревшими. Вызывается при каждом
+// изменении проходимости карты: башни и стены поставлены или убраны, партия загружена.
+func (g *Game) invalidateRouteFlows() {
+	g.routeFlows.valid = false
+}
+
+// setPassable меняет проходимость тайла и сбрасывает поля потока.
+func (g *Game) setPassable(hex hexmap.Hex, passable bool) {
+	g.HexMap.SetPassable(hex, passable)
+	g.invalidateRouteFlows()
+}
+
+// ensureRouteFlows перестраивает поля потока и маршруты, если они устарели.
+func (g *Game) ensureRouteFlows() *routeFlowCache {
+	cache := &g.routeFlows
+	if cache.valid {
+		return cache
+	}
+	cache.flows = hexmap.NewRouteFlows(g.HexMap)
+	cache.routes = make([][]hexmap.Hex, len(cache.flows.Gates))
+	for i, gf := range cache.flows.Gates {
+		cache.routes[i] = gf.Route(g.HexMap)
+	}
+	cache.critical, cache.open = g.HexMap.RouteCriticalHexes()
+	cache.blocking = nil
+	for hex, tile := range g.HexMap.Tiles {
+		if tile.Passable && tile.CanPlaceTower && (!cache.open || cache.critical[hex]) {
+			cache.blocking = append(cache.blocking, hex)
+		}
+	}
+	hexmap.SortHexes(cache.blocking)
+	cache.valid = true
+	return cache
+}
+
+// RouteFlows возвращает поля потока маршрутов для текущей карты.
+func (g *Game) RouteFlows() *hexmap.RouteFlows {
+	return g.ensureRouteFlows().flows
+}
+
+// isPathBlockedBy сообщает, перекроет ли препятствие на hex маршрут какого-либо
+// входа. Критичные гексы считаются один раз после изменения карты (точки
+// сочленения на каждом участке маршрута), поэтому проверка — поиск в множестве.
+func (g *Game) isPathBlockedBy(hex hexmap.Hex) bool {
+	cache := g.ensureRouteFlows()
+	return !cache.open || cache.critical[hex]
+}
+
+// BlockingHexes возвращает гексы, где постройку запрещает только маршрут врагов:
+// тайл свободен и открыт для строительства, но препятствие на нем перекроет
+// маршрут какого-либо входа. Гексы отсортированы, чтобы их можно было подсветить
+// разом; список считается вместе с полями потока, вызывающий не должен его менять.
+func (g *Game) BlockingHexes() []hexmap.Hex {
+	return g.ensureRouteFlows().blocking
+}
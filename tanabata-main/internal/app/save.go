@@ -0,0 +1,335 @@
+// internal/app/save.go
+package app
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
+	"go-tower-defense/pkg/hexmap"
+	"image/color"
+	"os"
+	"path/filepath"
+)
+
+// SaveVersion — версия формата сохранения. Увеличивается при несовместимых изменениях;
+// файлы другой версии не загружаются.
+const SaveVersion = 5
+
+// saveMagic отличает файл сохранения от произвольного gob-потока.
+const saveMagic = "TANABATA-SAVE"
+
+func init() {
+	// Цвета линий, лазеров и извержений хранятся как color.Color; все они — color.RGBA
+	gob.Register(color.RGBA{})
+}
+
+// saveHeader пишется первым, чтобы версию можно было проверить до разбора остального файла.
+type saveHeader struct {
+	Magic   string
+	Version int
+}
+
+// saveData — полный снимок партии. Системы в файл не пишутся: они пересоздаются
+// newGame, а их состояние живет в ECS, кроме счетчика врагов волны и веток генератора.
+type saveData struct {
+	Seed                   int64
+	LevelID                string
+	MapPath                string
+	Rng                    map[string]utils.PRNGState // Состояние корня ("") и всех веток
+	Tick                   uint64
+	Wave                   int
+	BaseHealth             int
+	TowersBuilt            int
+	SpeedLevel             int
+	SpeedMultiplier        float64
+	GameTime               float64
+	GodMode                bool
+	PlayerID               types.EntityID
+	HighlightedTower       types.EntityID
+	ManuallySelectedTowers []types.EntityID
+	ClearedCheckpoints     map[hexmap.Hex]bool
+	FuturePath             []hexmap.Hex
+	OreVeinHexes           [][]hexmap.Hex
+	PinnedLines            []utils.Edge // Линии, перетащенные игроком
+	BannedLines            []utils.Edge // Линии, с которых игрок их перетащил
+	ActiveEnemies          int
+	HexMap                 hexmap.HexMap
+	ECS                    ecsSnapshot
+	Recording              *Replay // Журнал команд с начала партии, если идет запись
+}
+
+// ecsSnapshot — компонентные карты entity.ECS в виде, пригодном для gob.
+// Combinables не сохраняются (они ссылаются на рецепты и пересчитываются при загрузке),
+// а пустые маркеры ручного выбора хранятся списком ID.
+type ecsSnapshot struct {
+	GameTime               float64
+	NextID                 types.EntityID
+	Positions              map[types.EntityID]*component.Position
+	Velocities             map[types.EntityID]*component.Velocity
+	Paths                  map[types.EntityID]*component.Path
+	Healths                map[types.EntityID]*component.Health
+	Renderables            map[types.EntityID]*component.Renderable
+	Towers                 map[types.EntityID]*component.Tower
+	Projectiles            map[types.EntityID]*component.Projectile
+	Combats                map[types.EntityID]*component.Combat
+	Ores                   map[types.EntityID]*component.Ore
+	Enemies                map[types.EntityID]*component.Enemy
+	LineRenders            map[types.EntityID]*component.LineRender
+	Texts                  map[types.EntityID]*component.Text
+	DamageFlashes          map[types.EntityID]*component.DamageFlashComponent
+	AoeEffects             map[types.EntityID]*component.AoeEffectComponent
+	Auras                  map[types.EntityID]*component.Aura
+	AuraEffects            map[types.EntityID]*component.AuraEffect
+	SlowEffects            map[types.EntityID]*component.SlowEffect
+	PoisonEffects          map[types.EntityID]*component.PoisonEffect
+	JadePoisonContainers   map[types.EntityID]*component.JadePoisonContainer
+	Lasers                 map[types.EntityID]*component.Laser
+	VolcanoEffects         map[types.EntityID]*component.VolcanoEffect
+	VolcanoAuras           map[types.EntityID]*component.VolcanoAura
+	ManualSelectionMarkers []types.EntityID
+	PlayerState            map[types.EntityID]*component.PlayerStateComponent
+	Beacons                map[types.EntityID]*component.Beacon
+	BeaconAttackSectors    map[types.EntityID]*component.BeaconAttackSector
+	Turrets                map[types.EntityID]*component.TurretComponent
+	EnemyAbilities         map[types.EntityID]*component.EnemyAbilities
+	Batteries              map[types.EntityID]*component.Battery
+	Wave                   *component.Wave
+	GameState              *component.GameState
+}
+
+func snapshotECS(ecs *entity.ECS) ecsSnapshot {
+	return ecsSnapshot{
+		GameTime:               ecs.GameTime,
+		NextID:                 ecs.NextID,
+		Positions:              ecs.Positions,
+		Velocities:             ecs.Velocities,
+		Paths:                  ecs.Paths,
+		Healths:                ecs.Healths,
+		Renderables:            ecs.Renderables,
+		Towers:                 ecs.Towers,
+		Projectiles:            ecs.Projectiles,
+		Combats:                ecs.Combats,
+		Ores:                   ecs.Ores,
+		Enemies:                ecs.Enemies,
+		LineRenders:            ecs.LineRenders,
+		Texts:                  ecs.Texts,
+		DamageFlashes:          ecs.DamageFlashes,
+		AoeEffects:             ecs.AoeEffects,
+		Auras:                  ecs.Auras,
+		AuraEffects:            ecs.AuraEffects,
+		SlowEffects:            ecs.SlowEffects,
+		PoisonEffects:          ecs.PoisonEffects,
+		JadePoisonContainers:   ecs.JadePoisonContainers,
+		Lasers:                 ecs.Lasers,
+		VolcanoEffects:         ecs.VolcanoEffects,
+		VolcanoAuras:           ecs.VolcanoAuras,
+		ManualSelectionMarkers: entity.SortedIDs(ecs.ManualSelectionMarkers),
+		PlayerState:            ecs.PlayerState,
+		Beacons:                ecs.Beacons,
+		BeaconAttackSectors:    ecs.BeaconAttackSectors,
+		Turrets:                ecs.Turrets,
+		EnemyAbilities:         ecs.EnemyAbilities,
+		Batteries:              ecs.Batteries,
+		Wave:                   ecs.Wave,
+		GameState:              ecs.GameState,
+	}
+}
+
+// restoreInto переносит снимок в существующий ECS. Сам указатель на ECS менять нельзя:
+// он уже роздан системам. Пустые карты gob не пишет, поэтому nil заменяется пустой картой.
+func (s ecsSnapshot) restoreInto(ecs *entity.ECS) {
+	ecs.GameTime = s.GameTime
+	ecs.NextID = s.NextID
+	restoreMap(&ecs.Positions, s.Positions)
+	restoreMap(&ecs.Velocities, s.Velocities)
+	restoreMap(&ecs.Paths, s.Paths)
+	restoreMap(&ecs.Healths, s.Healths)
+	restoreMap(&ecs.Renderables, s.Renderables)
+	restoreMap(&ecs.Towers, s.Towers)
+	restoreMap(&ecs.Projectiles, s.Projectiles)
+	restoreMap(&ecs.Combats, s.Combats)
+	restoreMap(&ecs.Ores, s.Ores)
+	restoreMap(&ecs.Enemies, s.Enemies)
+	restoreMap(&ecs.LineRenders, s.LineRenders)
+	restoreMap(&ecs.Texts, s.Texts)
+	restoreMap(&ecs.DamageFlashes, s.DamageFlashes)
+	restoreMap(&ecs.AoeEffects, s.AoeEffects)
+	restoreMap(&ecs.Auras, s.Auras)
+	restoreMap(&ecs.AuraEffects, s.AuraEffects)
+	restoreMap(&ecs.SlowEffects, s.SlowEffects)
+	restoreMap(&ecs.PoisonEffects, s.PoisonEffects)
+	restoreMap(&ecs.JadePoisonContainers, s.JadePoisonContainers)
+	restoreMap(&ecs.Lasers, s.Lasers)
+	restoreMap(&ecs.VolcanoEffects, s.VolcanoEffects)
+	restoreMap(&ecs.VolcanoAuras, s.VolcanoAuras)
+	restoreMap(&ecs.PlayerState, s.PlayerState)
+	restoreMap(&ecs.Beacons, s.Beacons)
+	restoreMap(&ecs.BeaconAttackSectors, s.BeaconAttackSectors)
+	restoreMap(&ecs.Turrets, s.Turrets)
+	restoreMap(&ecs.EnemyAbilities, s.EnemyAbilities)
+	restoreMap(&ecs.Batteries, s.Batteries)
+	ecs.ManualSelectionMarkers = make(map[types.EntityID]*component.ManualSelectionMarker)
+	for _, id := range s.ManualSelectionMarkers {
+		ecs.ManualSelectionMarkers[id] = &component.ManualSelectionMarker{}
+	}
+	ecs.Combinables = make(map[types.EntityID]*component.Combinable)
+	ecs.Wave = s.Wave
+	if s.GameState != nil {
+		ecs.GameState = s.GameState
+	}
+}
+
+func restoreMap[V any](dst *map[types.EntityID]V, src map[types.EntityID]V) {
+	if src == nil {
+		src = make(map[types.EntityID]V)
+	}
+	*dst = src
+}
+
+// SaveGame записывает полное состояние партии в файл, создавая каталог при необходимости.
+// Незавершенное перетаскивание линии не сохраняется.
+func (g *Game) SaveGame(path string) error {
+	rngStates := map[string]utils.PRNGState{"": g.Rng.State()}
+	for name, stream := range g.rngStreams {
+		rngStates[name] = stream.State()
+	}
+	pinnedLines, bannedLines := g.energyGraph.sortedOverrides()
+	data := saveData{
+		Seed:                   g.Rng.Seed(),
+		LevelID:                g.LevelID,
+		MapPath:                g.MapPath,
+		Rng:                    rngStates,
+		Tick:                   g.Tick,
+		Wave:                   g.Wave,
+		BaseHealth:             g.BaseHealth,
+		TowersBuilt:            g.towersBuilt,
+		SpeedLevel:             g.SpeedLevel,
+		SpeedMultiplier:        g.SpeedMultiplier,
+		GameTime:               g.gameTime,
+		GodMode:                g.isGodMode,
+		PlayerID:               g.PlayerID,
+		HighlightedTower:       g.highlightedTower,
+		ManuallySelectedTowers: g.manuallySelectedTowers,
+		ClearedCheckpoints:     g.ClearedCheckpoints,
+		FuturePath:             g.FuturePath,
+		OreVeinHexes:           g.OreVeinHexes,
+		PinnedLines:            pinnedLines,
+		BannedLines:            bannedLines,
+		ActiveEnemies:          g.WaveSystem.ActiveEnemies(),
+		HexMap:                 *g.HexMap,
+		ECS:                    snapshotECS(g.ECS),
+		Recording:              g.recording,
+	}
+
+	if dir := filepath.Dir(path); dir != "" {
+		if err := os.MkdirAll(dir, 0o755); err != nil {
+			return err
+		}
+	}
+	// Пишем во временный файл и переименовываем, чтобы сбой не испортил прежнее сохранение
+	tmpPath := path + ".tmp"
+	f, err := os.Create(tmpPath)
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(f)
+	enc := gob.NewEncoder(w)
+	if err := enc.Encode(saveHeader{Magic: saveMagic, Version: SaveVersion}); err != nil {
+		f.Close()
+		return err
+	}
+	if err := enc.Encode(&data); err != nil {
+		f.Close()
+		return fmt.Errorf("encode save: %w", err)
+	}
+	if err := w.Flush(); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, path)
+}
+
+// LoadGame читает сохранение и восстанавливает игру в точности в том состоянии,
+// в котором она была записана: продолжение партии дает тот же результат,
+// что и игра без сохранения.
+func LoadGame(path string, towerDefs map[string]*defs.TowerDefinition) (*Game, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	dec := gob.NewDecoder(bufio.NewReader(f))
+	var header saveHeader
+	if err := dec.Decode(&header); err != nil {
+		return nil, fmt.Errorf("read save header: %w", err)
+	}
+	if header.Magic != saveMagic {
+		return nil, fmt.Errorf("%s is not a save file", path)
+	}
+	if header.Version != SaveVersion {
+		return nil, fmt.Errorf("unsupported save version %d (expected %d)", header.Version, SaveVersion)
+	}
+	var data saveData
+	if err := dec.Decode(&data); err != nil {
+		return nil, fmt.Errorf("decode save: %w", err)
+	}
+
+	hexMap := data.HexMap
+	if hexMap.Tiles == nil {
+		return nil, fmt.Errorf("save has no map")
+	}
+	g := newGame(&hexMap, towerDefs, utils.NewPRNGService(data.Seed))
+	if state, ok := data.Rng[""]; ok {
+		g.Rng.Restore(state)
+	}
+	for name, stream := range g.rngStreams {
+		if state, ok := data.Rng[name]; ok {
+			stream.Restore(state)
+		}
+	}
+
+	data.ECS.restoreInto(g.ECS)
+	g.invalidatePowerNetworks()
+	g.invalidateRouteFlows()
+	g.LevelID = data.LevelID
+	g.MapPath = data.MapPath
+	g.Tick = data.Tick
+	g.Wave = data.Wave
+	g.BaseHealth = data.BaseHealth
+	g.towersBuilt = data.TowersBuilt
+	g.SpeedLevel = data.SpeedLevel
+	g.SpeedMultiplier = data.SpeedMultiplier
+	g.gameTime = data.GameTime
+	g.isGodMode = data.GodMode
+	g.PlayerID = data.PlayerID
+	g.highlightedTower = data.HighlightedTower
+	g.manuallySelectedTowers = data.ManuallySelectedTowers
+	g.ClearedCheckpoints = data.ClearedCheckpoints
+	g.FuturePath = data.FuturePath
+	g.OreVeinHexes = data.OreVeinHexes
+	for _, edge := range data.PinnedLines {
+		g.energyGraph.pinned[edge] = true
+	}
+	for _, edge := range data.BannedLines {
+		g.energyGraph.banned[edge] = true
+	}
+	g.resetEnergyGraph()
+	g.WaveSystem.SetActiveEnemies(data.ActiveEnemies)
+	g.recording = data.Recording
+	if g.recording != nil && g.recording.Commands == nil {
+		g.recording.Commands = []Command{}
+	}
+
+	// Производные данные не хранятся в файле и пересчитываются из восстановленного мира
+	g.CraftingSystem.RecalculateCombinations()
+	return g, nil
+}
@@ -0,0 +1,95 @@
+// internal/app/terrain_generation.go
+package app
+
+import (
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/utils"
+	"go-tower-defense/pkg/hexmap"
+)
+
+// terrainPatchAttempts ограничивает поиск центра одного пятна местности.
+const terrainPatchAttempts = 50
+
+// generateTerrain раскладывает местность по профилю уровня. Дороги идут по
+// исходным маршрутам входов, пятна грязи, воды и возвышенностей лежат только
+// на проходимой земле и не трогают входы, выходы, чекпоинты и дороги. Вода
+// обходит руду, а озеро, после которого какой-то маршрут оказался перекрыт,
+// убирается.
+func (g *Game) generateTerrain(rng *utils.PRNGService, profile defs.TerrainGenerationProfile) {
+	hm := g.HexMap
+	if profile.Roads {
+		for _, gate := range hm.Gates() {
+			for _, hex := range hm.GateRoute(gate) {
+				if !hm.IsGate(hex) && !hm.IsCheckpoint(hex) {
+					hm.SetTerrain(hex, hexmap.TerrainRoad)
+				}
+			}
+		}
+	}
+
+	oreHexes := make(map[hexmap.Hex]bool)
+	for _, ore := range g.ECS.Ores {
+		oreHexes[ore.Hex] = true
+	}
+	isReserved := func(hex hexmap.Hex) bool {
+		if !hm.IsPassable(hex) || hm.IsCheckpoint(hex) || hm.TerrainAt(hex) != hexmap.TerrainPlain {
+			return true
+		}
+		for _, gateHex := range hm.GateHexes() {
+			if gateHex.Distance(hex) < 2 {
+				return true
+			}
+		}
+		return false
+	}
+
+	candidates := hm.SortedHexes()
+	patches := []struct {
+		terrain hexmap.Terrain
+		count   int
+	}{
+		{hexmap.TerrainWater, profile.Water},
+		{hexmap.TerrainMud, profile.Mud},
+		{hexmap.TerrainHighGround, profile.HighGround},
+	}
+	for _, patch := range patches {
+		for i := 0; i < patch.count; i++ {
+			for attempt := 0; attempt < terrainPatchAttempts; attempt++ {
+				center := candidates[rng.Intn(len(candidates))]
+				if isReserved(center) || (patch.terrain == hexmap.TerrainWater && oreHexes[center]) {
+					continue
+				}
+				if g.placeTerrainPatch(rng, center, profile.PatchRadius, patch.terrain, isReserved, oreHexes) {
+					break
+				}
+			}
+		}
+	}
+}
+
+// placeTerrainPatch кладет пятно местности: центр и, с убыванием к краю, гексы
+// в радиусе radius. Возвращает false, если пятно воды перекрыло маршрут и было убрано.
+func (g *Game) placeTerrainPatch(rng *utils.PRNGService, center hexmap.Hex, radius int, terrain hexmap.Terrain, isReserved func(hexmap.Hex) bool, oreHexes map[hexmap.Hex]bool) bool {
+	hm := g.HexMap
+	area := hm.GetHexesInRange(center, radius)
+	hexmap.SortHexes(area)
+
+	var placed []hexmap.Hex
+	for _, hex := range area {
+		if isReserved(hex) || (terrain == hexmap.TerrainWater && oreHexes[hex]) {
+			continue
+		}
+		if d := center.Distance(hex); d > 0 && rng.Intn(radius+1) < d {
+			continue // Чем дальше от центра, тем реже гекс попадает в пятно
+		}
+		hm.SetTerrain(hex, terrain)
+		placed = append(placed, hex)
+	}
+	if terrain == hexmap.TerrainWater && !hm.RoutesOpen() {
+		for _, hex := range placed {
+			hm.SetTerrain(hex, hexmap.TerrainPlain)
+		}
+		return false
+	}
+	return len(placed) > 0
+}
@@ -14,6 +14,7 @@ import (
 
 // PlaceTower attempts to place a tower at the given hex.
 func (g *Game) PlaceTower(hex hexmap.Hex) bool {
+	defer g.beginCommand(Command{Type: CmdPlaceTower, Hex: hex})()
 	if !g.canPlaceTower(hex) {
 		return false
 	}
@@ -36,8 +37,7 @@ func (g *Game) PlaceTower(hex hexmap.Hex) bool {
 		tower.IsSelected = true // Шахтеры выбираются автоматически
 	}
 
-	tile := g.HexMap.Tiles[hex]
-	g.HexMap.Tiles[hex] = hexmap.Tile{Passable: false, CanPlaceTower: tile.CanPlaceTower}
+	g.setPassable(hex, false)
 
 	g.towersBuilt++
 	if g.towersBuilt >= config.MaxTowersInBuildPhase {
@@ -55,6 +55,7 @@ func (g *Game) PlaceTower(hex hexmap.Hex) bool {
 
 // RemoveTower removes a tower from the given hex.
 func (g *Game) RemoveTower(hex hexmap.Hex) bool {
+	defer g.beginCommand(Command{Type: CmdRemoveTower, Hex: hex})()
 	// Разрешаем удаление в фазах строительства и выбора.
 	if g.ECS.GameState.Phase != component.BuildState && g.ECS.GameState.Phase != component.TowerSelectionState {
 		return false
@@ -76,25 +77,16 @@ func (g *Game) RemoveTower(hex hexmap.Hex) bool {
 			g.towersBuilt--
 		}
 
-		towerDef, ok := defs.TowerDefs[towerToRemove.DefID]
-		if !ok {
+		if _, ok := defs.TowerDefs[towerToRemove.DefID]; !ok {
 			return false
 		}
 
-		// Get neighbors before deleting the entity
-		neighbors := g.findPotentialNeighbors(towerToRemove.Hex, towerDef.Type)
-
-		// Delete the entity and its direct connections
+		// Delete the entity; the energy graph reconnects its former neighbors
 		g.deleteTowerEntity(towerIDToRemove)
-
-		// Now, handle the network update for the neighbors
-		g.handleTowerRemoval(neighbors)
+		g.syncEnergyLines()
 		g.AuraSystem.RecalculateAuras()
 
-		if tile, exists := g.HexMap.Tiles[hex]; exists {
-			tile.Passable = true
-			g.HexMap.Tiles[hex] = tile
-		}
+		g.setPassable(hex, true)
 
 		g.EventDispatcher.Dispatch(event.Event{Type: event.TowerRemoved, Data: hex})
 		g.UpdateFuturePath() // Обновляем путь
@@ -108,20 +100,13 @@ func (g *Game) canPlaceTower(hex hexmap.Hex) bool {
 		return false
 	}
 
+	// Гекс под башней или стеной непроходим, поэтому Passable заодно проверяет,
+	// что он свободен
 	tile, exists := g.HexMap.Tiles[hex]
 	if !exists || !tile.Passable || !tile.CanPlaceTower {
 		return false
 	}
 
-	for id, pos := range g.ECS.Positions {
-		if _, hasTower := g.ECS.Towers[id]; hasTower {
-			px, py := utils.HexToScreen(hex)
-			if pos.X == px && pos.Y == py {
-				return false
-			}
-		}
-	}
-
 	if g.isPathBlockedBy(hex) {
 		return false
 	}
@@ -129,26 +114,9 @@ func (g *Game) canPlaceTower(hex hexmap.Hex) bool {
 	return true
 }
 
-func (g *Game) isPathBlockedBy(hex hexmap.Hex) bool {
-	originalTile := g.HexMap.Tiles[hex]
-	g.HexMap.Tiles[hex] = hexmap.Tile{Passable: false, CanPlaceTower: originalTile.CanPlaceTower}
-	defer func() {
-		g.HexMap.Tiles[hex] = originalTile
-	}()
-
-	current := g.HexMap.Entry
-	for _, cp := range g.HexMap.Checkpoints {
-		if path := hexmap.AStar(current, cp, g.HexMap); path == nil {
-			return true
-		}
-		current = cp
-	}
-
-	if path := hexmap.AStar(current, g.HexMap.Exit, g.HexMap); path == nil {
-		return true
-	}
-
-	return false
+// towerRange возвращает дальность башни на гексе: возвышенность ее увеличивает.
+func (g *Game) towerRange(hex hexmap.Hex, baseRange int) int {
+	return baseRange + g.HexMap.TerrainAt(hex).Info().RangeBonus
 }
 
 func (g *Game) createTowerEntity(hex hexmap.Hex, towerDefID string) types.EntityID {
@@ -173,7 +141,7 @@ func (g *Game) createTowerEntity(hex hexmap.Hex, towerDefID string) types.Entity
 	if def.Combat != nil {
 		combatComponent := &component.Combat{
 			FireRate: def.Combat.FireRate,
-			Range:    def.Combat.Range,
+			Range:    g.towerRange(hex, def.Combat.Range),
 			ShotCost: def.Combat.ShotCost,
 		}
 		if def.Combat.Attack != nil {
@@ -189,6 +157,11 @@ func (g *Game) createTowerEntity(hex hexmap.Hex, towerDefID string) types.Entity
 		}
 	}
 
+	if def.Type == defs.TowerTypeBattery {
+		g.ECS.Batteries[id] = &component.Battery{}
+	}
+	g.invalidatePowerNetworks()
+
 	g.ECS.Renderables[id] = &component.Renderable{
 		Color:     def.Visuals.Color,
 		Radius:    float32(config.HexSize * def.Visuals.RadiusFactor),
@@ -203,7 +176,7 @@ func (g *Game) createTowerEntity(hex hexmap.Hex, towerDefID string) types.Entity
 			CurrentPitch:     0,
 			TargetPitch:      0,
 			TurnSpeed:        8.0, // Увеличена скорость поворота
-			AcquisitionRange: float32(def.Combat.Range) * 1.4,
+			AcquisitionRange: float32(g.towerRange(hex, def.Combat.Range)) * 1.4,
 		}
 	}
 
@@ -211,10 +184,13 @@ func (g *Game) createTowerEntity(hex hexmap.Hex, towerDefID string) types.Entity
 }
 
 func (g *Game) deleteTowerEntity(id types.EntityID) {
+	g.removeTowerFromEnergyGraph(id)
 	delete(g.ECS.Positions, id)
 	delete(g.ECS.Towers, id)
 	delete(g.ECS.Combats, id)
+	delete(g.ECS.Batteries, id)
 	delete(g.ECS.Renderables, id)
+	g.invalidatePowerNetworks()
 
 	linesToRemove := []types.EntityID{}
 	for lineID, line := range g.ECS.LineRenders {
@@ -223,7 +199,7 @@ func (g *Game) deleteTowerEntity(id types.EntityID) {
 		}
 	}
 	for _, lineID := range linesToRemove {
-		delete(g.ECS.LineRenders, lineID)
+		g.removeLine(lineID)
 	}
 }
 
@@ -263,7 +239,7 @@ func (g *Game) determineTowerID() string {
 	}
 
 	// Используем наш новый сервис для взвешенного выбора
-	return g.Rng.ChooseWeighted(lootTable.Entries)
+	return g.lootRng.ChooseWeighted(lootTable.Entries)
 }
 
 func (g *Game) createPermanentWall(hex hexmap.Hex) {
@@ -272,26 +248,17 @@ func (g *Game) createPermanentWall(hex hexmap.Hex) {
 		return // Failed to create wall
 	}
 	// Mark the tile as occupied
-	if tile, exists := g.HexMap.Tiles[hex]; exists {
-		tile.Passable = false
-		g.HexMap.Tiles[hex] = tile
-	}
+	g.setPassable(hex, false)
 }
 
 // canPlaceWall checks if a wall can be placed at a given hex.
 func (g *Game) canPlaceWall(hex hexmap.Hex) bool {
+	// An occupied hex is impassable, so Passable also means it is free
 	tile, exists := g.HexMap.Tiles[hex]
 	if !exists || !tile.Passable || !tile.CanPlaceTower {
 		return false
 	}
 
-	// Check if any other entity is already there
-	for _, tower := range g.ECS.Towers {
-		if tower.Hex == hex {
-			return false
-		}
-	}
-
 	// Most importantly, check if it blocks the path for creeps
 	if g.isPathBlockedBy(hex) {
 		return false
@@ -0,0 +1,27 @@
+package component
+
+// AbilityState — текущее состояние одной способности врага.
+type AbilityState struct {
+	ID         string  // ID из ability_definitions.json
+	Cooldown   float64 // Время до следующего срабатывания активной способности
+	Duration   float64 // Сколько еще длится эффект (рывок)
+	Stacks     int     // Заряды отражения или стаки реактивной брони
+	StackTimer float64 // Время до сброса стаков реактивной брони
+	Amount     float64 // Величина, заданная волной (гексы блинка); 0 — amount из определения
+	Interval   float64 // Перезарядка, заданная волной; 0 — cooldown из определения
+}
+
+// EnemyAbilities хранит способности врага, полученные от волны.
+type EnemyAbilities struct {
+	Abilities []AbilityState
+}
+
+// Get возвращает состояние способности с указанным ID или nil, если ее нет.
+func (a *EnemyAbilities) Get(id string) *AbilityState {
+	for i := range a.Abilities {
+		if a.Abilities[i].ID == id {
+			return &a.Abilities[i]
+		}
+	}
+	return nil
+}
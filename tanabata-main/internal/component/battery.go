@@ -0,0 +1,16 @@
+package component
+
+// BatteryMode — режим работы батареи.
+type BatteryMode int
+
+const (
+	BatteryCharging    BatteryMode = iota // Копит руду из сети
+	BatteryDischarging                    // Отдает накопленную руду как источник энергии
+)
+
+// Battery хранит накопленную батареей руду.
+type Battery struct {
+	Mode      BatteryMode
+	Stored    float64 // Накопленная руда
+	TickTimer float64 // Время до следующего тика зарядки
+}
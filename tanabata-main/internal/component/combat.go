@@ -14,5 +14,6 @@ type Combat struct {
 	FireCooldown float64
 	Range        int
 	ShotCost     float64 // Стоимость одного выстрела в единицах руды
+	IdleTime     float64 // Сколько секунд башне не в кого стрелять; простаивающая башня не входит в спрос сети
 	Attack       defs.AttackDef
 }
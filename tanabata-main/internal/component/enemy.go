@@ -8,6 +8,13 @@ type Enemy struct {
 	PhysicalArmor       int
 	MagicalArmor        int
 	Damage              int     // Урон, который нанесет враг
-	LastCheckpointIndex int     // Индекс последнего пройденного чекпоинта
+	LastCheckpointIndex int     // Индекс последнего пройденного чекпоинта маршрута своего входа
+	Gate                int     // Вход, из которого вышел враг (HexMap.Gates)
 	ReachedEnd          bool    // Достиг ли враг конца пути
-}
\ No newline at end of file
+	MaxHealth           int     // Здоровье при появлении; выше него регенерация не лечит
+	Regen               float64 // Восстановление здоровья в секунду (из волны)
+	RegenAccumulator    float64 // Накопленная дробная часть регенерации
+	BonusRegen          float64 // Реген от чужих способностей (аура лечения), пересчитывается каждый кадр
+	EvasionChance       float64 // Шанс увернуться от снаряда (0..1)
+	Flying              bool    // Летит над лабиринтом; не получает урона от руды и линий
+}
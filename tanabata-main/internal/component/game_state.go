@@ -11,6 +11,7 @@ const (
 
 // GameState — компонент для хранения состояния игры
 type GameState struct {
-	Phase        GamePhase
-	TowersToKeep int
+	Phase         GamePhase
+	TowersToKeep  int
+	OreDrawPolicy OreDrawPolicy // Политика выбора источника руды для башен с OreDrawInherit
 }
@@ -15,4 +15,16 @@ type Ore struct {
 	Radius         float32    // Радиус кружка
 	Color          color.RGBA // Цвет руды
 	PulseRate      float64    // Частота пульсации
-}
\ No newline at end of file
+	Vein           int        // Индекс жилы (см. defs.OreVeinDefs)
+	Type           string     // Тип руды (см. defs.OreTypeDefs)
+	Depleted       bool       // Запас иссяк; гекс остается на карте, пока жила не восстановится
+}
+
+// OreWarningLevel — прогноз истощения жилы для индикатора.
+type OreWarningLevel int
+
+const (
+	OreWarningNone     OreWarningLevel = iota // Руды хватит надолго
+	OreWarningSoon                            // Жила иссякнет через несколько волн
+	OreWarningCritical                        // Жила иссякнет в ближайшие секунды волны
+)
\ No newline at end of file
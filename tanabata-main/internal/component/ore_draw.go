@@ -0,0 +1,36 @@
+package component
+
+// OreDrawPolicy — правило, по которому потребитель энергосети выбирает,
+// из какого источника списать руду за выстрел или тик.
+type OreDrawPolicy int
+
+const (
+	OreDrawInherit      OreDrawPolicy = iota // Башня использует политику игры
+	OreDrawRandom                            // Случайный источник (поведение по умолчанию)
+	OreDrawNearest                           // Ближайший источник по числу переходов по линиям
+	OreDrawLargest                           // Источник с наибольшим запасом
+	OreDrawProportional                      // Случайно, с весом пропорционально запасу
+	OreDrawRoundRobin                        // Источники по очереди
+)
+
+// OreDrawPolicyCount — число политик, включая OreDrawInherit. Нужен для циклического переключения.
+const OreDrawPolicyCount = 6
+
+// String возвращает подпись политики для UI.
+func (p OreDrawPolicy) String() string {
+	switch p {
+	case OreDrawInherit:
+		return "Как в игре"
+	case OreDrawRandom:
+		return "Случайно"
+	case OreDrawNearest:
+		return "Ближайший"
+	case OreDrawLargest:
+		return "Крупнейший"
+	case OreDrawProportional:
+		return "По запасу"
+	case OreDrawRoundRobin:
+		return "По кругу"
+	}
+	return "?"
+}
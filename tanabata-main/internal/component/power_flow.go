@@ -0,0 +1,12 @@
+package component
+
+// PowerFlow — сводка пропускной способности энергосети для башни.
+// Пересчитывается каждый кадр (см. app/power_flow.go), в сохранение попадает как есть.
+// Нулевое значение (Computed == false) означает, что баланс не считался: башня
+// вне энергосети, и ограничение темпа к ней не применяется.
+type PowerFlow struct {
+	Computed bool    // Баланс посчитан для сети башни; тогда Throttle == 0 — сеть ничего не дает
+	Supply   float64 // Руда в секунду, которую могут выдать источники сети башни
+	Demand   float64 // Руда в секунду, которую хотят потребители сети башни
+	Throttle float64 // Доля потребности этой башни, которую сеть может покрыть (0..1)
+}
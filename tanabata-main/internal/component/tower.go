@@ -4,14 +4,18 @@ package component
 import "go-tower-defense/pkg/hexmap"
 
 type Tower struct {
-	DefID              string     // ID из towers.json
-	Level              int        // Уровень башни
-	CraftingLevel      int        // Уровень крафта (0 - базо��ая, 1 - крафт 1-го уровня и т.д.)
-	Range              int        // Радиус действия
-	Hex                hexmap.Hex // Гекс, на котором стоит башня
-	IsActive           bool       // Активна ли башня (стреляет или просто стена)
-	IsTemporary        bool       // Временная ли башня (для механики выбора)
-	IsSelected         bool       // Выбрана ли башня для СОХРАНЕНИЯ после фазы выбора
-	IsManuallySelected bool       // Выбрана ли башня вручную в группу (для крафта)
-	IsHighlighted      bool       // Подсвечена ли башня в данный момент (для UI)
+	DefID              string        // ID из towers.json
+	Level              int           // Уровень башни
+	CraftingLevel      int           // Уровень крафта (0 - базо��ая, 1 - крафт 1-го уровня и т.д.)
+	Range              int           // Радиус действия
+	Hex                hexmap.Hex    // Гекс, на котором стоит башня
+	IsActive           bool          // Активна ли башня (стреляет или просто стена)
+	IsTemporary        bool          // Временная ли башня (для механики выбора)
+	IsSelected         bool          // Выбрана ли башня для СОХРАНЕНИЯ после фазы выбора
+	IsManuallySelected bool          // Выбрана ли башня вручную в группу (для крафта)
+	IsHighlighted      bool          // Подсвечена ли башня в данный момент (для UI)
+	IsManuallyDisabled bool          // Выключена ли башня игроком (остается в сети, но не стреляет и не добывает)
+	OreDrawPolicy      OreDrawPolicy // Политика выбора источника руды; OreDrawInherit — как в игре
+	OreDrawCursor      int           // Позиция очереди для OreDrawRoundRobin
+	Flow               PowerFlow     // Баланс добычи и потребления сети, в которой стоит башня
 }
@@ -3,13 +3,28 @@ package component
 
 import "go-tower-defense/pkg/hexmap"
 
+// SpawnGroup — состояние одной группы врагов внутри идущей волны.
+type SpawnGroup struct {
+	EnemyID   string       // ID врага из enemies.json
+	Remaining int          // Сколько врагов группы осталось спавнить
+	Interval  float64      // Интервал между спавнами (в секундах)
+	Timer     float64      // Таймер спавна; начинается с -delay, враг появляется при Timer >= Interval
+	Routes    []SpawnRoute // Входы группы; враги идут по ним по очереди
+	Spawned   int          // Сколько врагов группы уже заспавнено
+	Abilities []string     // Способности группы сверх способностей волны
+	Evasion   float64      // Шанс уклонения группы; 0 — шанс волны
+}
+
+// SpawnRoute — вход, через который спавнится группа, и путь от него до выхода.
+type SpawnRoute struct {
+	Gate int          // Индекс входа (HexMap.Gates)
+	Path []hexmap.Hex // Путь от входа через его чекпоинты до выхода
+}
+
 // Wave — компонент для волны врагов
 type Wave struct {
 	Number         int          // Номер волны
-	EnemiesToSpawn int          // Сколько врагов осталось спавнить
-	SpawnTimer     float64      // Таймер спавна
-	SpawnInterval  float64      // Интервал между спавнами (в секундах)
-	CurrentPath    []hexmap.Hex // Текущий путь для врагов
-	EnemyID        string       // ID врага для этой волны
+	EnemiesToSpawn int          // Сколько врагов осталось спавнить во всех группах
+	Groups         []SpawnGroup // Группы в порядке из waves.json
 	DamagePerEnemy []int        // Урон для каждого врага в волне
 }
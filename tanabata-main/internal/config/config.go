@@ -3,8 +3,6 @@ package config
 
 import (
 	"image/color"
-
-	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 const (
@@ -17,7 +15,9 @@ const (
 	// CoordScale масштабирует мировые координаты для рендеринга
 	CoordScale = 0.25
 	// MaxDeltaTime максимальное время кадра для предотвращения спирали смерти
-	MaxDeltaTime    = 0.05
+	MaxDeltaTime = 0.05
+	// SimTimestep фиксированный шаг симуляции; запись и воспроизведение реплеев требуют одинакового шага
+	SimTimestep     = 1.0 / 60.0
 	GridWidth       = 40 // Из нового (новая константа)
 	GridHeight      = 30 // Из нового (новая константа)
 	TowerBuildLimit = 2  // Из нового (аналог MaxTowersInBuildPhase)
@@ -43,10 +43,12 @@ const (
 	SpeedButtonOffsetX      = 80
 	SpeedButtonY            = 30
 	SpeedButtonSize         = 18.0
+	SpeedLevelCount         = 3 // Количество уровней ускорения игры (x1, x2, x4)
 	EnergyTransferRadius    = 3
-	OrePerHexMin            = 15
-	OrePerHexMax            = 75
+	LineMaxFlow             = 0.5 // Сколько руды в секунду может пропустить одна линия энергосети
+	FlowIdleGrace           = 1.0 // Сколько секунд без целей башня еще считается в спросе сети
 	LineHeight              = 5.0 // Высота линии энергии
+	FlyingEnemyHeight       = 10.0 // Высота полета летающих врагов над землей (в единицах рендера)
 
 	// Новые константы для управления камерой
 	CameraZoomStep    = 5.0   // Шаг приближения/отдаления
@@ -79,12 +81,14 @@ var (
 )
 
 // --- Новые цвета и константы для Raylib ---
+// rl.Color является псевдонимом color.RGBA, поэтому пакет config не зависит от raylib
+// и может использоваться в безголовой симуляции.
 var (
 	// Основная палитра UI (приглушенные цвета)
-	UIColorBlue   = rl.NewColor(44, 85, 119, 255)   // Приглушенный синий
-	UIColorRed    = rl.NewColor(169, 68, 66, 255)   // Приглушенный красный
-	UIColorYellow = rl.NewColor(204, 146, 67, 255)  // Приглушенный желтый/оранжевый
-	UIBorderColor = rl.NewColor(220, 220, 220, 220) // Слегка прозрачный белый
+	UIColorBlue   = color.RGBA{R: 44, G: 85, B: 119, A: 255}   // Приглушенный синий
+	UIColorRed    = color.RGBA{R: 169, G: 68, B: 66, A: 255}   // Приглушенный красный
+	UIColorYellow = color.RGBA{R: 204, G: 146, B: 67, A: 255}  // Приглушенный желтый/оранжевый
+	UIBorderColor = color.RGBA{R: 220, G: 220, B: 220, A: 220} // Слегка прозрачный белый
 
 	// Цвета состояний из основной палитры
 	BuildStateColor         = UIColorBlue
@@ -96,78 +100,86 @@ var (
 	UIndicatorInactiveColor = UIColorBlue
 
 	// Цвета для кнопки скорости
-	SpeedButtonColorsRL = []rl.Color{
+	SpeedButtonColorsRL = []color.RGBA{
 		UIColorBlue,
 		UIColorRed,
 		UIColorYellow,
 	}
 
 	// Остальные цвета UI
-	InfoPanelBgColorRL             = rl.NewColor(40, 40, 40, 230)
-	InfoPanelBorderColorRL         = rl.Gray
-	WaveIndicatorColorRL           = rl.LightGray
-	XpBarBackgroundColorRL         = rl.DarkGray
-	XpBarForegroundColorRL         = rl.NewColor(77, 144, 77, 255) // Приглушенный зеленый
-	XpBarBorderColorRL             = rl.Gray
-	PlayerLevelTextColorRL         = rl.White
-	PlayerXpTextColorRL            = rl.White
-	RecipeBookBackgroundColorRL    = rl.NewColor(20, 20, 30, 245)
-	RecipeBookBorderColorRL        = rl.Gray
-	RecipeTitleColorRL             = rl.NewColor(255, 215, 0, 220) // Слегка приглушенное золото
-	RecipeDefaultColorRL           = rl.Gray
-	RecipeCanCraftColorRL          = rl.LightGray
+	InfoPanelBgColorRL             = color.RGBA{R: 40, G: 40, B: 40, A: 230}
+	InfoPanelBorderColorRL         = color.RGBA{R: 130, G: 130, B: 130, A: 255}
+	WaveIndicatorColorRL           = color.RGBA{R: 200, G: 200, B: 200, A: 255}
+	XpBarBackgroundColorRL         = color.RGBA{R: 80, G: 80, B: 80, A: 255}
+	XpBarForegroundColorRL         = color.RGBA{R: 77, G: 144, B: 77, A: 255} // Приглушенный зеленый
+	XpBarBorderColorRL             = color.RGBA{R: 130, G: 130, B: 130, A: 255}
+	PlayerLevelTextColorRL         = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	PlayerXpTextColorRL            = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	RecipeBookBackgroundColorRL    = color.RGBA{R: 20, G: 20, B: 30, A: 245}
+	RecipeBookBorderColorRL        = color.RGBA{R: 130, G: 130, B: 130, A: 255}
+	RecipeTitleColorRL             = color.RGBA{R: 255, G: 215, B: 0, A: 220} // Слегка приглушенное золото
+	RecipeDefaultColorRL           = color.RGBA{R: 130, G: 130, B: 130, A: 255}
+	RecipeCanCraftColorRL          = color.RGBA{R: 200, G: 200, B: 200, A: 255}
 	CombineButtonColorRL           = UIColorBlue
-	SelectButtonColorRL            = rl.NewColor(77, 144, 77, 255)
+	SelectButtonColorRL            = color.RGBA{R: 77, G: 144, B: 77, A: 255}
 	SelectButtonActiveColorRL      = UIColorYellow
-	UIndicatorStrikethroughColorRL = rl.NewColor(255, 255, 255, 150)
+	UIndicatorStrikethroughColorRL = color.RGBA{R: 255, G: 255, B: 255, A: 150}
 
 	// Цвета для нового индикатора руды
-	OreIndicatorFullColor     = UIColorBlue // Насыщенный синий
-	OreIndicatorEmptyColor    = rl.NewColor(0, 0, 0, 0)        // Полностью прозрачный
-	OreIndicatorWarningColor  = rl.NewColor(217, 83, 79, 220)  // Насыщенный красный
-	OreIndicatorCriticalColor = rl.NewColor(240, 173, 78, 220) // Насыщенный желтый/оранжевый
-	OreIndicatorDepletedColor = rl.NewColor(10, 10, 10, 220)   // Очень темный серый (почти черный)
+	OreIndicatorFullColor     = UIColorBlue                               // Насыщенный синий
+	OreIndicatorEmptyColor    = color.RGBA{R: 0, G: 0, B: 0, A: 0}        // Полностью прозрачный
+	OreIndicatorWarningColor  = color.RGBA{R: 217, G: 83, B: 79, A: 220}  // Насыщенный красный
+	OreIndicatorCriticalColor = color.RGBA{R: 240, G: 173, B: 78, A: 220} // Насыщенный желтый/оранжевый
+	OreIndicatorDepletedColor = color.RGBA{R: 10, G: 10, B: 10, A: 220}   // Очень темный серый (почти черный)
+	OreIndicatorRefillColor   = color.RGBA{R: 70, G: 110, B: 200, A: 140} // Бледно-синий: руда, которая вернется к следующей фазе строительства
+	OreIndicatorAbsentColor   = color.RGBA{R: 130, G: 130, B: 130, A: 70} // Блеклый серый: контур жилы, которой нет на уровне
 
 	// Цвета для нового индикатора здоровья
-	HealthIndicatorFullColor     = UIColorBlue  // Приглушенный синий
-	HealthIndicatorWarningColor  = rl.NewColor(240, 173, 78, 220) // Насыщенный желтый/оранжевый
-	HealthIndicatorCriticalColor = rl.NewColor(217, 83, 79, 220)  // Насыщенный красный
-	HealthIndicatorEmptyColor    = rl.NewColor(0, 0, 0, 0)        // Полностью прозрачный
+	HealthIndicatorFullColor     = UIColorBlue                               // Приглушенный синий
+	HealthIndicatorWarningColor  = color.RGBA{R: 240, G: 173, B: 78, A: 220} // Насыщенный желтый/оранжевый
+	HealthIndicatorCriticalColor = color.RGBA{R: 217, G: 83, B: 79, A: 220}  // Насыщенный красный
+	HealthIndicatorEmptyColor    = color.RGBA{R: 0, G: 0, B: 0, A: 0}        // Полностью прозрачный
 
 	// --- Игровые цвета (НЕ ТРОГАТЬ) ---
-	BackgroundColorRL       = rl.NewColor(30, 30, 30, 255)
-	GridColorRL             = rl.NewColor(50, 50, 50, 255)
-	HighlightColorRL        = rl.NewColor(255, 255, 0, 100)
-	TextLightColorRL        = rl.White
-	TextDarkColorRL         = rl.Black
-	CheckpointColorRL       = rl.NewColor(255, 215, 0, 255)
-	OreHexBackgroundColorRL = rl.NewColor(45, 45, 45, 255)
-	StrokeColorRL           = rl.NewColor(100, 100, 100, 255)
+	BackgroundColorRL       = color.RGBA{R: 30, G: 30, B: 30, A: 255}
+	GridColorRL             = color.RGBA{R: 50, G: 50, B: 50, A: 255}
+	HighlightColorRL        = color.RGBA{R: 255, G: 255, B: 0, A: 100}
+	TextLightColorRL        = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	TextDarkColorRL         = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	CheckpointColorRL       = color.RGBA{R: 255, G: 215, B: 0, A: 255}
+	OreHexBackgroundColorRL = color.RGBA{R: 45, G: 45, B: 45, A: 255}
+	StrokeColorRL           = color.RGBA{R: 100, G: 100, B: 100, A: 255}
 
 	// Цвета снарядов
-	ProjectileColorPhysicalRL = rl.NewColor(255, 100, 0, 255)
-	ProjectileColorMagicalRL  = rl.NewColor(220, 50, 220, 255)
-	ProjectileColorPureRL     = rl.NewColor(180, 240, 255, 255)
-	ProjectileColorSlowRL     = rl.NewColor(173, 216, 230, 255)
-	ProjectileColorPoisonRL   = rl.NewColor(124, 252, 0, 255)
+	ProjectileColorPhysicalRL = color.RGBA{R: 255, G: 100, B: 0, A: 255}
+	ProjectileColorMagicalRL  = color.RGBA{R: 220, G: 50, B: 220, A: 255}
+	ProjectileColorPureRL     = color.RGBA{R: 180, G: 240, B: 255, A: 255}
+	ProjectileColorSlowRL     = color.RGBA{R: 173, G: 216, B: 230, A: 255}
+	ProjectileColorPoisonRL   = color.RGBA{R: 124, G: 252, B: 0, A: 255}
 
 	// Цвета сущностей
-	OreColorRL         = rl.NewColor(70, 130, 180, 128)
-	EnemyDamageColorRL = rl.White
-	TowerWireColorRL   = rl.NewColor(80, 80, 80, 255)
+	OreColorRL         = color.RGBA{R: 70, G: 130, B: 180, A: 128}
+	EnemyDamageColorRL = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	TowerWireColorRL   = color.RGBA{R: 80, G: 80, B: 80, A: 255}
 
 	// Старые цвета (адаптированные)
-	PassableColorRL     = rl.NewColor(70, 100, 120, 220)
-	ImpassableColorRL   = rl.NewColor(150, 70, 70, 220)
-	EntryColorRL        = rl.NewColor(0, 255, 0, 255)
-	ExitColorRL         = rl.NewColor(255, 0, 0, 255)
-	IndicatorStrokeRL   = rl.NewColor(240, 240, 240, 255)
-	BaseColorRL         = rl.NewColor(50, 205, 50, 255)
-	EnemyColorRL        = rl.NewColor(0, 0, 0, 255)
-	TowerStrokeColorRL  = rl.NewColor(255, 255, 255, 255)
-	TowerAStrokeColorRL = rl.NewColor(255, 80, 80, 255)
-	TowerBStrokeColorRL = rl.NewColor(255, 255, 0, 255)
-	LineColorRL         = rl.NewColor(255, 195, 0, 150)
+	PassableColorRL     = color.RGBA{R: 70, G: 100, B: 120, A: 220}
+	ImpassableColorRL   = color.RGBA{R: 150, G: 70, B: 70, A: 220}
+	EntryColorRL        = color.RGBA{R: 0, G: 255, B: 0, A: 255}
+	ExitColorRL         = color.RGBA{R: 255, G: 0, B: 0, A: 255}
+	IndicatorStrokeRL   = color.RGBA{R: 240, G: 240, B: 240, A: 255}
+	BaseColorRL         = color.RGBA{R: 50, G: 205, B: 50, A: 255}
+	EnemyColorRL        = color.RGBA{R: 0, G: 0, B: 0, A: 255}
+	TowerStrokeColorRL  = color.RGBA{R: 255, G: 255, B: 255, A: 255}
+	TowerAStrokeColorRL = color.RGBA{R: 255, G: 80, B: 80, A: 255}
+	TowerBStrokeColorRL = color.RGBA{R: 255, G: 255, B: 0, A: 255}
+	LineColorRL         = color.RGBA{R: 255, G: 195, B: 0, A: 150}
+
+	// Цвета местности (hexmap.Terrain)
+	TerrainMudColorRL        = color.RGBA{R: 95, G: 75, B: 50, A: 220}
+	TerrainRoadColorRL       = color.RGBA{R: 120, G: 115, B: 100, A: 220}
+	TerrainWaterColorRL      = color.RGBA{R: 40, G: 80, B: 160, A: 220}
+	TerrainHighGroundColorRL = color.RGBA{R: 90, G: 130, B: 90, A: 220}
 )
 
 const (
@@ -201,7 +213,9 @@ const (
 	LineDamageTicksPerSecond = 5.0  // Из нового; в старом 8.0
 
 	// Параметры руды (из нового + добавлено из старого)
-	OreDepletionThreshold = 0.1 // Из нового и старого
+	OreDepletionThreshold    = 0.1  // Из нового и старого
+	OreDryWarningSeconds     = 10.0 // Мигать рамкой жилы, если она иссякнет быстрее, чем за столько секунд волны
+	OreDepletionWarningWaves = 2.0  // Предупреждать, если жила иссякнет за столько волн
 
 	// Параметры текста (из нового + добавлено из старого)
 	TextCharWidth = 10.0 // Из нового; в старом 7
@@ -219,4 +233,4 @@ func CalculateXPForNextLevel(level int) int {
 	// Формула из нового: 100 для первого уровня, +50 за каждый последующий
 	// (Если нужно старую: return 75 + (level * 25))
 	return 100 + (level-1)*50
-}
\ No newline at end of file
+}
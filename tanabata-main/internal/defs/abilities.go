@@ -0,0 +1,39 @@
+package defs
+
+// Идентификаторы способностей врагов, которые поддерживает EnemyAbilitySystem.
+const (
+	AbilityEvasion        = "evasion"
+	AbilityHealerAura     = "healer_aura"
+	AbilityBlink          = "blink"
+	AbilityReflection     = "reflection"
+	AbilityBKB            = "bkb"
+	AbilityEffectImmunity = "effect_immunity"
+	AbilityReactiveArmor  = "reactive_armor"
+	AbilityRush           = "rush"
+)
+
+// AbilityType — пассивная способность действует постоянно, активная срабатывает по кулдауну.
+type AbilityType string
+
+const (
+	AbilityPassive AbilityType = "passive"
+	AbilityActive  AbilityType = "active"
+)
+
+// AbilityDefinition описывает способность врага из ability_definitions.json.
+// Смысл Amount зависит от способности: шанс уклонения, бонус регена ауры,
+// дальность блинка в гексах, множитель скорости рывка или броня за стак.
+type AbilityDefinition struct {
+	ID            string      `json:"id"`
+	Name          string      `json:"name"`
+	Type          AbilityType `json:"type"`
+	Cooldown      float64     `json:"cooldown,omitempty"`       // Перезарядка активной способности (в секундах)
+	StartCooldown float64     `json:"start_cooldown,omitempty"` // Задержка до первого срабатывания после появления
+	Duration      float64     `json:"duration,omitempty"`       // Длительность эффекта или жизни стака
+	Radius        int         `json:"radius,omitempty"`         // Радиус ауры в гексах
+	Amount        float64     `json:"amount,omitempty"`
+	MaxStacks     int         `json:"max_stacks,omitempty"`
+}
+
+// AbilityDefs — библиотека способностей врагов, ключ — ID способности.
+var AbilityDefs map[string]AbilityDefinition
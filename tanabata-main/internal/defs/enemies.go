@@ -3,14 +3,16 @@ package defs
 
 // EnemyDefinition holds all the static data for a specific type of enemy.
 type EnemyDefinition struct {
-	ID            string  `json:"id"`
-	Name          string  `json:"name"`
-	Health        int     `json:"health"`
-	Speed         float64 `json:"speed"`
-	PhysicalArmor int     `json:"physical_armor"`
-	MagicalArmor  int     `json:"magical_armor"`
-	Damage        int     `json:"damage"`
-	Visuals       Visuals `json:"visuals"`
+	ID            string   `json:"id"`
+	Name          string   `json:"name"`
+	Health        int      `json:"health"`
+	Speed         float64  `json:"speed"`
+	PhysicalArmor int      `json:"physical_armor"`
+	MagicalArmor  int      `json:"magical_armor"`
+	Damage        int      `json:"damage"`
+	Flying        bool     `json:"flying,omitempty"`    // Flies over walls in a straight line between checkpoints
+	Abilities     []string `json:"abilities,omitempty"` // Abilities every enemy of this kind has, on top of the wave's
+	Visuals       Visuals  `json:"visuals"`
 }
 
 // EnemyDefs is the library of all enemy definitions, mapped by their ID.
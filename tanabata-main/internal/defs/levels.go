@@ -0,0 +1,85 @@
+// internal/defs/levels.go
+package defs
+
+// DefaultLevelID — уровень, с которым игра запускается, если другой не выбран.
+const DefaultLevelID = "standard"
+
+// FloatRange — диапазон значений из данных уровня. Случайное значение берется
+// равномерно из [Min, Max].
+type FloatRange struct {
+	Min float64 `json:"min"`
+	Max float64 `json:"max"`
+}
+
+// OreVeinShape определяет, какие гексы занимает жила и как по ним делится мощность.
+type OreVeinShape string
+
+const (
+	// OreShapeCluster — центр и случайные соседи; мощность жилы делится между гексами.
+	OreShapeCluster OreVeinShape = "cluster"
+	// OreShapeCircles — гексы в радиусе от центра; мощность раскладывается случайными кругами.
+	OreShapeCircles OreVeinShape = "circles"
+)
+
+// OreCenterPlacement определяет, как выбирается центр жилы внутри кольца.
+type OreCenterPlacement string
+
+const (
+	// OrePlacementRandom — случайный гекс кольца.
+	OrePlacementRandom OreCenterPlacement = "random"
+	// OrePlacementFarthest — гекс кольца, наиболее удаленный от уже выбранных центров.
+	OrePlacementFarthest OreCenterPlacement = "farthest"
+)
+
+// OreVeinProfile — правила генерации одной жилы.
+type OreVeinProfile struct {
+	MinDistance int                `json:"min_distance"`    // Кольцо вокруг центра карты, в котором ищется центр жилы
+	MaxDistance int                `json:"max_distance"`    // 0 — до края карты
+	Placement   OreCenterPlacement `json:"placement"`       // Выбор центра в кольце
+	Shape       OreVeinShape       `json:"shape"`           // Форма жилы
+	Size        int                `json:"size"`            // cluster: число гексов (не больше 7); circles: радиус области
+	Share       *FloatRange        `json:"share,omitempty"` // Доля общей мощности карты; nil — остаток после остальных жил
+}
+
+// OreGenerationProfile — экономика руды уровня: сколько жил, где они лежат и сколько в них руды.
+type OreGenerationProfile struct {
+	VeinCount     int              `json:"vein_count"`
+	TotalPower    FloatRange       `json:"total_power"`     // Общая мощность карты в процентах
+	MinSeparation int              `json:"min_separation"`  // Минимальное расстояние между центрами жил
+	ReservePerHex FloatRange       `json:"reserve_per_hex"` // Пределы запаса одного гекса; 0 — без предела
+	Veins         []OreVeinProfile `json:"veins"`           // Правила жил по порядку; если их меньше VeinCount, повторяется последнее
+}
+
+// Vein возвращает правила жилы с индексом i.
+func (p OreGenerationProfile) Vein(i int) OreVeinProfile {
+	if i < len(p.Veins) {
+		return p.Veins[i]
+	}
+	return p.Veins[len(p.Veins)-1]
+}
+
+// TerrainGenerationProfile — местность сгенерированной карты уровня: сколько пятен
+// каждого типа разбросать и нужны ли дороги. Пустой профиль оставляет обычную землю.
+type TerrainGenerationProfile struct {
+	Mud         int  `json:"mud,omitempty"`          // Пятна грязи
+	Water       int  `json:"water,omitempty"`        // Озера; озеро, перекрывающее маршрут, не кладется
+	HighGround  int  `json:"high_ground,omitempty"`  // Возвышенности
+	PatchRadius int  `json:"patch_radius,omitempty"` // Радиус пятна вокруг его центра
+	Roads       bool `json:"roads,omitempty"`        // Дорога вдоль исходного маршрута каждого входа
+}
+
+// Empty сообщает, что профиль не добавляет местности.
+func (p TerrainGenerationProfile) Empty() bool {
+	return p.Mud == 0 && p.Water == 0 && p.HighGround == 0 && !p.Roads
+}
+
+// LevelDefinition описывает уровень из levels.json.
+type LevelDefinition struct {
+	ID      string                   `json:"id"`
+	Name    string                   `json:"name"`
+	Ore     OreGenerationProfile     `json:"ore"`
+	Terrain TerrainGenerationProfile `json:"terrain,omitempty"`
+}
+
+// LevelDefs — определения уровней, ключ — ID уровня.
+var LevelDefs map[string]LevelDefinition
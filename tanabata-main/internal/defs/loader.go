@@ -22,14 +22,26 @@ func LoadAll(dataDir string) error {
 	if err := LoadLootTables(filepath.Join(dataDir, "loot_tables.json")); err != nil {
 		return fmt.Errorf("failed to load loot tables: %w", err)
 	}
-	// Загрузка волн может быть добавлена сюда же, если потребуется
-	// if err := LoadWaves(filepath.Join(dataDir, "waves.json")); err != nil {
-	// 	return fmt.Errorf("failed to load waves: %w", err)
-	// }
+	// Жилы ссылаются на типы руды, поэтому типы грузятся первыми
+	if err := LoadOreTypes(filepath.Join(dataDir, "ore_types.json")); err != nil {
+		return fmt.Errorf("failed to load ore types: %w", err)
+	}
+	if err := LoadOreVeins(filepath.Join(dataDir, "ore_veins.json")); err != nil {
+		return fmt.Errorf("failed to load ore veins: %w", err)
+	}
+	if err := LoadLevels(filepath.Join(dataDir, "levels.json")); err != nil {
+		return fmt.Errorf("failed to load levels: %w", err)
+	}
+	if err := LoadAbilities(filepath.Join(dataDir, "ability_definitions.json")); err != nil {
+		return fmt.Errorf("failed to load ability definitions: %w", err)
+	}
+	// Волны ссылаются на врагов и способности, поэтому грузятся после них
+	if err := LoadWaves(filepath.Join(dataDir, "waves.json")); err != nil {
+		return fmt.Errorf("failed to load waves: %w", err)
+	}
 	return nil
 }
 
-
 // LoadTowerDefinitions загружает определения башен из JSON-файла.
 func LoadTowerDefinitions(filename string) error {
 	file, err := os.ReadFile(filename)
@@ -44,6 +56,11 @@ func LoadTowerDefinitions(filename string) error {
 
 	TowerDefs = make(map[string]TowerDefinition)
 	for _, tower := range towers {
+		switch tower.Targets {
+		case "", TargetAll, TargetGround, TargetAir:
+		default:
+			return fmt.Errorf("tower %q: unknown targets %q", tower.ID, tower.Targets)
+		}
 		TowerDefs[tower.ID] = tower
 	}
 	return nil
@@ -103,4 +120,222 @@ func LoadLootTables(filename string) error {
 		LootTablesByLevel[table.PlayerLevel] = table
 	}
 	return nil
-}
\ No newline at end of file
+}
+
+// LoadOreTypes загружает типы руды из JSON-файла.
+func LoadOreTypes(filename string) error {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var oreTypes []OreTypeDefinition
+	if err := json.Unmarshal(file, &oreTypes); err != nil {
+		return err
+	}
+
+	OreTypeDefs = make(map[string]OreTypeDefinition)
+	for _, oreType := range oreTypes {
+		if _, dup := OreTypeDefs[oreType.ID]; dup {
+			return fmt.Errorf("ore type %q is defined twice", oreType.ID)
+		}
+		for attackType, m := range oreType.DamageMultipliers {
+			if m < 0 {
+				return fmt.Errorf("ore type %q: negative damage multiplier for %s", oreType.ID, attackType)
+			}
+		}
+		if oreType.LineDamageMultiplier < 0 {
+			return fmt.Errorf("ore type %q: negative line_damage_multiplier", oreType.ID)
+		}
+		OreTypeDefs[oreType.ID] = oreType
+	}
+	return nil
+}
+
+// LoadOreVeins загружает правила восстановления рудных жил из JSON-файла.
+func LoadOreVeins(filename string) error {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var veins []OreVeinDefinition
+	if err := json.Unmarshal(file, &veins); err != nil {
+		return err
+	}
+
+	OreVeinDefs = make(map[int]OreVeinDefinition)
+	for _, vein := range veins {
+		if _, dup := OreVeinDefs[vein.Vein]; dup {
+			return fmt.Errorf("vein %d is defined twice", vein.Vein)
+		}
+		if vein.Vein < 0 || vein.RegenPerWave < 0 || vein.Cap < 0 || vein.Cap > 1 {
+			return fmt.Errorf("vein %d (%s): index, regen_per_wave and cap must be non-negative, cap at most 1", vein.Vein, vein.Name)
+		}
+		if _, ok := OreTypeDefs[vein.OreType]; !ok {
+			return fmt.Errorf("vein %d (%s): unknown ore_type %q", vein.Vein, vein.Name, vein.OreType)
+		}
+		OreVeinDefs[vein.Vein] = vein
+	}
+	return nil
+}
+
+// LoadLevels загружает определения уровней из JSON-файла.
+func LoadLevels(filename string) error {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var levels []LevelDefinition
+	if err := json.Unmarshal(file, &levels); err != nil {
+		return err
+	}
+
+	LevelDefs = make(map[string]LevelDefinition)
+	for _, level := range levels {
+		if _, dup := LevelDefs[level.ID]; dup {
+			return fmt.Errorf("level %q is defined twice", level.ID)
+		}
+		if err := validateOreProfile(level.Ore); err != nil {
+			return fmt.Errorf("level %q: %w", level.ID, err)
+		}
+		if t := level.Terrain; t.Mud < 0 || t.Water < 0 || t.HighGround < 0 || t.PatchRadius < 0 {
+			return fmt.Errorf("level %q: terrain counts and patch_radius must be non-negative", level.ID)
+		}
+		LevelDefs[level.ID] = level
+	}
+	if _, ok := LevelDefs[DefaultLevelID]; !ok {
+		return fmt.Errorf("default level %q is not defined", DefaultLevelID)
+	}
+	return nil
+}
+
+// validateOreProfile проверяет профиль генерации руды уровня.
+func validateOreProfile(p OreGenerationProfile) error {
+	if p.VeinCount <= 0 || len(p.Veins) == 0 {
+		return fmt.Errorf("ore: vein_count and veins must not be empty")
+	}
+	if p.TotalPower.Min <= 0 || p.TotalPower.Max < p.TotalPower.Min {
+		return fmt.Errorf("ore: total_power must be positive with min <= max")
+	}
+	if p.ReservePerHex.Min < 0 || (p.ReservePerHex.Max > 0 && p.ReservePerHex.Max < p.ReservePerHex.Min) {
+		return fmt.Errorf("ore: reserve_per_hex must be non-negative with min <= max")
+	}
+	if p.MinSeparation < 0 {
+		return fmt.Errorf("ore: min_separation must be non-negative")
+	}
+	for i, vein := range p.Veins {
+		switch vein.Placement {
+		case OrePlacementRandom, OrePlacementFarthest:
+		default:
+			return fmt.Errorf("ore vein %d: unknown placement %q", i, vein.Placement)
+		}
+		switch {
+		case vein.Shape == OreShapeCluster && (vein.Size < 1 || vein.Size > 7):
+			return fmt.Errorf("ore vein %d: cluster size must be 1..7", i)
+		case vein.Shape == OreShapeCircles && vein.Size < 0:
+			return fmt.Errorf("ore vein %d: circles size must be non-negative", i)
+		case vein.Shape != OreShapeCluster && vein.Shape != OreShapeCircles:
+			return fmt.Errorf("ore vein %d: unknown shape %q", i, vein.Shape)
+		}
+		if vein.MinDistance < 0 || (vein.MaxDistance > 0 && vein.MaxDistance < vein.MinDistance) {
+			return fmt.Errorf("ore vein %d: invalid distance band %d..%d", i, vein.MinDistance, vein.MaxDistance)
+		}
+		if vein.Share != nil && (vein.Share.Min < 0 || vein.Share.Max < vein.Share.Min) {
+			return fmt.Errorf("ore vein %d: share must be non-negative with min <= max", i)
+		}
+	}
+	return nil
+}
+
+// LoadAbilities загружает определения способностей врагов из JSON-файла.
+func LoadAbilities(filename string) error {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var abilities []AbilityDefinition
+	if err := json.Unmarshal(file, &abilities); err != nil {
+		return err
+	}
+
+	AbilityDefs = make(map[string]AbilityDefinition)
+	for _, ability := range abilities {
+		if ability.Type != AbilityPassive && ability.Type != AbilityActive {
+			return fmt.Errorf("ability %q: unknown type %q", ability.ID, ability.Type)
+		}
+		AbilityDefs[ability.ID] = ability
+	}
+	return nil
+}
+
+// LoadWaves загружает определения волн из JSON-файла и проверяет их.
+// Волны должны идти подряд с первой и ссылаться на существующих врагов и способности.
+func LoadWaves(filename string) error {
+	file, err := os.ReadFile(filename)
+	if err != nil {
+		return err
+	}
+
+	var data struct {
+		Waves []WaveDefinition `json:"waves"`
+	}
+	if err := json.Unmarshal(file, &data); err != nil {
+		return err
+	}
+
+	waves := make(map[int]WaveDefinition, len(data.Waves))
+	for _, wave := range data.Waves {
+		if _, dup := waves[wave.WaveNumber]; dup {
+			return fmt.Errorf("wave %d is defined twice", wave.WaveNumber)
+		}
+		for _, ability := range wave.Abilities {
+			if _, ok := AbilityDefs[ability]; !ok {
+				return fmt.Errorf("wave %d: unknown ability %q", wave.WaveNumber, ability)
+			}
+		}
+		if wave.BlinkHexes < 0 || wave.BlinkCooldown < 0 || wave.BlinkStartCooldown < 0 {
+			return fmt.Errorf("wave %d: blink parameters must not be negative", wave.WaveNumber)
+		}
+		for i, group := range wave.SpawnGroups() {
+			enemy, ok := EnemyDefs[group.EnemyID]
+			if !ok {
+				return fmt.Errorf("wave %d, group %d: unknown enemy %q", wave.WaveNumber, i, group.EnemyID)
+			}
+			for _, ability := range enemy.Abilities {
+				if _, ok := AbilityDefs[ability]; !ok {
+					return fmt.Errorf("enemy %q: unknown ability %q", group.EnemyID, ability)
+				}
+			}
+			if group.Count <= 0 {
+				return fmt.Errorf("wave %d, group %d: count must be positive", wave.WaveNumber, i)
+			}
+			if group.SpawnInterval <= 0 {
+				return fmt.Errorf("wave %d, group %d: spawn_interval must be positive", wave.WaveNumber, i)
+			}
+			if group.Delay < 0 || (group.Entry != nil && *group.Entry < 0) {
+				return fmt.Errorf("wave %d, group %d: delay and entry must not be negative", wave.WaveNumber, i)
+			}
+			for _, ability := range group.Abilities {
+				if _, ok := AbilityDefs[ability]; !ok {
+					return fmt.Errorf("wave %d, group %d: unknown ability %q", wave.WaveNumber, i, ability)
+				}
+			}
+			if group.EvasionChance < 0 || group.EvasionChance > 1 {
+				return fmt.Errorf("wave %d, group %d: evasion_chance must be between 0 and 1", wave.WaveNumber, i)
+			}
+		}
+		waves[wave.WaveNumber] = wave
+	}
+	for n := 1; n <= len(waves); n++ {
+		if _, ok := waves[n]; !ok {
+			return fmt.Errorf("waves must be numbered from 1 without gaps, wave %d is missing", n)
+		}
+	}
+
+	WaveDefs = waves
+	LastWaveNumber = len(waves)
+	return nil
+}
@@ -0,0 +1,32 @@
+package defs
+
+import "image/color"
+
+// OreTypeDefinition описывает тип руды из ore_types.json и модификаторы,
+// которые получают башни, запитанные от руды этого типа.
+type OreTypeDefinition struct {
+	ID                   string                       `json:"id"`
+	Name                 string                       `json:"name"`
+	Color                color.RGBA                   `json:"color"`
+	DamageMultipliers    map[AttackDamageType]float64 `json:"damage_multipliers,omitempty"`     // Множитель урона по типу атаки башни
+	LineDamageMultiplier float64                      `json:"line_damage_multiplier,omitempty"` // Множитель урона линий сети (0 — без изменений)
+}
+
+// DamageMultiplier возвращает множитель урона для типа атаки (1, если не задан).
+func (d OreTypeDefinition) DamageMultiplier(attackType AttackDamageType) float64 {
+	if m, ok := d.DamageMultipliers[attackType]; ok {
+		return m
+	}
+	return 1
+}
+
+// LineMultiplier возвращает множитель урона линий (1, если не задан).
+func (d OreTypeDefinition) LineMultiplier() float64 {
+	if d.LineDamageMultiplier <= 0 {
+		return 1
+	}
+	return d.LineDamageMultiplier
+}
+
+// OreTypeDefs — типы руды, ключ — ID типа.
+var OreTypeDefs map[string]OreTypeDefinition
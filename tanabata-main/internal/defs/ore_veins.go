@@ -0,0 +1,24 @@
+package defs
+
+// OreVeinDefinition описывает восстановление одной рудной жилы из ore_veins.json.
+// Правила применяются в начале каждой фазы строительства к каждому гексу руды жилы.
+type OreVeinDefinition struct {
+	Vein         int     `json:"vein"` // Индекс жилы: 0 — центральная, 1 — средняя, 2 — дальняя
+	Name         string  `json:"name"`
+	OreType      string  `json:"ore_type"`            // Тип руды, которой заполняется жила (см. OreTypeDefs)
+	RegenPerWave float64 `json:"regen_per_wave"`      // Прирост за волну в долях MaxReserve гекса
+	IdleOnly     bool    `json:"idle_only,omitempty"` // Восстанавливаться, только если на жиле нет работающего добытчика
+	Cap          float64 `json:"cap,omitempty"`       // Предел восстановления в долях MaxReserve (0 — до MaxReserve)
+	Revive       bool    `json:"revive,omitempty"`    // Может ли истощенный гекс снова стать источником
+}
+
+// RegenCap возвращает предел восстановления в долях MaxReserve.
+func (d OreVeinDefinition) RegenCap() float64 {
+	if d.Cap <= 0 || d.Cap > 1 {
+		return 1
+	}
+	return d.Cap
+}
+
+// OreVeinDefs — правила восстановления жил, ключ — индекс жилы.
+var OreVeinDefs map[int]OreVeinDefinition
@@ -12,6 +12,9 @@ const (
 	TowerTypeAttack TowerType = "ATTACK"
 	TowerTypeMiner  TowerType = "MINER"
 	TowerTypeWall   TowerType = "WALL"
+
+	// TowerTypeBattery stores ore: it charges from the grid and can discharge as a power source.
+	TowerTypeBattery TowerType = "BATTERY"
 )
 
 // TowerDefinition holds all the static data for a specific type of tower.
@@ -25,6 +28,28 @@ type TowerDefinition struct {
 	Aura           *AuraDef     `json:"aura,omitempty"`
 	Energy         *EnergyStats `json:"energy,omitempty"`
 	Visuals        Visuals      `json:"visuals"`
+	Targets        TargetLayer  `json:"targets,omitempty"` // Empty means ALL
+}
+
+// TargetLayer defines which enemies a tower can attack: ground, flying or both.
+type TargetLayer string
+
+const (
+	TargetAll    TargetLayer = "ALL"
+	TargetGround TargetLayer = "GROUND"
+	TargetAir    TargetLayer = "AIR"
+)
+
+// CanTarget reports whether the tower can attack an enemy that is flying or not.
+func (d *TowerDefinition) CanTarget(flying bool) bool {
+	switch d.Targets {
+	case TargetGround:
+		return !flying
+	case TargetAir:
+		return flying
+	default:
+		return true
+	}
 }
 
 // AuraDef defines the properties of an aura tower.
@@ -76,6 +101,20 @@ type CombatStats struct {
 type EnergyStats struct {
 	TransferRadius      int     `json:"transfer_radius"`
 	LineDegradationFactor float64 `json:"line_degradation_factor"`
+	// Battery parameters
+	StorageMax float64 `json:"storage_max,omitempty"` // Maximum amount of stored ore
+	ChargeRate float64 `json:"charge_rate,omitempty"` // Ore per second drawn from the grid while charging
+	// DischargeRate is the ore per second a discharging battery can feed into the grid.
+	DischargeRate float64 `json:"discharge_rate,omitempty"`
+	// ExtractionRate is the ore per second a miner (or battery) standing on ore can
+	// extract per unit of Ore.Power.
+	ExtractionRate float64 `json:"extraction_rate,omitempty"`
+}
+
+// IsEnergyRelay reports whether towers of this type can link over long
+// straight lines in the energy network (miners and batteries).
+func (t TowerType) IsEnergyRelay() bool {
+	return t == TowerTypeMiner || t == TowerTypeBattery
 }
 
 // Visuals contains parameters for rendering a tower.
@@ -1,25 +1,143 @@
 package defs
 
-import "time"
+import "encoding/json"
+
+// DefaultTotalWaveDamage — сколько урона суммарно наносит игроку волна, если в ней
+// не задано total_wave_damage. Делится между всеми врагами волны.
+const DefaultTotalWaveDamage = 100
+
+// WaveRepeatCount — сколько последних волн из waves.json повторяется по кругу,
+// когда игрок проходит все описанные волны.
+const WaveRepeatCount = 5
+
+// SpawnGroupDefinition — группа одинаковых врагов внутри волны.
+// Группы одной волны спавнятся параллельно, каждая по своему таймеру.
+type SpawnGroupDefinition struct {
+	EnemyID       string  `json:"enemy_id"`
+	Count         int     `json:"count"`
+	SpawnInterval float64 `json:"spawn_interval,omitempty"` // 0 — интервал волны
+	Delay         float64 `json:"delay,omitempty"`          // Пауза от начала волны до запуска группы (в секундах)
+	// Индекс точки входа (HexMap.Gates). Без него враги группы по очереди выходят
+	// из всех входов карты, начиная с входа номер группы по модулю числа входов.
+	Entry *int `json:"entry,omitempty"`
+	// Враги группы по очереди выходят из всех входов карты, начиная с entry
+	AlternateEntries bool `json:"alternate_entries,omitempty"`
+	// Способности врагов группы; добавляются к способностям волны
+	Abilities []string `json:"abilities,omitempty"`
+	// Шанс уклонения врагов группы; 0 — шанс волны
+	EvasionChance float64 `json:"evasion_chance,omitempty"`
+}
 
 // WaveDefinition описывает параметры для одной волны врагов.
+// Формат совпадает с waves.json из Godot-версии; неуказанные множители равны 1.
+// Волна задается либо одним врагом (enemy_id и count), либо списком групп (enemies).
 type WaveDefinition struct {
-	EnemyID       string          // Идентификатор врага из enemies.json
-	Count         int             // Количество врагов в волне
-	SpawnInterval time.Duration   // Интервал между появлением врагов
+	WaveNumber    int                    `json:"wave_number"`
+	EnemyID       string                 `json:"enemy_id"`       // Идентификатор врага из enemies.json
+	Count         int                    `json:"count"`          // Количество врагов в волне
+	SpawnInterval float64                `json:"spawn_interval"` // Интервал между появлением врагов (в секундах)
+	Groups        []SpawnGroupDefinition `json:"enemies,omitempty"`
+
+	// Здоровье: (health_override или здоровье из enemies.json) * health_multiplier * health_multiplier_modifier
+	HealthOverride           int     `json:"health_override,omitempty"`
+	HealthMultiplier         float64 `json:"health_multiplier"`
+	HealthMultiplierModifier float64 `json:"health_multiplier_modifier"`
+	// Заменяют health_multiplier для летающих и наземных врагов волны (0 — не задан)
+	HealthMultiplierFlying float64 `json:"health_multiplier_flying,omitempty"`
+	HealthMultiplierGround float64 `json:"health_multiplier_ground,omitempty"`
+
+	// Скорость: скорость из enemies.json * speed_multiplier * speed_multiplier_modifier
+	SpeedMultiplier         float64 `json:"speed_multiplier"`
+	SpeedMultiplierModifier float64 `json:"speed_multiplier_modifier"`
+
+	// Броня: (броня из enemies.json + бонус); магическая броня дополнительно умножается
+	PhysicalArmorBonus     int     `json:"physical_armor_bonus,omitempty"`
+	MagicalArmorBonus      int     `json:"magical_armor_bonus,omitempty"`
+	MagicalArmorMultiplier float64 `json:"magical_armor_multiplier"`
+
+	Regen                   float64  `json:"regen,omitempty"` // Восстановление здоровья в секунду
+	RegenMultiplierModifier float64  `json:"regen_multiplier_modifier"`
+	EvasionChance           float64  `json:"evasion_chance,omitempty"` // Шанс увернуться от снаряда (0..1)
+	Abilities               []string `json:"abilities,omitempty"`
+	TotalWaveDamage         int      `json:"total_wave_damage,omitempty"`
+
+	// Блинк врагов волны; 0 — значения из ability_definitions.json
+	BlinkHexes         int     `json:"blink_hexes,omitempty"`          // На сколько гексов переносит блинк
+	BlinkCooldown      float64 `json:"blink_cooldown,omitempty"`       // Перезарядка блинка
+	BlinkStartCooldown float64 `json:"blink_start_cooldown,omitempty"` // Задержка до первого блинка
 }
 
-// WavePatterns определяет последовательность волн в игре.
-// Ключ карты - это номер волны.
-var WavePatterns = map[int]WaveDefinition{
-	1:  {EnemyID: "ENEMY_NORMAL_WEAK", Count: 5, SpawnInterval: time.Millisecond * 800},
-	2:  {EnemyID: "ENEMY_NORMAL_WEAK", Count: 7, SpawnInterval: time.Millisecond * 800},
-	3:  {EnemyID: "ENEMY_NORMAL_WEAK", Count: 9, SpawnInterval: time.Millisecond * 800},
-	4:  {EnemyID: "ENEMY_TOUGH", Count: 7, SpawnInterval: time.Second * 1},
-	5:  {EnemyID: "ENEMY_NORMAL", Count: 10, SpawnInterval: time.Millisecond * 800},
-	6:  {EnemyID: "ENEMY_MAGIC_RESIST", Count: 10, SpawnInterval: time.Millisecond * 750},
-	7:  {EnemyID: "ENEMY_PHYSICAL_RESIST", Count: 10, SpawnInterval: time.Millisecond * 750},
-	8:  {EnemyID: "ENEMY_FAST", Count: 15, SpawnInterval: time.Millisecond * 500},
-	9:  {EnemyID: "ENEMY_NORMAL", Count: 20, SpawnInterval: time.Millisecond * 400},
-	10: {EnemyID: "ENEMY_BOSS", Count: 1, SpawnInterval: time.Second * 1},
+// UnmarshalJSON подставляет множители по умолчанию для полей, отсутствующих в файле.
+func (w *WaveDefinition) UnmarshalJSON(data []byte) error {
+	type plain WaveDefinition
+	def := plain{
+		HealthMultiplier:         1,
+		HealthMultiplierModifier: 1,
+		SpeedMultiplier:          1,
+		SpeedMultiplierModifier:  1,
+		MagicalArmorMultiplier:   1,
+		RegenMultiplierModifier:  1,
+	}
+	if err := json.Unmarshal(data, &def); err != nil {
+		return err
+	}
+	*w = WaveDefinition(def)
+	return nil
+}
+
+// SpawnGroups возвращает группы волны по порядку. Волна с одним врагом
+// превращается в одну группу; интервалы групп без своего берутся у волны.
+func (w WaveDefinition) SpawnGroups() []SpawnGroupDefinition {
+	if len(w.Groups) == 0 {
+		return []SpawnGroupDefinition{{EnemyID: w.EnemyID, Count: w.Count, SpawnInterval: w.SpawnInterval}}
+	}
+	groups := make([]SpawnGroupDefinition, len(w.Groups))
+	for i, g := range w.Groups {
+		if g.SpawnInterval <= 0 {
+			g.SpawnInterval = w.SpawnInterval
+		}
+		groups[i] = g
+	}
+	return groups
+}
+
+// HealthMultiplierFor возвращает множитель здоровья для летающего или наземного врага.
+func (w WaveDefinition) HealthMultiplierFor(flying bool) float64 {
+	if flying && w.HealthMultiplierFlying > 0 {
+		return w.HealthMultiplierFlying
+	}
+	if !flying && w.HealthMultiplierGround > 0 {
+		return w.HealthMultiplierGround
+	}
+	return w.HealthMultiplier
+}
+
+// TotalCount возвращает общее число врагов во всех группах волны.
+func (w WaveDefinition) TotalCount() int {
+	total := 0
+	for _, g := range w.SpawnGroups() {
+		total += g.Count
+	}
+	return total
+}
+
+// WaveDefs — библиотека волн из waves.json, ключ — номер волны.
+var WaveDefs map[int]WaveDefinition
+
+// LastWaveNumber — номер последней описанной волны.
+var LastWaveNumber int
+
+// GetWaveDefinition возвращает определение волны с указанным номером.
+// После последней описанной волны по кругу повторяются WaveRepeatCount последних.
+func GetWaveDefinition(waveNumber int) (WaveDefinition, bool) {
+	if def, ok := WaveDefs[waveNumber]; ok {
+		return def, true
+	}
+	if waveNumber <= LastWaveNumber || LastWaveNumber == 0 {
+		return WaveDefinition{}, false
+	}
+	repeat := min(WaveRepeatCount, LastWaveNumber)
+	first := LastWaveNumber - repeat + 1
+	def, ok := WaveDefs[first+(waveNumber-first)%repeat]
+	return def, ok
 }
@@ -4,29 +4,30 @@ package entity
 import (
 	"go-tower-defense/internal/component"
 	"go-tower-defense/internal/types"
+	"sort"
 )
 
 type ECS struct {
-	GameTime      float64
-	NextID        types.EntityID
-	Positions     map[types.EntityID]*component.Position
-	Velocities    map[types.EntityID]*component.Velocity
-	Paths         map[types.EntityID]*component.Path
-	Healths       map[types.EntityID]*component.Health
-	Renderables   map[types.EntityID]*component.Renderable
-	Towers        map[types.EntityID]*component.Tower
-	Projectiles   map[types.EntityID]*component.Projectile
-	Combats       map[types.EntityID]*component.Combat
-	Ores          map[types.EntityID]*component.Ore
-	Enemies       map[types.EntityID]*component.Enemy
-	LineRenders   map[types.EntityID]*component.LineRender
-	Texts         map[types.EntityID]*component.Text
-	DamageFlashes map[types.EntityID]*component.DamageFlashComponent
-	AoeEffects    map[types.EntityID]*component.AoeEffectComponent
-	Auras         map[types.EntityID]*component.Aura
-	AuraEffects   map[types.EntityID]*component.AuraEffect
-	SlowEffects   map[types.EntityID]*component.SlowEffect
-	PoisonEffects map[types.EntityID]*component.PoisonEffect
+	GameTime               float64
+	NextID                 types.EntityID
+	Positions              map[types.EntityID]*component.Position
+	Velocities             map[types.EntityID]*component.Velocity
+	Paths                  map[types.EntityID]*component.Path
+	Healths                map[types.EntityID]*component.Health
+	Renderables            map[types.EntityID]*component.Renderable
+	Towers                 map[types.EntityID]*component.Tower
+	Projectiles            map[types.EntityID]*component.Projectile
+	Combats                map[types.EntityID]*component.Combat
+	Ores                   map[types.EntityID]*component.Ore
+	Enemies                map[types.EntityID]*component.Enemy
+	LineRenders            map[types.EntityID]*component.LineRender
+	Texts                  map[types.EntityID]*component.Text
+	DamageFlashes          map[types.EntityID]*component.DamageFlashComponent
+	AoeEffects             map[types.EntityID]*component.AoeEffectComponent
+	Auras                  map[types.EntityID]*component.Aura
+	AuraEffects            map[types.EntityID]*component.AuraEffect
+	SlowEffects            map[types.EntityID]*component.SlowEffect
+	PoisonEffects          map[types.EntityID]*component.PoisonEffect
 	JadePoisonContainers   map[types.EntityID]*component.JadePoisonContainer
 	Lasers                 map[types.EntityID]*component.Laser
 	VolcanoEffects         map[types.EntityID]*component.VolcanoEffect // Добавлено для эффектов вулкана
@@ -37,6 +38,8 @@ type ECS struct {
 	Beacons                map[types.EntityID]*component.Beacon
 	BeaconAttackSectors    map[types.EntityID]*component.BeaconAttackSector
 	Turrets                map[types.EntityID]*component.TurretComponent
+	EnemyAbilities         map[types.EntityID]*component.EnemyAbilities
+	Batteries              map[types.EntityID]*component.Battery
 	Wave                   *component.Wave
 	GameState              *component.GameState
 }
@@ -72,10 +75,13 @@ func NewECS() *ECS {
 		Beacons:                make(map[types.EntityID]*component.Beacon),
 		BeaconAttackSectors:    make(map[types.EntityID]*component.BeaconAttackSector),
 		Turrets:                make(map[types.EntityID]*component.TurretComponent),
+		EnemyAbilities:         make(map[types.EntityID]*component.EnemyAbilities),
+		Batteries:              make(map[types.EntityID]*component.Battery),
 		Wave:                   nil,
 		GameState: &component.GameState{
-			Phase:        component.BuildState,
-			TowersToKeep: 2, // 1 miner + 1 attacker
+			Phase:         component.BuildState,
+			TowersToKeep:  2, // 1 miner + 1 attacker
+			OreDrawPolicy: component.OreDrawRandom,
 		},
 	}
 }
@@ -85,3 +91,16 @@ func (ecs *ECS) NewEntity() types.EntityID {
 	ecs.NextID++
 	return id
 }
+
+// SortedIDs возвращает ключи компонентной карты по возрастанию ID.
+// Порядок обхода map в Go случаен; системы, которые создают сущности или
+// тратят случайность внутри цикла, обходят компоненты в этом порядке,
+// чтобы прогоны с одинаковым сидом совпадали.
+func SortedIDs[V any](m map[types.EntityID]V) []types.EntityID {
+	ids := make([]types.EntityID, 0, len(m))
+	for id := range m {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
@@ -9,8 +9,13 @@ const (
 	TowerRemoved                     EventType = "TowerRemoved"
 	OreDepleted                      EventType = "OreDepleted" // Руда истощена
 	OreConsumed                      EventType = "OreConsumed" // Руда потрачена (например, на выстрел)
+	OreRestored                      EventType = "OreRestored" // Истощенная руда восстановилась (Data: ID руды)
+	NetworkRunningDry                EventType = "NetworkRunningDry" // Сеть иссякнет посреди волны (Data: app.NetworkForecast)
 	BuildPhaseStarted                EventType = "BuildPhaseStarted"
 	WavePhaseStarted                 EventType = "WavePhaseStarted"
 	CombineTowersRequest             EventType = "CombineTowersRequest" // Запрос на объединение башен
 	ToggleTowerSelectionForSaveRequest EventType = "ToggleTowerSelectionForSaveRequest" // Запрос на изменение выбора башни для сохранения
+	ToggleBatteryModeRequest         EventType = "ToggleBatteryModeRequest" // Запрос на переключение режима батареи
+	ToggleTowerEnabledRequest        EventType = "ToggleTowerEnabledRequest" // Запрос на включение/выключение башни
+	CycleOreDrawPolicyRequest        EventType = "CycleOreDrawPolicyRequest" // Запрос на смену политики руды (Data: ID башни, 0 — для всей игры)
 )
@@ -2,6 +2,7 @@
 package state
 
 import (
+	"fmt"
 	"go-tower-defense/internal/app"
 	"go-tower-defense/internal/assets"
 	"go-tower-defense/internal/component"
@@ -9,8 +10,10 @@ import (
 	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/types"
 	"go-tower-defense/internal/ui"
+	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
 	"go-tower-defense/pkg/render"
+	"log"
 	"strings"
 	"time"
 
@@ -26,7 +29,11 @@ type GameState struct {
 	game                  *app.Game
 	hexMap                *hexmap.HexMap
 	renderer              *render.HexRenderer
+	renderSystem          *render.RenderSystemRL
 	modelManager          *assets.ModelManager // <-- Менеджер моделей
+	towerDefs             map[string]*defs.TowerDefinition
+	speedButton           *ui.SpeedButtonRL
+	pauseButton           *ui.PauseButtonRL
 	indicator             *ui.StateIndicatorRL
 	playerLevelIndicator  *ui.PlayerLevelIndicatorRL
 	playerHealthIndicator *ui.PlayerHealthIndicator
@@ -41,9 +48,28 @@ type GameState struct {
 	checkpointTextures    map[int]rl.Texture2D
 	isGameOver            bool
 	restartButton         rl.Rectangle
-	visualDebugEnabled    bool // Флаг для режима визуальной отладки
+	visualDebugEnabled    bool                // Флаг для режима визуальной отладки
+	networkInspector      bool                // Инспектор энергосети (F4)
+	networkSnapshot       app.NetworkSnapshot // Снимок сети, обновляется каждый кадр, пока инспектор включен
+	seed                  int64               // Сид, запрошенный при запуске (0 — случайный); переиспользуется при рестарте
+	simAccumulator        float64             // Накопленное время кадров для шагов фиксированной длины
+	replayPlayer          *app.ReplayPlayer   // Не nil в режиме воспроизведения реплея
 }
 
+// ReplayRecordPath — файл, куда GameState сохраняет журнал команд текущей партии.
+// Пустая строка отключает запись. Задается флагом -record в cmd/game.
+var ReplayRecordPath = ""
+
+// LevelID — уровень новых партий (см. defs.LevelDefs). Задается флагом -level в cmd/game.
+var LevelID = defs.DefaultLevelID
+
+// MapPath — файл карты новых партий (см. hexmap.MapFile); пусто — карта генерируется
+// из сида. Задается флагом -map в cmd/game.
+var MapPath = ""
+
+// SaveFilePath — файл быстрого сохранения, с которым работают пауза и главное меню.
+var SaveFilePath = "saves/quicksave.sav"
+
 // intToRoman конвертирует целое число в римскую цифру
 func intToRoman(num int) string {
 	// ... (реализация без изменений)
@@ -62,9 +88,41 @@ func intToRoman(num int) string {
 	return roman.String()
 }
 
-// NewGameState создает новое состояние игры для Raylib
-func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, towerDefs map[string]*defs.TowerDefinition, camera *rl.Camera3D) *GameState {
-	hexMap := hexmap.NewHexMap()
+// NewGameState создает новое состояние игры для Raylib на уровне LevelID и карте MapPath.
+// seed задает корень дерева PRNG (0 — случайный сид).
+func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, towerDefs map[string]*defs.TowerDefinition, camera *rl.Camera3D, seed int64) *GameState {
+	return newStartedGameState(sm, recipeLibrary, towerDefs, camera, seed, LevelID, MapPath)
+}
+
+// newStartedGameState создает новую партию заданного уровня на карте из файла mapPath
+// или, если файл пуст или не читается, на сгенерированной карте.
+func newStartedGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, towerDefs map[string]*defs.TowerDefinition, camera *rl.Camera3D, seed int64, levelID, mapPath string) *GameState {
+	gameLogic, err := app.NewGameOnMap(mapPath, towerDefs, utils.NewPRNGService(seed), levelID)
+	if err != nil {
+		log.Printf("Failed to load map %s, generating one: %v", mapPath, err)
+		gameLogic, _ = app.NewGameOnMap("", towerDefs, utils.NewPRNGService(seed), levelID)
+	}
+
+	gs := newGameStateFor(sm, recipeLibrary, towerDefs, camera, gameLogic, seed)
+	if ReplayRecordPath != "" {
+		gameLogic.EnableRecording(config.SimTimestep)
+	}
+	return gs
+}
+
+// LoadGameState восстанавливает партию из файла сохранения.
+// Запись реплея продолжается, только если она шла в сохраненной партии.
+func LoadGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, towerDefs map[string]*defs.TowerDefinition, camera *rl.Camera3D, path string) (*GameState, error) {
+	gameLogic, err := app.LoadGame(path, towerDefs)
+	if err != nil {
+		return nil, err
+	}
+	return newGameStateFor(sm, recipeLibrary, towerDefs, camera, gameLogic, gameLogic.Rng.Seed()), nil
+}
+
+// newGameStateFor строит рендер и интерфейс вокруг уже созданной игровой логики.
+func newGameStateFor(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, towerDefs map[string]*defs.TowerDefinition, camera *rl.Camera3D, gameLogic *app.Game, seed int64) *GameState {
+	hexMap := gameLogic.HexMap
 
 	var fontChars []rune
 	for i := 32; i <= 127; i++ {
@@ -80,16 +138,19 @@ func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, t
 	modelManager := assets.NewModelManager()
 	modelManager.LoadTowerModels(towerDefs) // Загружаем все модели
 
-	// Передаем менеджер в логику игры
-	gameLogic := app.NewGame(hexMap, font, towerDefs, modelManager)
+	// Логика игры не зависит от графики; рендер получает менеджер моделей отдельно
+	renderSystem := render.NewRenderSystemRL(gameLogic.ECS, font, modelManager)
 
 	// ... (остальная часть инициализации UI без изменений)
 	oreHexColors := make(map[hexmap.Hex]rl.Color)
 	specialHexes := make(map[hexmap.Hex]struct{})
-	specialHexes[hexMap.Entry] = struct{}{}
-	specialHexes[hexMap.Exit] = struct{}{}
-	for _, cp := range hexMap.Checkpoints {
-		specialHexes[cp] = struct{}{}
+	for _, hex := range hexMap.GateHexes() {
+		specialHexes[hex] = struct{}{}
+	}
+	for _, gate := range hexMap.Gates() {
+		for _, cp := range gate.Checkpoints {
+			specialHexes[cp] = struct{}{}
+		}
 	}
 
 	for _, oreComp := range gameLogic.ECS.Ores {
@@ -110,6 +171,18 @@ func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, t
 	levelIndicatorRightEdge := indicatorX + indicatorRadius
 	levelIndicatorWidth := levelIndicatorRightEdge - levelIndicatorLeftEdge
 
+	// Кнопка скорости располагается посередине между паузой и индикатором состояния
+	speedButton := ui.NewSpeedButtonRL(
+		(pauseButtonX+indicatorX)/2+2,
+		float32(config.SpeedButtonY),
+		float32(config.SpeedButtonSize),
+	)
+	pauseButton := ui.NewPauseButtonRL(
+		pauseButtonX,
+		float32(config.IndicatorOffsetX),
+		float32(config.IndicatorRadius),
+	)
+
 	indicator := ui.NewStateIndicatorRL(
 		indicatorX,
 		float32(config.IndicatorOffsetX),
@@ -132,7 +205,7 @@ func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, t
 	// 2. Индикатор здоровья
 	// Высота индикатора руды = 2 * высота сегмента + 2 * отступ
 	oreTotalHeight := (oreIndicatorHeight * 2) + (2 * 2)
-	healthIndicatorY := oreIndicatorY + oreTotalHeight + 15 // Y руды + высота руды + отступ
+	healthIndicatorY := oreIndicatorY + oreTotalHeight + 15  // Y руды + высота руды + отступ
 	tempHealthIndicator := ui.NewPlayerHealthIndicator(0, 0) // Для получения ширины
 	healthIndicatorX := centralX - tempHealthIndicator.GetWidth()/2
 	playerHealthIndicator := ui.NewPlayerHealthIndicator(healthIndicatorX, healthIndicatorY)
@@ -170,8 +243,13 @@ func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, t
 		font,
 	)
 
+	// Номера чекпоинтов считаются в пределах маршрута своего входа
+	maxCheckpoints := 0
+	for _, gate := range hexMap.Gates() {
+		maxCheckpoints = max(maxCheckpoints, len(gate.Checkpoints))
+	}
 	checkpointTextures := make(map[int]rl.Texture2D)
-	for i := 0; i < len(hexMap.Checkpoints); i++ {
+	for i := 0; i < maxCheckpoints; i++ {
 		romanNumeral := intToRoman(i + 1)
 		img := rl.ImageTextEx(font, romanNumeral, 64, 1, rl.White)
 		tex := rl.LoadTextureFromImage(img)
@@ -194,7 +272,11 @@ func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, t
 		game:                  gameLogic,
 		hexMap:                hexMap,
 		renderer:              renderer,
+		renderSystem:          renderSystem,
 		modelManager:          modelManager, // <-- Сохраняем менеджер
+		towerDefs:             towerDefs,
+		speedButton:           speedButton,
+		pauseButton:           pauseButton,
 		indicator:             indicator,
 		playerLevelIndicator:  playerLevelIndicator,
 		playerHealthIndicator: playerHealthIndicator,
@@ -209,17 +291,132 @@ func NewGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, t
 		checkpointTextures:    checkpointTextures,
 		isGameOver:            false,
 		restartButton:         restartButton,
+		seed:                  seed,
 	}
 
 	return gs
 }
 
+// NewReplayGameState создает состояние игры, которое проигрывает записанный реплей.
+// После последнего записанного тика управление переходит к игроку.
+func NewReplayGameState(sm *StateMachine, recipeLibrary *defs.CraftingRecipeLibrary, towerDefs map[string]*defs.TowerDefinition, camera *rl.Camera3D, replay *app.Replay) *GameState {
+	gs := newStartedGameState(sm, recipeLibrary, towerDefs, camera, replay.Seed, replay.Level, replay.Map)
+	gs.replayPlayer = app.NewReplayPlayer(replay)
+	return gs
+}
+
+// drawDryNetworkWarning сообщает рядом с индикатором жил о сети, которая иссякнет посреди волны.
+func (g *GameState) drawDryNetworkWarning() {
+	network, ok := g.game.DryNetworkWarning()
+	if !ok {
+		return
+	}
+	text := fmt.Sprintf("Сеть #%d иссякнет через %.0f с", network.RootID, network.Seconds)
+	pos := rl.NewVector2(g.oreSectorIndicator.X+g.oreSectorIndicator.TotalWidth+10, g.oreSectorIndicator.Y)
+	rl.DrawTextEx(g.font, text, pos, 18, 1, config.OreIndicatorWarningColor)
+}
+
+// stepSimulation продвигает симуляцию шагами фиксированной длины, чтобы партию
+// можно было воспроизвести из журнала команд бит в бит независимо от FPS.
+func (g *GameState) stepSimulation(deltaTime float64) {
+	step := config.SimTimestep
+	if g.replayPlayer != nil {
+		step = g.replayPlayer.Replay().Timestep
+	}
+
+	g.simAccumulator += deltaTime
+	for g.simAccumulator >= step {
+		g.simAccumulator -= step
+		if g.replayPlayer == nil {
+			g.game.Update(step)
+			continue
+		}
+		if g.replayPlayer.Done(g.game) {
+			g.finishReplay()
+			return
+		}
+		g.replayPlayer.Step(g.game)
+	}
+}
+
+// finishReplay сверяет итоговое состояние с записью и возвращает управление игроку.
+func (g *GameState) finishReplay() {
+	if err := g.replayPlayer.Verify(g.game); err != nil {
+		log.Printf("[REPLAY] %v", err)
+	} else {
+		log.Printf("[REPLAY] Воспроизведение завершено на тике %d, состояние совпало", g.game.Tick)
+	}
+	g.replayPlayer = nil
+	g.simAccumulator = 0
+}
+
+// saveReplay сохраняет журнал команд партии в ReplayRecordPath.
+func (g *GameState) saveReplay() {
+	if ReplayRecordPath == "" || g.replayPlayer != nil {
+		return
+	}
+	recording := g.game.Recording()
+	if recording == nil {
+		return
+	}
+	if err := app.SaveReplay(ReplayRecordPath, recording); err != nil {
+		log.Printf("[REPLAY] Не удалось сохранить реплей: %v", err)
+	}
+}
+
+// NetworkExportDir — каталог, куда Shift+F4 выгружает граф энергосети.
+var NetworkExportDir = "debug"
+
+// exportNetwork выгружает текущий граф энергосети в DOT и JSON.
+func (g *GameState) exportNetwork() {
+	path, err := g.game.ExportNetwork(NetworkExportDir)
+	if err != nil {
+		log.Printf("[NETWORK] Не удалось выгрузить граф сети: %v", err)
+		return
+	}
+	log.Printf("[NETWORK] Граф сети выгружен в %s (и .json рядом)", path)
+}
+
+// drawNetworkInspectorUI подписывает башни номером сети и множителем деградации,
+// а для выбранной башни выводит путь до источника и список источников.
+func (g *GameState) drawNetworkInspectorUI() {
+	fontSize := float32(config.RegularFontSizeRL)
+	for _, tower := range g.networkSnapshot.Towers {
+		if tower.Network < 0 {
+			continue
+		}
+		x, y := tower.Hex.ToPixel(config.HexSize)
+		screenPos := rl.GetWorldToScreen(rl.NewVector3(float32(x*config.CoordScale), 14, float32(y*config.CoordScale)), *g.camera)
+		label := fmt.Sprintf("#%d x%.2f", tower.Network, tower.Degradation)
+		rl.DrawTextEx(g.font, label, screenPos, fontSize*0.8, 1.0, render.NetworkInspectorColor(tower.Network))
+	}
+
+	lines := []string{"Инспектор сети (F4), Shift+F4 — выгрузка DOT/JSON"}
+	for _, network := range g.networkSnapshot.Networks {
+		lines = append(lines, fmt.Sprintf("Сеть #%d: башен %d, руда %v, батареи %v, %.2f / %.2f руды/с",
+			network.Index, len(network.Members), network.OreSources, network.Batteries, network.Supply, network.Demand))
+	}
+	if tower, ok := g.networkSnapshot.Tower(g.infoPanel.TargetEntity); ok {
+		lines = append(lines,
+			fmt.Sprintf("Башня %d (%s), сеть #%d, корень: %v", tower.ID, tower.DefID, tower.Network, tower.PowerRoot),
+			fmt.Sprintf("Путь до источника: %v", tower.PathToSource),
+			fmt.Sprintf("Деградация: x%.3f, обеспечение: %.0f%%", tower.Degradation, tower.Throttle*100),
+			fmt.Sprintf("Источники: %v", tower.Sources),
+		)
+	}
+	y := float32(80)
+	for _, line := range lines {
+		rl.DrawTextEx(g.font, line, rl.NewVector2(10, y), fontSize*0.8, 1.0, rl.White)
+		y += fontSize
+	}
+}
+
 // ... (методы Update, Draw, DrawUI и другие остаются без изменений) ...
 
 func (g *GameState) SetCamera(camera *rl.Camera3D) {
 	g.camera = camera
-	if g.game != nil && g.game.RenderSystem != nil {
-		g.game.RenderSystem.SetCamera(camera)
+	if g.renderSystem != nil {
+		g.renderSystem.SetCamera(camera)
 	}
 }
 
@@ -241,7 +438,7 @@ func (g *GameState) Update(deltaTime float64) {
 					towerDefPtrs[id] = &d
 				}
 				// Пересоздаем состояние игры
-				newState := NewGameState(g.sm, defs.RecipeLibrary, towerDefPtrs, g.camera)
+				newState := newStartedGameState(g.sm, defs.RecipeLibrary, towerDefPtrs, g.camera, g.seed, g.game.LevelID, g.game.MapPath)
 				newState.SetCamera(g.camera)
 				g.sm.SetState(newState)
 			}
@@ -250,11 +447,10 @@ func (g *GameState) Update(deltaTime float64) {
 	}
 
 	// Проверяем условие проигрыша
-	if playerState, ok := g.game.ECS.PlayerState[g.game.PlayerID]; ok {
-		if playerState.Health <= 0 {
-			g.isGameOver = true
-			return // Останавливаем дальнейшее обновление
-		}
+	if g.game.IsGameOver() {
+		g.isGameOver = true
+		g.saveReplay()
+		return // Останавливаем дальнейшее обновление
 	}
 
 	// Управление масштабированием камеры
@@ -304,6 +500,22 @@ func (g *GameState) Update(deltaTime float64) {
 		}
 	}
 
+	// Инспектор энергосети работает и при просмотре реплея: F4 — вкл/выкл, Shift+F4 — выгрузка
+	if rl.IsKeyPressed(rl.KeyF4) {
+		if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+			g.exportNetwork()
+		} else {
+			g.networkInspector = !g.networkInspector
+		}
+	}
+
+	// В режиме реплея ввод игрока не влияет на симуляцию: доступна только камера
+	if g.replayPlayer != nil {
+		g.stepSimulation(deltaTime)
+		g.renderSystem.Update()
+		return
+	}
+
 	g.infoPanel.Update(g.game.ECS)
 
 	if rl.IsKeyPressed(rl.KeyB) {
@@ -318,22 +530,8 @@ func (g *GameState) Update(deltaTime float64) {
 		return
 	}
 
-	if g.game.ECS.GameState.Phase == component.TowerSelectionState {
-		selectedCount := 0
-		for _, tower := range g.game.ECS.Towers {
-			if tower.IsTemporary && tower.IsSelected {
-				selectedCount++
-			}
-		}
-		if selectedCount == g.game.ECS.GameState.TowersToKeep {
-			g.game.FinalizeTowerSelection()
-			g.game.ECS.GameState.Phase = component.WaveState
-			g.game.StartWave()
-		}
-	}
-
 	if rl.IsKeyPressed(rl.KeyF9) {
-		g.sm.SetState(NewPauseState(g.sm, g, g.font, g.game.PauseButton))
+		g.sm.SetState(NewPauseState(g.sm, g, g.font, g.pauseButton))
 		return
 	}
 
@@ -346,6 +544,11 @@ func (g *GameState) Update(deltaTime float64) {
 		g.visualDebugEnabled = !g.visualDebugEnabled
 	}
 
+	// Перезагрузка моделей по F5
+	if rl.IsKeyPressed(rl.KeyF5) {
+		g.modelManager.ReloadTowerModels(g.towerDefs)
+	}
+
 	if g.game.ECS.GameState.Phase == component.BuildState || g.game.ECS.GameState.Phase == component.TowerSelectionState {
 		g.handleDebugKeys()
 		if rl.IsKeyPressed(rl.KeyU) {
@@ -353,7 +556,8 @@ func (g *GameState) Update(deltaTime float64) {
 		}
 	}
 
-	g.game.Update(deltaTime)
+	g.stepSimulation(deltaTime)
+	g.renderSystem.Update()
 
 	isShiftPressed := rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift)
 	mousePos := rl.GetMousePosition()
@@ -427,7 +631,7 @@ func (g *GameState) handleDebugKeys() {
 }
 
 func (g *GameState) isClickOnUI(mousePos rl.Vector2) bool {
-	if g.game.SpeedButton.IsClicked(mousePos) || g.game.PauseButton.IsClicked(mousePos) {
+	if g.speedButton.IsClicked(mousePos) || g.pauseButton.IsClicked(mousePos) {
 		return true
 	}
 	if g.indicator.IsClicked(mousePos) {
@@ -440,10 +644,11 @@ func (g *GameState) isClickOnUI(mousePos rl.Vector2) bool {
 }
 
 func (g *GameState) handleUIClick(mousePos rl.Vector2) {
-	if g.game.SpeedButton.IsClicked(mousePos) {
+	if g.speedButton.IsClicked(mousePos) {
 		g.game.HandleSpeedClick()
-	} else if g.game.PauseButton.IsClicked(mousePos) {
-		g.sm.SetState(NewPauseState(g.sm, g, g.font, g.game.PauseButton))
+		g.speedButton.ToggleState()
+	} else if g.pauseButton.IsClicked(mousePos) {
+		g.sm.SetState(NewPauseState(g.sm, g, g.font, g.pauseButton))
 	} else if g.indicator.IsClicked(mousePos) {
 		g.indicator.HandleClick()
 		g.game.HandleIndicatorClick()
@@ -517,13 +722,22 @@ func (g *GameState) handleGameClick(button rl.MouseButton) {
 
 	if g.game.IsInLineDragMode() {
 		if button == rl.MouseLeftButton && hasHit {
-			g.game.HandleLineDragClick(hex, hitPoint)
+			// Переводим мировую точку обратно в пиксельные координаты карты
+			g.game.HandleLineDragClick(hex, float64(hitPoint.X/config.CoordScale), float64(hitPoint.Z/config.CoordScale))
 		} else if button == rl.MouseRightButton {
 			g.game.CancelLineDrag()
 		}
 		return
 	}
 
+	// ПКМ в фазах волны и выбора включает и выключает башню, в фазе строительства — удаляет
+	if button == rl.MouseRightButton && (g.game.ECS.GameState.Phase == component.WaveState || g.game.ECS.GameState.Phase == component.TowerSelectionState) {
+		if towerID, ok := g.game.GetTowerIDAtHex(hex); ok {
+			g.game.ToggleTowerEnabled(towerID)
+		}
+		return
+	}
+
 	if g.game.ECS.GameState.Phase == component.BuildState || g.game.ECS.GameState.Phase == component.TowerSelectionState {
 		if button == rl.MouseLeftButton {
 			if g.game.DebugTowerID != "" {
@@ -544,7 +758,7 @@ func (g *GameState) Draw() {
 	}
 
 	g.renderer.Draw()
-	g.game.RenderSystem.Draw(
+	g.renderSystem.Draw(
 		g.game.GetGameTime(),
 		g.game.IsInLineDragMode(),
 		g.game.GetDragSourceTowerID(),
@@ -555,6 +769,22 @@ func (g *GameState) Draw() {
 		g.game.FuturePath,
 		g.visualDebugEnabled, // Передаем флаг
 	)
+	if g.game.ECS.GameState.Phase == component.BuildState {
+		g.renderSystem.DrawBlockingHexes(g.game.BlockingHexes()) // Здесь строить нельзя: перекроется маршрут
+	}
+
+	if g.networkInspector {
+		g.networkSnapshot = g.game.InspectNetwork()
+		networkOf := make(map[types.EntityID]int, len(g.networkSnapshot.Towers))
+		for _, tower := range g.networkSnapshot.Towers {
+			networkOf[tower.ID] = tower.Network
+		}
+		var path []types.EntityID
+		if tower, ok := g.networkSnapshot.Tower(g.infoPanel.TargetEntity); ok {
+			path = tower.PathToSource
+		}
+		g.renderSystem.DrawNetworkInspector(networkOf, path)
+	}
 
 	selectedID := g.infoPanel.TargetEntity
 	if selectedID != 0 {
@@ -590,33 +820,35 @@ func (g *GameState) Draw() {
 	rl.DrawRenderBatchActive()
 	rl.DisableDepthTest()
 
-	for i, checkpoint := range g.hexMap.Checkpoints {
-		if tex, ok := g.checkpointTextures[i]; ok {
-			px, py := checkpoint.ToPixel(config.HexSize)
-			worldPos := rl.NewVector3(float32(px*config.CoordScale), 5.0, float32(py*config.CoordScale))
-			camMatrix := rl.GetCameraMatrix(*g.camera)
-			camRight := rl.NewVector3(camMatrix.M0, camMatrix.M4, camMatrix.M8)
-			camUp := rl.NewVector3(camMatrix.M1, camMatrix.M5, camMatrix.M9)
-			aspectRatio := float32(tex.Width) / float32(tex.Height)
-			height := float32(9.0)
-			width := height * aspectRatio
-			v1 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, -width/2), rl.Vector3Scale(camUp, -height/2)))
-			v2 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, width/2), rl.Vector3Scale(camUp, -height/2)))
-			v3 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, width/2), rl.Vector3Scale(camUp, height/2)))
-			v4 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, -width/2), rl.Vector3Scale(camUp, height/2)))
-			rl.SetTexture(tex.ID)
-			rl.Begin(rl.Quads)
-			rl.Color4ub(config.GridColorRL.R, config.GridColorRL.G, config.GridColorRL.B, config.GridColorRL.A)
-			rl.TexCoord2f(0.0, 1.0)
-			rl.Vertex3f(v1.X, v1.Y, v1.Z)
-			rl.TexCoord2f(1.0, 1.0)
-			rl.Vertex3f(v2.X, v2.Y, v2.Z)
-			rl.TexCoord2f(1.0, 0.0)
-			rl.Vertex3f(v3.X, v3.Y, v3.Z)
-			rl.TexCoord2f(0.0, 0.0)
-			rl.Vertex3f(v4.X, v4.Y, v4.Z)
-			rl.End()
-			rl.SetTexture(0)
+	for _, gate := range g.hexMap.Gates() {
+		for i, checkpoint := range gate.Checkpoints {
+			if tex, ok := g.checkpointTextures[i]; ok {
+				px, py := checkpoint.ToPixel(config.HexSize)
+				worldPos := rl.NewVector3(float32(px*config.CoordScale), 5.0, float32(py*config.CoordScale))
+				camMatrix := rl.GetCameraMatrix(*g.camera)
+				camRight := rl.NewVector3(camMatrix.M0, camMatrix.M4, camMatrix.M8)
+				camUp := rl.NewVector3(camMatrix.M1, camMatrix.M5, camMatrix.M9)
+				aspectRatio := float32(tex.Width) / float32(tex.Height)
+				height := float32(9.0)
+				width := height * aspectRatio
+				v1 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, -width/2), rl.Vector3Scale(camUp, -height/2)))
+				v2 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, width/2), rl.Vector3Scale(camUp, -height/2)))
+				v3 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, width/2), rl.Vector3Scale(camUp, height/2)))
+				v4 := rl.Vector3Add(worldPos, rl.Vector3Add(rl.Vector3Scale(camRight, -width/2), rl.Vector3Scale(camUp, height/2)))
+				rl.SetTexture(tex.ID)
+				rl.Begin(rl.Quads)
+				rl.Color4ub(config.GridColorRL.R, config.GridColorRL.G, config.GridColorRL.B, config.GridColorRL.A)
+				rl.TexCoord2f(0.0, 1.0)
+				rl.Vertex3f(v1.X, v1.Y, v1.Z)
+				rl.TexCoord2f(1.0, 1.0)
+				rl.Vertex3f(v2.X, v2.Y, v2.Z)
+				rl.TexCoord2f(1.0, 0.0)
+				rl.Vertex3f(v3.X, v3.Y, v3.Z)
+				rl.TexCoord2f(0.0, 0.0)
+				rl.Vertex3f(v4.X, v4.Y, v4.Z)
+				rl.End()
+				rl.SetTexture(0)
+			}
 		}
 	}
 	rl.DrawRenderBatchActive()
@@ -624,7 +856,7 @@ func (g *GameState) Draw() {
 
 	rl.DrawRenderBatchActive()
 	rl.DisableDepthTest()
-	g.game.RenderSystem.DrawProjectiles()
+	g.renderSystem.DrawProjectiles()
 	rl.DrawRenderBatchActive()
 	rl.EnableDepthTest()
 }
@@ -643,16 +875,24 @@ func (g *GameState) DrawUI() {
 	}
 	g.indicator.Draw(stateColor)
 
-	g.game.SpeedButton.Draw()
-	g.game.PauseButton.Draw(false)
+	g.speedButton.Draw()
+	g.pauseButton.Draw(false)
 	g.infoPanel.Draw(g.game.ECS)
 
+	if g.networkInspector {
+		g.drawNetworkInspectorUI()
+	}
+
 	if playerState, ok := g.game.ECS.PlayerState[g.game.PlayerID]; ok {
 		g.playerLevelIndicator.Draw(playerState.Level, playerState.CurrentXP, playerState.XPToNextLevel)
 		g.playerHealthIndicator.Draw(playerState.Health, 100)
 	}
 
-	g.waveIndicator.Draw(g.game.Wave, g.font)
+	var upcomingGroups []defs.SpawnGroupDefinition
+	if waveDef, ok := g.game.UpcomingWave(); ok {
+		upcomingGroups = waveDef.SpawnGroups()
+	}
+	g.waveIndicator.Draw(g.game.Wave, upcomingGroups, g.font)
 
 	if g.recipeBook.IsVisible {
 		availableTowers := make(map[string]int)
@@ -667,8 +907,8 @@ func (g *GameState) DrawUI() {
 	}
 
 	// Отрисовка индикатора состояния жил
-	percentages := g.game.GetOreSectorPercentages()
-	g.oreSectorIndicator.Draw(percentages[0], percentages[1], percentages[2])
+	g.oreSectorIndicator.Draw(g.game.GetOreSectorPercentages(), g.game.GetOreSectorProjections(), g.game.GetOreSectorWarnings(), g.game.GetOreVeinCount())
+	g.drawDryNetworkWarning()
 
 	// Если игра окончена, рисуем оверлей
 	if g.isGameOver {
@@ -700,10 +940,14 @@ func (g *GameState) DrawUI() {
 	}
 }
 
-func (g *GameState) Exit() {}
+// Exit сохраняет реплей при уходе из состояния (пауза, меню, рестарт).
+func (g *GameState) Exit() {
+	g.saveReplay()
+}
 
 // Cleanup освобождает ресурсы, используемые состоянием
 func (g *GameState) Cleanup() {
+	g.saveReplay()
 	g.renderer.Cleanup()
 	g.modelManager.Cleanup() // <-- Очищаем модели
 	for _, tex := range g.checkpointTextures {
@@ -4,19 +4,22 @@ package state
 import (
 	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/ui"
+	"log"
 	"os"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 type MenuState struct {
-	sm          *StateMachine
-	font        rl.Font
-	startButton *ui.Button
-	exitButton  *ui.Button
+	sm             *StateMachine
+	font           rl.Font
+	continueButton *ui.Button // nil, если файла сохранения нет
+	startButton    *ui.Button
+	exitButton     *ui.Button
+	seed           int64 // Сид для новых игр (0 — случайный)
 }
 
-func NewMenuState(sm *StateMachine, font rl.Font) *MenuState {
+func NewMenuState(sm *StateMachine, font rl.Font, seed int64) *MenuState {
 	btnWidth := float32(220)
 	btnHeight := float32(50)
 	spacing := float32(20)
@@ -35,11 +38,23 @@ func NewMenuState(sm *StateMachine, font rl.Font) *MenuState {
 		font,
 	)
 
+	// Кнопка "Продолжить" появляется над "Начать игру", только если есть сохранение
+	var continueButton *ui.Button
+	if _, err := os.Stat(SaveFilePath); err == nil {
+		continueButton = ui.NewButton(
+			rl.NewRectangle(startX, startY-btnHeight-spacing, btnWidth, btnHeight),
+			"Продолжить",
+			font,
+		)
+	}
+
 	return &MenuState{
-		sm:          sm,
-		font:        font,
-		startButton: startButton,
-		exitButton:  exitButton,
+		sm:             sm,
+		font:           font,
+		continueButton: continueButton,
+		startButton:    startButton,
+		exitButton:     exitButton,
+		seed:           seed,
 	}
 }
 
@@ -48,22 +63,22 @@ func (s *MenuState) Enter() {}
 func (s *MenuState) Update(deltaTime float64) {
 	mousePos := rl.GetMousePosition()
 
-	if s.startButton.IsClicked(mousePos) {
-		camera := rl.NewCamera3D(
-			rl.NewVector3(0.0, 80.0, 100.0),
-			rl.NewVector3(0.0, 0.0, 0.0),
-			rl.NewVector3(0.0, 1.0, 0.0),
-			45.0,
-			rl.CameraPerspective,
-		)
-
-		towerDefPtrs := make(map[string]*defs.TowerDefinition)
-		for id, def := range defs.TowerDefs {
-			d := def
-			towerDefPtrs[id] = &d
+	if s.continueButton != nil && s.continueButton.IsClicked(mousePos) {
+		camera := newGameCamera()
+		newState, err := LoadGameState(s.sm, defs.RecipeLibrary, towerDefPointers(), &camera, SaveFilePath)
+		if err != nil {
+			log.Printf("[SAVE] Не удалось загрузить игру: %v", err)
+			s.continueButton = nil
+		} else {
+			newState.SetCamera(&camera)
+			s.sm.SetState(newState)
+			return
 		}
+	}
 
-		newState := NewGameState(s.sm, defs.RecipeLibrary, towerDefPtrs, &camera)
+	if s.startButton.IsClicked(mousePos) {
+		camera := newGameCamera()
+		newState := NewGameState(s.sm, defs.RecipeLibrary, towerDefPointers(), &camera, s.seed)
 		newState.SetCamera(&camera)
 		s.sm.SetState(newState)
 	}
@@ -81,13 +96,37 @@ func (s *MenuState) Draw() {
 	rl.DrawTextEx(s.font, title, rl.NewVector2((float32(rl.GetScreenWidth())-titleWidth)/2, float32(rl.GetScreenHeight()/2-150)), float32(titleFontSize), 1, rl.White)
 
 	mousePos := rl.GetMousePosition()
+	if s.continueButton != nil {
+		s.continueButton.Draw(mousePos)
+	}
 	s.startButton.Draw(mousePos)
 	s.exitButton.Draw(mousePos)
 }
 
 func (s *MenuState) Exit() {}
 
-func (s *MenuState) GetGame() GameInterface            { return nil }
-func (s *MenuState) GetFont() rl.Font                  { return s.font }
-func (s *MenuState) Cleanup()                          {}
-func (s *MenuState) SetCamera(camera *rl.Camera3D)     {}
\ No newline at end of file
+// newGameCamera возвращает стартовую камеру партии.
+func newGameCamera() rl.Camera3D {
+	return rl.NewCamera3D(
+		rl.NewVector3(0.0, 80.0, 100.0),
+		rl.NewVector3(0.0, 0.0, 0.0),
+		rl.NewVector3(0.0, 1.0, 0.0),
+		45.0,
+		rl.CameraPerspective,
+	)
+}
+
+// towerDefPointers создает карту указателей на определения башен для передачи в системы.
+func towerDefPointers() map[string]*defs.TowerDefinition {
+	towerDefPtrs := make(map[string]*defs.TowerDefinition)
+	for id, def := range defs.TowerDefs {
+		d := def
+		towerDefPtrs[id] = &d
+	}
+	return towerDefPtrs
+}
+
+func (s *MenuState) GetGame() GameInterface        { return nil }
+func (s *MenuState) GetFont() rl.Font              { return s.font }
+func (s *MenuState) Cleanup()                      {}
+func (s *MenuState) SetCamera(camera *rl.Camera3D) {}
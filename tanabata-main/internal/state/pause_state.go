@@ -5,6 +5,7 @@ import (
 	"go-tower-defense/internal/config"
 	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/ui"
+	"log"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -19,6 +20,8 @@ type PauseState struct {
 
 	// Кнопки меню
 	continueButton *ui.MenuButton
+	saveButton     *ui.MenuButton
+	loadButton     *ui.MenuButton
 	restartButton  *ui.MenuButton
 	mainMenuButton *ui.MenuButton
 }
@@ -36,20 +39,30 @@ func NewPauseState(sm *StateMachine, prevState State, font rl.Font, pauseButton
 	btnHeight := float32(50)
 	spacing := float32(20)
 	startX := (float32(config.ScreenWidth) - btnWidth) / 2
-	startY := (float32(config.ScreenHeight) - (btnHeight*3 + spacing*2)) / 2
+	startY := (float32(config.ScreenHeight) - (btnHeight*5 + spacing*4)) / 2
 
 	s.continueButton = ui.NewMenuButton(
 		rl.NewRectangle(startX, startY, btnWidth, btnHeight),
 		"Продолжить",
 		font,
 	)
-	s.restartButton = ui.NewMenuButton(
+	s.saveButton = ui.NewMenuButton(
 		rl.NewRectangle(startX, startY+btnHeight+spacing, btnWidth, btnHeight),
+		"Сохранить",
+		font,
+	)
+	s.loadButton = ui.NewMenuButton(
+		rl.NewRectangle(startX, startY+(btnHeight+spacing)*2, btnWidth, btnHeight),
+		"Загрузить",
+		font,
+	)
+	s.restartButton = ui.NewMenuButton(
+		rl.NewRectangle(startX, startY+(btnHeight+spacing)*3, btnWidth, btnHeight),
 		"Начать заново",
 		font,
 	)
 	s.mainMenuButton = ui.NewMenuButton(
-		rl.NewRectangle(startX, startY+(btnHeight+spacing)*2, btnWidth, btnHeight),
+		rl.NewRectangle(startX, startY+(btnHeight+spacing)*4, btnWidth, btnHeight),
 		"Главное меню",
 		font,
 	)
@@ -70,6 +83,31 @@ func (s *PauseState) Update(deltaTime float64) {
 		return
 	}
 
+	// Обработка клика по кнопке "Сохранить": партия остается на паузе
+	if s.saveButton.IsClicked(rl.GetMousePosition()) && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		if gs, ok := s.previousState.(*GameState); ok {
+			if err := gs.game.SaveGame(SaveFilePath); err != nil {
+				log.Printf("[SAVE] Не удалось сохранить игру: %v", err)
+			} else {
+				log.Printf("[SAVE] Игра сохранена в %s", SaveFilePath)
+			}
+		}
+	}
+
+	// Обработка клика по кнопке "Загрузить"
+	if s.loadButton.IsClicked(rl.GetMousePosition()) && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
+		if gs, ok := s.previousState.(*GameState); ok {
+			newState, err := LoadGameState(s.stateMachine, defs.RecipeLibrary, gs.towerDefs, gs.camera, SaveFilePath)
+			if err != nil {
+				log.Printf("[SAVE] Не удалось загрузить игру: %v", err)
+			} else {
+				newState.SetCamera(gs.camera)
+				s.stateMachine.SetState(newState)
+				return
+			}
+		}
+	}
+
 	// Обработка клика по кнопке "Начать заново"
 	if s.restartButton.IsClicked(rl.GetMousePosition()) && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
 		if gs, ok := s.previousState.(*GameState); ok {
@@ -80,7 +118,7 @@ func (s *PauseState) Update(deltaTime float64) {
 				towerDefPtrs[id] = &d
 			}
 			// Пересоздаем состояние игры
-			newState := NewGameState(s.stateMachine, defs.RecipeLibrary, towerDefPtrs, gs.camera)
+			newState := newStartedGameState(s.stateMachine, defs.RecipeLibrary, towerDefPtrs, gs.camera, gs.seed, gs.game.LevelID, gs.game.MapPath)
 			newState.SetCamera(gs.camera)
 			s.stateMachine.SetState(newState)
 		}
@@ -90,7 +128,11 @@ func (s *PauseState) Update(deltaTime float64) {
 	if s.mainMenuButton.IsClicked(rl.GetMousePosition()) && rl.IsMouseButtonPressed(rl.MouseLeftButton) {
 		// Предполагается, что у вас есть состояние NewMenuState
 		// Если его нет, эту строку нужно будет адаптировать
-		s.stateMachine.SetState(NewMenuState(s.stateMachine, s.font))
+		var seed int64
+		if gs, ok := s.previousState.(*GameState); ok {
+			seed = gs.seed
+		}
+		s.stateMachine.SetState(NewMenuState(s.stateMachine, s.font, seed))
 	}
 }
 
@@ -114,6 +156,8 @@ func (s *PauseState) DrawUI() {
 
 	// Рисуем кнопки меню
 	s.continueButton.Draw()
+	s.saveButton.Draw()
+	s.loadButton.Draw()
 	s.restartButton.Draw()
 	s.mainMenuButton.Draw()
 }
@@ -132,6 +176,11 @@ func (s *PauseState) GetFont() rl.Font {
 	return s.font
 }
 
-func (s *PauseState) Cleanup() {}
+// Cleanup освобождает ресурсы игры под паузой (окно могут закрыть прямо из меню паузы).
+func (s *PauseState) Cleanup() {
+	if s.previousState != nil {
+		s.previousState.Cleanup()
+	}
+}
 
-func (s *PauseState) SetCamera(camera *rl.Camera3D) {}
\ No newline at end of file
+func (s *PauseState) SetCamera(camera *rl.Camera3D) {}
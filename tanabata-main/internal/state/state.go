@@ -2,15 +2,15 @@
 package state
 
 import (
-	"go-tower-defense/internal/app"
 	rl "github.com/gen2brain/raylib-go/raylib"
+	"go-tower-defense/internal/app"
 )
 
 // GameInterface определяет методы, которые состояния могут запрашивать у GameState.
 // Это нужно, чтобы PauseState мог взаимодействовать с GameState, не создавая циклического импорта.
+// Шрифт в интерфейс не входит: app.Game не зависит от raylib.
 type GameInterface interface {
 	GetGame() *app.Game
-	GetFont() rl.Font
 }
 
 // State — интерфейс для всех состояний
@@ -64,4 +64,4 @@ func (sm *StateMachine) Draw() {
 	if sm.current != nil {
 		sm.current.Draw()
 	}
-}
\ No newline at end of file
+}
@@ -19,7 +19,8 @@ func NewAreaAttackSystem(ecs *entity.ECS) *AreaAttackSystem {
 
 func (s *AreaAttackSystem) Update(deltaTime float64) {
 	// Перебираем все башни с боевым компонентом
-	for id, combat := range s.ecs.Combats {
+	for _, id := range entity.SortedIDs(s.ecs.Combats) {
+		combat := s.ecs.Combats[id]
 		// Проверяем, что это наша башня
 		if combat.Attack.Type != defs.BehaviorAreaOfEffect {
 			continue
@@ -27,7 +28,7 @@ func (s *AreaAttackSystem) Update(deltaTime float64) {
 
 		// Проверяем, активна ли башня
 		tower, ok := s.ecs.Towers[id]
-		if !ok || !tower.IsActive {
+		if !ok || !tower.IsActive || tower.IsManuallyDisabled {
 			continue
 		}
 
@@ -63,9 +64,9 @@ func (s *AreaAttackSystem) Update(deltaTime float64) {
 		// --- Конец создания эффекта ---
 
 		// Находим всех врагов в радиусе и наносим урон
-		for enemyID, enemyPos := range s.ecs.Positions {
-			// Убеждаемся, что это враг
-			if _, isEnemy := s.ecs.Enemies[enemyID]; !isEnemy {
+		for _, enemyID := range entity.SortedIDs(s.ecs.Enemies) {
+			enemyPos, ok := s.ecs.Positions[enemyID]
+			if !ok || !canTowerTarget(s.ecs, tower, enemyID) {
 				continue
 			}
 			dx := towerPos.X - enemyPos.X
@@ -39,7 +39,7 @@ func (s *AuraSystem) RecalculateAuras() {
 			}
 
 			// Эффект не применяется к самой башне-ауре, стенам и добытчикам.
-			if targetID == auraTowerID || targetDef.Type == defs.TowerTypeWall || targetDef.Type == defs.TowerTypeMiner {
+			if targetID == auraTowerID || targetDef.Type == defs.TowerTypeWall || targetDef.Type.IsEnergyRelay() {
 				continue
 			}
 			// Проверяем, является ли цель атакующей башней
@@ -0,0 +1,119 @@
+// internal/system/battery.go
+package system
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/event"
+	"go-tower-defense/internal/types"
+	"sort"
+)
+
+const batteryTickInterval = 1.0 // Батарея заряжается раз в секунду
+
+// BatterySystem управляет башнями "Батарея". В режиме зарядки батарея раз в секунду
+// забирает руду из самой богатой жилы своей сети и складывает ее в хранилище.
+// В режиме разрядки она сама становится источником энергии, а потребители
+// списывают руду из ее хранилища так же, как из жилы.
+type BatterySystem struct {
+	ecs               *entity.ECS
+	eventDispatcher   *event.Dispatcher
+	powerSourceFinder func(towerID types.EntityID) []types.EntityID
+}
+
+func NewBatterySystem(ecs *entity.ECS, eventDispatcher *event.Dispatcher, finder func(towerID types.EntityID) []types.EntityID) *BatterySystem {
+	return &BatterySystem{
+		ecs:               ecs,
+		eventDispatcher:   eventDispatcher,
+		powerSourceFinder: finder,
+	}
+}
+
+func (s *BatterySystem) Update(deltaTime float64) {
+	for _, id := range entity.SortedIDs(s.ecs.Batteries) {
+		battery := s.ecs.Batteries[id]
+		tower, ok := s.ecs.Towers[id]
+		if !ok {
+			continue
+		}
+
+		if battery.Mode == component.BatteryDischarging {
+			// Разряженная батарея возвращается в режим зарядки; сеть пересчитывается
+			// так же, как при истощении жилы
+			if battery.Stored < config.OreDepletionThreshold {
+				battery.Mode = component.BatteryCharging
+				s.eventDispatcher.Dispatch(event.Event{Type: event.OreDepleted, Data: id})
+			}
+			continue
+		}
+
+		battery.TickTimer -= deltaTime
+		if battery.TickTimer > 0 {
+			continue
+		}
+		battery.TickTimer = batteryTickInterval
+
+		if tower.IsActive && !tower.IsManuallyDisabled {
+			s.charge(id, battery, defs.TowerDefs[tower.DefID].Energy)
+		}
+	}
+}
+
+// charge переносит руду из самой богатой жилы сети в хранилище батареи.
+func (s *BatterySystem) charge(id types.EntityID, battery *component.Battery, energy *defs.EnergyStats) {
+	if energy == nil {
+		return
+	}
+	amount := min(energy.ChargeRate, energy.StorageMax-battery.Stored)
+	if amount <= 0 {
+		return
+	}
+
+	// Заряжаемся только от жил: разряжающиеся батареи сети не перекачивают руду друг в друга
+	var ores []types.EntityID
+	for _, sourceID := range s.powerSourceFinder(id) {
+		if _, ok := s.ecs.Ores[sourceID]; ok {
+			ores = append(ores, sourceID)
+		}
+	}
+	if len(ores) == 0 {
+		return
+	}
+	sort.SliceStable(ores, func(i, j int) bool {
+		return s.ecs.Ores[ores[i]].CurrentReserve > s.ecs.Ores[ores[j]].CurrentReserve
+	})
+
+	richest := s.ecs.Ores[ores[0]]
+	amount = min(amount, richest.CurrentReserve)
+	s.eventDispatcher.Dispatch(event.Event{
+		Type: event.OreConsumed,
+		Data: OreConsumptionData{SourceID: ores[0], Amount: amount},
+	})
+	battery.Stored += amount
+}
+
+// --- Хелперы для потребителей энергии ---
+
+// powerSourceReserve возвращает запас источника питания: жилы руды или разряжающейся батареи.
+func powerSourceReserve(ecs *entity.ECS, sourceID types.EntityID) float64 {
+	if ore, ok := ecs.Ores[sourceID]; ok {
+		return ore.CurrentReserve
+	}
+	if battery, ok := ecs.Batteries[sourceID]; ok {
+		return battery.Stored
+	}
+	return 0
+}
+
+// spendFromPowerSource списывает руду из жилы или хранилища батареи, не уходя ниже нуля.
+func spendFromPowerSource(ecs *entity.ECS, sourceID types.EntityID, amount float64) {
+	if ore, ok := ecs.Ores[sourceID]; ok {
+		ore.CurrentReserve = max(0, ore.CurrentReserve-amount)
+		return
+	}
+	if battery, ok := ecs.Batteries[sourceID]; ok {
+		battery.Stored = max(0, battery.Stored-amount)
+	}
+}
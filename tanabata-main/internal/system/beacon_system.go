@@ -6,11 +6,10 @@ import (
 	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/entity"
 	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
 	"image/color"
 	"math"
-	"math/rand"
-
-	rl "github.com/gen2brain/raylib-go/raylib"
+	"sort"
 )
 
 const beaconTickRate = 24.0 // 24 раза в секунду
@@ -19,20 +18,25 @@ const beaconTickRate = 24.0 // 24 раза в секунду
 type BeaconSystem struct {
 	ecs               *entity.ECS
 	powerSourceFinder func(towerID types.EntityID) []types.EntityID
+	oreDrawer         *OreDrawer
+	rng               *utils.PRNGService
 }
 
 // NewBeaconSystem создает новую систему для маяков.
-func NewBeaconSystem(ecs *entity.ECS, finder func(towerID types.EntityID) []types.EntityID) *BeaconSystem {
+func NewBeaconSystem(ecs *entity.ECS, finder func(towerID types.EntityID) []types.EntityID, oreDrawer *OreDrawer, rng *utils.PRNGService) *BeaconSystem {
 	return &BeaconSystem{
 		ecs:               ecs,
 		powerSourceFinder: finder,
+		oreDrawer:         oreDrawer,
+		rng:               rng,
 	}
 }
 
 // Update обновляет состояние всех маяков.
 func (s *BeaconSystem) Update(deltaTime float64) {
-	for id, tower := range s.ecs.Towers {
-		if tower.DefID != "TOWER_LIGHTHOUSE" || !tower.IsActive {
+	for _, id := range entity.SortedIDs(s.ecs.Towers) {
+		tower := s.ecs.Towers[id]
+		if tower.DefID != "TOWER_LIGHTHOUSE" || !tower.IsActive || tower.IsManuallyDisabled {
 			if _, hasSector := s.ecs.BeaconAttackSectors[id]; hasSector {
 				s.ecs.BeaconAttackSectors[id].IsVisible = false
 			}
@@ -46,7 +50,7 @@ func (s *BeaconSystem) Update(deltaTime float64) {
 		if !ok {
 			beacon = &component.Beacon{
 				RotationSpeed: towerDef.Combat.Attack.Params.RotationSpeed,
-				ArcAngle:      towerDef.Combat.Attack.Params.ArcAngle * rl.Deg2rad, // Конвертируем градусы в радианы
+				ArcAngle:      towerDef.Combat.Attack.Params.ArcAngle * (math.Pi / 180), // Конвертируем градусы в радианы
 			}
 			s.ecs.Beacons[id] = beacon
 		}
@@ -79,9 +83,7 @@ func (s *BeaconSystem) Update(deltaTime float64) {
 
 		var totalReserve float64
 		for _, sourceID := range powerSources {
-			if ore, ok := s.ecs.Ores[sourceID]; ok {
-				totalReserve += ore.CurrentReserve
-			}
+			totalReserve += powerSourceReserve(s.ecs, sourceID)
 		}
 
 		tickCost := combat.ShotCost / beaconTickRate
@@ -94,10 +96,11 @@ func (s *BeaconSystem) Update(deltaTime float64) {
 			continue
 		}
 
-		s.spendPower(powerSources, tickCost)
+		sourceID := s.spendPower(id, powerSources, tickCost)
 
 		// Урон в 4 раза больше базового, но распределен по тикам
 		tickDamage := (float64(towerDef.Combat.Damage) * 4) / beaconTickRate
+		tickDamage *= oreTypeDamageMultiplier(s.ecs, sourceID, combat.Attack.DamageType)
 		if tickDamage < 1 {
 			tickDamage = 1
 		}
@@ -113,7 +116,7 @@ func (s *BeaconSystem) Update(deltaTime float64) {
 						Y:         enemyPos.Y,
 						Z:         float64(enemyRenderable.Radius * config.CoordScale),
 						MaxRadius: float64(enemyRenderable.Radius * 1.5),
-						Duration:  0.25, // Длительность равна тику
+						Duration:  0.25,                                       // Длительность равна тику
 						Color:     color.RGBA{R: 255, G: 255, B: 224, A: 255}, // Бело-желтый
 					}
 				}
@@ -163,7 +166,7 @@ func (s *BeaconSystem) findTargetsInSector(towerID types.EntityID, tower *compon
 	cy := ay + rangePixels*math.Sin(endAngle)
 
 	for enemyID, enemyPos := range s.ecs.Positions {
-		if _, isEnemy := s.ecs.Enemies[enemyID]; !isEnemy {
+		if !canTowerTarget(s.ecs, tower, enemyID) {
 			continue
 		}
 		if s.ecs.Healths[enemyID].Value <= 0 {
@@ -175,23 +178,15 @@ func (s *BeaconSystem) findTargetsInSector(towerID types.EntityID, tower *compon
 			targets = append(targets, enemyID)
 		}
 	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
 	return targets
 }
 
-func (s *BeaconSystem) spendPower(powerSources []types.EntityID, cost float64) {
-	availableSources := []types.EntityID{}
-	for _, sourceID := range powerSources {
-		if ore, ok := s.ecs.Ores[sourceID]; ok && ore.CurrentReserve > 0 {
-			availableSources = append(availableSources, sourceID)
-		}
-	}
-	if len(availableSources) > 0 {
-		chosenSourceID := availableSources[rand.Intn(len(availableSources))]
-		chosenOre := s.ecs.Ores[chosenSourceID]
-		if chosenOre.CurrentReserve >= cost {
-			chosenOre.CurrentReserve -= cost
-		} else {
-			chosenOre.CurrentReserve = 0
-		}
+// spendPower списывает стоимость тика и возвращает выбранный источник (0, если списывать не из чего).
+func (s *BeaconSystem) spendPower(towerID types.EntityID, powerSources []types.EntityID, cost float64) types.EntityID {
+	chosenSourceID, ok := s.oreDrawer.Draw(towerID, powerSources, s.rng)
+	if ok {
+		spendFromPowerSource(s.ecs, chosenSourceID, cost)
 	}
+	return chosenSourceID
 }
@@ -9,13 +9,10 @@ import (
 	"go-tower-defense/internal/types"
 	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
+	"image/color"
 	"log"
 	"math"
-	"math/rand"
 	"sort"
-	"time"
-
-	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
 // OreConsumptionData содержит информацию о потраченной руде.
@@ -30,22 +27,28 @@ type CombatSystem struct {
 	eventDispatcher   *event.Dispatcher // Добавляем диспатчер
 	powerSourceFinder func(towerID types.EntityID) []types.EntityID
 	pathFinder        func(towerID types.EntityID) []types.EntityID
+	oreDrawer         *OreDrawer         // Выбор источника, из которого списывается выстрел
+	rng               *utils.PRNGService // Случайные политики выбора источника руды для выстрела
 }
 
 func NewCombatSystem(ecs *entity.ECS, dispatcher *event.Dispatcher,
 	finder func(towerID types.EntityID) []types.EntityID,
-	pathFinder func(towerID types.EntityID) []types.EntityID) *CombatSystem {
-	rand.Seed(time.Now().UnixNano())
+	pathFinder func(towerID types.EntityID) []types.EntityID,
+	oreDrawer *OreDrawer,
+	rng *utils.PRNGService) *CombatSystem {
 	return &CombatSystem{
 		ecs:               ecs,
 		eventDispatcher:   dispatcher, // Сохраняем диспатчер
 		powerSourceFinder: finder,
 		pathFinder:        pathFinder,
+		oreDrawer:         oreDrawer,
+		rng:               rng,
 	}
 }
 
 func (s *CombatSystem) Update(deltaTime float64) {
-	for id, combat := range s.ecs.Combats {
+	for _, id := range entity.SortedIDs(s.ecs.Combats) {
+		combat := s.ecs.Combats[id]
 		tower, hasTower := s.ecs.Towers[id]
 		if !hasTower {
 			continue
@@ -70,7 +73,7 @@ func (s *CombatSystem) Update(deltaTime float64) {
 
 			// 2. Если текущая цель невалидна, ищем новую.
 			if !targetIsValid {
-				targets := s.findTargetsForSplitAttack(tower.Hex, int(turret.AcquisitionRange), 1)
+				targets := s.findTargetsForSplitAttack(tower, int(turret.AcquisitionRange), 1)
 				if len(targets) > 0 {
 					turret.TargetID = targets[0]
 				} else {
@@ -94,6 +97,9 @@ func (s *CombatSystem) Update(deltaTime float64) {
 					if targetRenderable, ok := s.ecs.Renderables[turret.TargetID]; ok {
 						turretHeadHeight := getTowerRenderHeight(tower, towerRenderable)
 						targetHeight := targetRenderable.Radius * float32(config.CoordScale)
+						if enemy, ok := s.ecs.Enemies[turret.TargetID]; ok && enemy.Flying {
+							targetHeight += config.FlyingEnemyHeight
+						}
 						deltaHeight := targetHeight - turretHeadHeight
 						horizontalDist := float32(math.Sqrt(dx*dx + dy*dy))
 						turret.TargetPitch = float32(math.Atan2(float64(deltaHeight), float64(horizontalDist)))
@@ -112,7 +118,7 @@ func (s *CombatSystem) Update(deltaTime float64) {
 		}
 		// --- КОНЕЦ ОБНОВЛЕННОГО БЛОКА ---
 
-		if !tower.IsActive {
+		if !tower.IsActive || tower.IsManuallyDisabled {
 			continue
 		}
 
@@ -138,81 +144,136 @@ func (s *CombatSystem) Update(deltaTime float64) {
 
 		var totalReserve float64
 		for _, sourceID := range powerSources {
-			if ore, ok := s.ecs.Ores[sourceID]; ok {
-				totalReserve += ore.CurrentReserve
-			}
+			totalReserve += powerSourceReserve(s.ecs, sourceID)
 		}
 
 		if totalReserve < combat.ShotCost {
 			continue
 		}
 
-		attackPerformed := false
+		// Цели ищутся до списания руды, чтобы башня без целей не тратила выбор источника
+		targets, hasTargets := s.findAttackTargets(id, tower, combat)
+		if !hasTargets {
+			combat.IdleTime += deltaTime // Простой: после FlowIdleGrace башня выпадает из спроса сети
+			continue
+		}
+		combat.IdleTime = 0
+		// Сеть посчитана и ничего не дает этой башне — выстрела нет
+		if tower.Flow.Computed && tower.Flow.Throttle <= 0 {
+			continue
+		}
+		// Источник выбирается один раз: из него списывается выстрел, и его руда задает бонусы урона
+		chosenSourceID, ok := s.oreDrawer.Draw(id, powerSources, s.rng)
+		if !ok {
+			continue
+		}
+
 		switch combat.Attack.Type {
-		case defs.BehaviorProjectile:
-			attackPerformed = s.handleProjectileAttack(id, tower, combat, &towerDef)
 		case defs.BehaviorLaser:
-			attackPerformed = s.handleLaserAttack(id, tower, combat, &towerDef)
+			s.handleLaserAttack(id, tower, combat, &towerDef, targets[0], chosenSourceID)
 		default:
-			attackPerformed = s.handleProjectileAttack(id, tower, combat, &towerDef)
+			s.handleProjectileAttack(id, tower, combat, &towerDef, targets, chosenSourceID)
 		}
 
-		if attackPerformed {
-			availableSources := []types.EntityID{}
-			for _, sourceID := range powerSources {
-				if ore, ok := s.ecs.Ores[sourceID]; ok && ore.CurrentReserve > 0 {
-					availableSources = append(availableSources, sourceID)
-				}
-			}
-			if len(availableSources) > 0 {
-				chosenSourceID := availableSources[rand.Intn(len(availableSources))]
-
-				// --- ИЗМЕНЕНИЕ: Отправляем событие вместо прямого вычитания ---
-				consumptionData := OreConsumptionData{
-					SourceID: chosenSourceID,
-					Amount:   combat.ShotCost,
-				}
-				s.eventDispatcher.Dispatch(event.Event{
-					Type: event.OreConsumed,
-					Data: consumptionData,
-				})
-				// --- КОНЕЦ ИЗМЕНЕНИЯ ---
-
-				fireRate := combat.FireRate
-				if auraEffect, ok := s.ecs.AuraEffects[id]; ok {
-					fireRate *= auraEffect.SpeedMultiplier
-				}
-				combat.FireCooldown = 1.0 / fireRate
-			}
+		// --- ИЗМЕНЕНИЕ: Отправляем событие вместо прямого вычитания ---
+		consumptionData := OreConsumptionData{
+			SourceID: chosenSourceID,
+			Amount:   combat.ShotCost,
+		}
+		s.eventDispatcher.Dispatch(event.Event{
+			Type: event.OreConsumed,
+			Data: consumptionData,
+		})
+		// --- КОНЕЦ ИЗМЕНЕНИЯ ---
+
+		fireRate := combat.FireRate
+		if auraEffect, ok := s.ecs.AuraEffects[id]; ok {
+			fireRate *= auraEffect.SpeedMultiplier
 		}
+		// Сеть не успевает добывать или передавать руду — башня стреляет реже
+		if tower.Flow.Computed && tower.Flow.Throttle < 1 {
+			fireRate *= tower.Flow.Throttle
+		}
+		combat.FireCooldown = 1.0 / fireRate
 	}
 }
-// ... (остальная часть файла без изменений)
-func (s *CombatSystem) handleLaserAttack(towerID types.EntityID, tower *component.Tower, combat *component.Combat, towerDef *defs.TowerDefinition) bool {
-	// 1. Найти одну ближайшую цель
-	targets := s.findTargetsForSplitAttack(tower.Hex, combat.Range, 1)
-	if len(targets) == 0 {
-		return false
+
+// findAttackTargets выбирает цели выстрела. Лазер бьет одну ближайшую цель;
+// снаряды — цель турели, если она в радиусе атаки, иначе до SplitCount ближайших.
+// INTERNAL-атаке цели не нужны: выстрел считается состоявшимся без них.
+func (s *CombatSystem) findAttackTargets(towerID types.EntityID, tower *component.Tower, combat *component.Combat) ([]types.EntityID, bool) {
+	if combat.Attack.Type == defs.BehaviorLaser {
+		targets := s.findTargetsForSplitAttack(tower, combat.Range, 1)
+		if len(targets) == 0 {
+			return nil, false
+		}
+		if _, ok := s.ecs.Positions[targets[0]]; !ok {
+			return nil, false
+		}
+		if _, ok := s.ecs.Renderables[targets[0]]; !ok {
+			return nil, false
+		}
+		return targets, true
+	}
+
+	// Для INTERNAL атак цель не нужна, просто считаем выстрел успешным
+	if combat.Attack.DamageType == defs.AttackInternal {
+		return nil, true
 	}
-	targetID := targets[0]
-	targetPos, okPos := s.ecs.Positions[targetID]
-	targetRenderable, okRender := s.ecs.Renderables[targetID]
-	if !okPos || !okRender {
-		return false
+
+	var targets []types.EntityID
+
+	// --- НОВАЯ ЛОГИКА СИНХРОНИЗАЦИИ С ТУРЕЛЬЮ ---
+	// Если у башни есть турель и у нее есть валидная цель
+	if turret, hasTurret := s.ecs.Turrets[towerID]; hasTurret && turret.TargetID != 0 {
+		// Проверяем, находится ли цель турели в РАДИУСЕ АТАКИ (а не захвата)
+		if targetPos, exists := s.ecs.Positions[turret.TargetID]; exists {
+			towerPosPixelX, towerPosPixelY := tower.Hex.ToPixel(config.HexSize)
+			// Расстояние в пикселях в квадрате
+			distSq := (targetPos.X-towerPosPixelX)*(targetPos.X-towerPosPixelX) + (targetPos.Y-towerPosPixelY)*(targetPos.Y-towerPosPixelY)
+			// Сравниваем с радиусом атаки, переведенным в пиксели в квадрате
+			if distSq < float64(combat.Range*combat.Range*config.HexSize*config.HexSize) {
+				// Если да, то это наша единственная цель
+				targets = []types.EntityID{turret.TargetID}
+			}
+		}
 	}
 
-	// 2. Рассчитать урон (логика аналогична handleProjectileAttack)
-	powerSources := s.powerSourceFinder(towerID)
-	if len(powerSources) == 0 {
-		return false
+	// Если после проверки турели целей нет (или это башня без турели), используем старую логику
+	if len(targets) == 0 {
+		splitCount := 1
+		if combat.Attack.Params != nil && combat.Attack.Params.SplitCount != nil {
+			splitCount = *combat.Attack.Params.SplitCount
+		}
+		if splitCount <= 0 {
+			splitCount = 1
+		}
+		targets = s.findTargetsForSplitAttack(tower, combat.Range, splitCount)
 	}
-	chosenSourceID := powerSources[rand.Intn(len(powerSources))]
-	chosenOre := s.ecs.Ores[chosenSourceID]
-	boostMultiplier := calculateOreBoostMultiplier(chosenOre.CurrentReserve)
+	// --- КОНЕЦ НОВОЙ ЛОГИКИ ---
+
+	return targets, len(targets) > 0
+}
+
+// shotDamage считает урон выстрела: бонус запаса и типа руды берутся у источника
+// sourceID, из которого списан выстрел, ослабление — по длине линий до источника.
+func (s *CombatSystem) shotDamage(towerID types.EntityID, combat *component.Combat, towerDef *defs.TowerDefinition, sourceID types.EntityID) int {
+	boostMultiplier := calculateOreBoostMultiplier(powerSourceReserve(s.ecs, sourceID))
+	boostMultiplier *= oreTypeDamageMultiplier(s.ecs, sourceID, combat.Attack.DamageType)
 	pathToSource := s.pathFinder(towerID)
 	degradationMultiplier := s.calculateLineDegradationMultiplier(pathToSource)
 	baseDamage := float64(towerDef.Combat.Damage)
-	finalDamage := int(math.Round(baseDamage * boostMultiplier * degradationMultiplier))
+	return int(math.Round(baseDamage * boostMultiplier * degradationMultiplier))
+}
+
+// handleLaserAttack бьет цель лучом; урон зависит от руды источника sourceID,
+// из которого списан выстрел.
+func (s *CombatSystem) handleLaserAttack(towerID types.EntityID, tower *component.Tower, combat *component.Combat, towerDef *defs.TowerDefinition, targetID, sourceID types.EntityID) {
+	targetPos := s.ecs.Positions[targetID]
+	targetRenderable := s.ecs.Renderables[targetID]
+
+	// Рассчитать урон (логика аналогична handleProjectileAttack)
+	finalDamage := s.shotDamage(towerID, combat, towerDef, sourceID)
 
 	// 3. Применить урон и эффекты напрямую
 	ApplyDamage(s.ecs, targetID, finalDamage, combat.Attack.DamageType)
@@ -221,7 +282,7 @@ func (s *CombatSystem) handleLaserAttack(towerID types.EntityID, tower *componen
 	if combat.Attack.Params != nil && combat.Attack.Params.SlowMultiplier != nil && combat.Attack.Params.SlowDuration != nil {
 		slowMultiplier := *combat.Attack.Params.SlowMultiplier
 		slowDuration := *combat.Attack.Params.SlowDuration
-		if slowMultiplier > 0 && slowDuration > 0 {
+		if slowMultiplier > 0 && slowDuration > 0 && !isEffectImmune(s.ecs, targetID) {
 			if existingEffect, ok := s.ecs.SlowEffects[targetID]; ok {
 				existingEffect.Timer = slowDuration
 			} else {
@@ -254,8 +315,6 @@ func (s *CombatSystem) handleLaserAttack(towerID types.EntityID, tower *componen
 		Timer:      0,
 	}
 	s.ecs.Renderables[laserID] = &component.Renderable{}
-
-	return true
 }
 
 // getTowerRenderHeight рассчитывает высоту башни для рендеринга.
@@ -279,63 +338,13 @@ func getTowerRenderHeight(tower *component.Tower, renderable *component.Renderab
 	}
 }
 
-func (s *CombatSystem) handleProjectileAttack(towerID types.EntityID, tower *component.Tower, combat *component.Combat, towerDef *defs.TowerDefinition) bool {
-	// Для INTERNAL атак цель не нужна, просто считаем выстрел успешным
-	if combat.Attack.DamageType == defs.AttackInternal {
-		return true
-	}
-
-	var targets []types.EntityID
-
-	// --- НОВАЯ ЛОГИКА СИНХРОНИЗАЦИИ С ТУРЕЛЬЮ ---
-	// Если у башни есть турель и у нее есть валидная цель
-	if turret, hasTurret := s.ecs.Turrets[towerID]; hasTurret && turret.TargetID != 0 {
-		// Проверяем, находится ли цель турели в РАДИУСЕ АТАКИ (а не захвата)
-		if targetPos, exists := s.ecs.Positions[turret.TargetID]; exists {
-			towerPosPixelX, towerPosPixelY := tower.Hex.ToPixel(config.HexSize)
-			// Расстояние в пикселях в квадрате
-			distSq := (targetPos.X-towerPosPixelX)*(targetPos.X-towerPosPixelX) + (targetPos.Y-towerPosPixelY)*(targetPos.Y-towerPosPixelY)
-			// Сравниваем с радиусом атаки, переведенным в пиксели в квадрате
-			if distSq < float64(combat.Range*combat.Range*config.HexSize*config.HexSize) {
-				// Если да, то это наша единственная цель
-				targets = []types.EntityID{turret.TargetID}
-			}
-		}
-	}
-
-	// Если после проверки турели целей нет (или это башня без турели), используем старую логику
-	if len(targets) == 0 {
-		splitCount := 1
-		if combat.Attack.Params != nil && combat.Attack.Params.SplitCount != nil {
-			splitCount = *combat.Attack.Params.SplitCount
-		}
-		if splitCount <= 0 {
-			splitCount = 1
-		}
-		targets = s.findTargetsForSplitAttack(tower.Hex, combat.Range, splitCount)
-	}
-	// --- КОНЕЦ НОВОЙ ЛОГИКИ ---
-
+// handleProjectileAttack выпускает снаряды по целям; урон зависит от руды
+// источника sourceID, из которого списан выстрел.
+func (s *CombatSystem) handleProjectileAttack(towerID types.EntityID, tower *component.Tower, combat *component.Combat, towerDef *defs.TowerDefinition, targets []types.EntityID, sourceID types.EntityID) {
 	if len(targets) == 0 {
-		return false
-	}
-	powerSources := s.powerSourceFinder(towerID)
-	if len(powerSources) == 0 {
-		return false
-	}
-	var totalReserve float64
-	for _, sourceID := range powerSources {
-		if ore, ok := s.ecs.Ores[sourceID]; ok {
-			totalReserve += ore.CurrentReserve
-		}
+		return
 	}
-	chosenSourceID := powerSources[rand.Intn(len(powerSources))]
-	chosenOre := s.ecs.Ores[chosenSourceID]
-	boostMultiplier := calculateOreBoostMultiplier(chosenOre.CurrentReserve)
-	pathToSource := s.pathFinder(towerID)
-	degradationMultiplier := s.calculateLineDegradationMultiplier(pathToSource)
-	baseDamage := float64(towerDef.Combat.Damage)
-	finalDamage := int(math.Round(baseDamage * boostMultiplier * degradationMultiplier))
+	finalDamage := s.shotDamage(towerID, combat, towerDef, sourceID)
 
 	towerX, towerY := tower.Hex.ToPixel(float64(config.HexSize))
 	startPos := &component.Position{X: towerX, Y: towerY}
@@ -343,11 +352,11 @@ func (s *CombatSystem) handleProjectileAttack(towerID types.EntityID, tower *com
 	for _, enemyID := range targets {
 		s.CreateProjectile(startPos, towerID, enemyID, towerDef.Combat.Attack, finalDamage, 1.0)
 	}
-	return true
 }
 
-// findTargetsForSplitAttack находит до `count` ближайших врагов.
-func (s *CombatSystem) findTargetsForSplitAttack(startHex hexmap.Hex, rangeRadius int, count int) []types.EntityID {
+// findTargetsForSplitAttack находит до `count` ближайших врагов, которых может атаковать башня.
+func (s *CombatSystem) findTargetsForSplitAttack(tower *component.Tower, rangeRadius int, count int) []types.EntityID {
+	startHex := tower.Hex
 	type enemyWithDist struct {
 		id   types.EntityID
 		dist float64
@@ -355,7 +364,7 @@ func (s *CombatSystem) findTargetsForSplitAttack(startHex hexmap.Hex, rangeRadiu
 	var candidates []enemyWithDist
 
 	for enemyID, enemyPos := range s.ecs.Positions {
-		if _, isEnemy := s.ecs.Enemies[enemyID]; !isEnemy {
+		if !canTowerTarget(s.ecs, tower, enemyID) {
 			continue
 		}
 		if health, hasHealth := s.ecs.Healths[enemyID]; !hasHealth || health.Value <= 0 {
@@ -370,7 +379,10 @@ func (s *CombatSystem) findTargetsForSplitAttack(startHex hexmap.Hex, rangeRadiu
 	}
 
 	sort.Slice(candidates, func(i, j int) bool {
-		return candidates[i].dist < candidates[j].dist
+		if candidates[i].dist != candidates[j].dist {
+			return candidates[i].dist < candidates[j].dist
+		}
+		return candidates[i].id < candidates[j].id
 	})
 
 	numTargets := count
@@ -474,6 +486,20 @@ func (s *CombatSystem) CreateProjectile(startPos *component.Position, sourceID,
 	}
 }
 
+// oreTypeDamageMultiplier возвращает множитель урона, который тип руды источника дает
+// атаке этого типа. Батареи и руда без типа урон не меняют.
+func oreTypeDamageMultiplier(ecs *entity.ECS, sourceID types.EntityID, attackType defs.AttackDamageType) float64 {
+	ore, ok := ecs.Ores[sourceID]
+	if !ok {
+		return 1
+	}
+	oreType, ok := defs.OreTypeDefs[ore.Type]
+	if !ok {
+		return 1
+	}
+	return oreType.DamageMultiplier(attackType)
+}
+
 func calculateOreBoostMultiplier(currentReserve float64) float64 {
 	lowT := config.OreBonusLowThreshold
 	highT := config.OreBonusHighThreshold
@@ -489,6 +515,12 @@ func calculateOreBoostMultiplier(currentReserve float64) float64 {
 	return (currentReserve-lowT)*(minM-maxM)/(highT-lowT) + maxM
 }
 
+// LineDegradationMultiplier возвращает множитель урона для пути до источника энергии.
+// Нужен инспектору энергосети, чтобы показывать то же значение, что использует бой.
+func (s *CombatSystem) LineDegradationMultiplier(path []types.EntityID) float64 {
+	return s.calculateLineDegradationMultiplier(path)
+}
+
 func (s *CombatSystem) calculateLineDegradationMultiplier(path []types.EntityID) float64 {
 	if path == nil {
 		return 1.0
@@ -498,7 +530,7 @@ func (s *CombatSystem) calculateLineDegradationMultiplier(path []types.EntityID)
 	for _, towerID := range path {
 		if tower, ok := s.ecs.Towers[towerID]; ok {
 			if towerDef, ok := defs.TowerDefs[tower.DefID]; ok {
-				if towerDef.Type != defs.TowerTypeMiner && towerDef.Type != defs.TowerTypeWall {
+				if !towerDef.Type.IsEnergyRelay() && towerDef.Type != defs.TowerTypeWall {
 					attackerCount++
 				}
 			}
@@ -507,7 +539,7 @@ func (s *CombatSystem) calculateLineDegradationMultiplier(path []types.EntityID)
 	return math.Pow(config.LineDegradationFactor, float64(attackerCount))
 }
 
-func getProjectileColorByAttackType(attackType defs.AttackDamageType) rl.Color {
+func getProjectileColorByAttackType(attackType defs.AttackDamageType) color.RGBA {
 	switch attackType {
 	case defs.AttackPhysical:
 		return config.ProjectileColorPhysicalRL
@@ -620,5 +652,6 @@ func (s *CombatSystem) FindEnemiesInRadius(startHex hexmap.Hex, radius float64)
 			targets = append(targets, enemyID)
 		}
 	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
 	return targets
 }
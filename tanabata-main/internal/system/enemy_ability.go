@@ -0,0 +1,204 @@
+// internal/system/enemy_ability.go
+package system
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/pkg/hexmap"
+)
+
+// EnemyAbilitySystem обслуживает способности врагов из ability_definitions.json:
+// тикает кулдауны, применяет блинк, рывок и заряды отражения, пересчитывает ауру
+// лечения и сбрасывает стаки реактивной брони. Способности, которые срабатывают
+// при получении урона или эффекта, проверяются хелперами ниже из ApplyDamage,
+// StatusEffectSystem, MovementSystem и мест наложения эффектов.
+type EnemyAbilitySystem struct {
+	ecs  *entity.ECS
+	game MovementGameContext
+}
+
+func NewEnemyAbilitySystem(ecs *entity.ECS, game MovementGameContext) *EnemyAbilitySystem {
+	return &EnemyAbilitySystem{ecs: ecs, game: game}
+}
+
+// InitEnemyAbilities выдает только что появившемуся врагу способности его вида и волны.
+// Активные способности стартуют с кулдауном start_cooldown.
+func InitEnemyAbilities(ecs *entity.ECS, id types.EntityID, abilities []string) {
+	if len(abilities) == 0 {
+		return
+	}
+	states := make([]component.AbilityState, 0, len(abilities))
+	for _, abilityID := range abilities {
+		def := defs.AbilityDefs[abilityID]
+		states = append(states, component.AbilityState{ID: abilityID, Cooldown: def.StartCooldown})
+	}
+	ecs.EnemyAbilities[id] = &component.EnemyAbilities{Abilities: states}
+}
+
+func (s *EnemyAbilitySystem) Update(deltaTime float64) {
+	ids := entity.SortedIDs(s.ecs.Enemies)
+
+	// Аура лечения пересчитывается заново каждый кадр
+	for _, id := range ids {
+		s.ecs.Enemies[id].BonusRegen = 0
+	}
+
+	for _, id := range ids {
+		abilities, ok := s.ecs.EnemyAbilities[id]
+		if !ok {
+			continue
+		}
+		for i := range abilities.Abilities {
+			state := &abilities.Abilities[i]
+			def := defs.AbilityDefs[state.ID]
+
+			switch state.ID {
+			case defs.AbilityHealerAura:
+				s.applyHealerAura(id, def)
+			case defs.AbilityReactiveArmor:
+				// Стаки сгорают, если враг какое-то время не получал урона
+				if state.Stacks > 0 {
+					state.StackTimer -= deltaTime
+					if state.StackTimer <= 0 {
+						state.Stacks = 0
+					}
+				}
+			case defs.AbilityReflection:
+				// Заряды восстанавливаются только после того, как враг потратил все
+				if state.Stacks == 0 {
+					state.Cooldown -= deltaTime
+					if state.Cooldown <= 0 {
+						state.Stacks = def.MaxStacks
+						state.Cooldown = def.Cooldown
+					}
+				}
+			case defs.AbilityRush:
+				// Кулдаун рывка начинает тикать после окончания рывка
+				if state.Duration > 0 {
+					state.Duration -= deltaTime
+				} else if state.Cooldown > 0 {
+					state.Cooldown -= deltaTime
+				} else {
+					state.Duration = def.Duration
+					state.Cooldown = def.Cooldown
+				}
+			case defs.AbilityBlink:
+				// Волна может задать свою дальность и перезарядку блинка
+				hexes, cooldown := def.Amount, def.Cooldown
+				if state.Amount > 0 {
+					hexes = state.Amount
+				}
+				if state.Interval > 0 {
+					cooldown = state.Interval
+				}
+				state.Cooldown -= deltaTime
+				if state.Cooldown <= 0 && s.blink(id, int(hexes)) {
+					state.Cooldown = cooldown
+				}
+			}
+		}
+	}
+}
+
+// applyHealerAura добавляет реген всем остальным врагам в радиусе ауры.
+func (s *EnemyAbilitySystem) applyHealerAura(healerID types.EntityID, def defs.AbilityDefinition) {
+	healerPos, ok := s.ecs.Positions[healerID]
+	if !ok {
+		return
+	}
+	healerHex := hexmap.PixelToHex(healerPos.X, healerPos.Y, float64(config.HexSize))
+	for id, enemy := range s.ecs.Enemies {
+		if id == healerID {
+			continue
+		}
+		pos, ok := s.ecs.Positions[id]
+		if !ok {
+			continue
+		}
+		if hexmap.PixelToHex(pos.X, pos.Y, float64(config.HexSize)).Distance(healerHex) <= def.Radius {
+			enemy.BonusRegen += def.Amount
+		}
+	}
+}
+
+// blink переносит врага на несколько гексов вперед по пути. Последний гекс пути
+// враг всегда проходит сам, чтобы урон игроку считался в MovementSystem.
+func (s *EnemyAbilitySystem) blink(id types.EntityID, hexes int) bool {
+	path, hasPath := s.ecs.Paths[id]
+	pos, hasPos := s.ecs.Positions[id]
+	if !hasPath || !hasPos {
+		return false
+	}
+	advance := min(hexes, len(path.Hexes)-1-path.CurrentIndex)
+	if advance <= 0 {
+		return false
+	}
+	hexMap := s.game.GetHexMap()
+	for _, hex := range path.Hexes[path.CurrentIndex : path.CurrentIndex+advance] {
+		updateLastCheckpoint(s.ecs, hexMap, id, hex)
+	}
+	path.CurrentIndex += advance
+	pos.X, pos.Y = path.Hexes[path.CurrentIndex-1].ToPixel(float64(config.HexSize))
+	return true
+}
+
+// --- Хуки для других систем ---
+
+func enemyAbility(ecs *entity.ECS, id types.EntityID, abilityID string) *component.AbilityState {
+	abilities, ok := ecs.EnemyAbilities[id]
+	if !ok {
+		return nil
+	}
+	return abilities.Get(abilityID)
+}
+
+// isEffectImmune сообщает, что на врага нельзя накладывать замедление и яды.
+func isEffectImmune(ecs *entity.ECS, id types.EntityID) bool {
+	return enemyAbility(ecs, id, defs.AbilityBKB) != nil || enemyAbility(ecs, id, defs.AbilityEffectImmunity) != nil
+}
+
+// isMagicImmune сообщает, что враг не получает магического урона (БКБ).
+func isMagicImmune(ecs *entity.ECS, id types.EntityID) bool {
+	return enemyAbility(ecs, id, defs.AbilityBKB) != nil
+}
+
+// absorbByReflection тратит заряд отражения, если он есть; попадание при этом не наносит урона.
+func absorbByReflection(ecs *entity.ECS, id types.EntityID) bool {
+	state := enemyAbility(ecs, id, defs.AbilityReflection)
+	if state == nil || state.Stacks <= 0 {
+		return false
+	}
+	state.Stacks--
+	return true
+}
+
+// reactiveArmorBonus возвращает броню, накопленную реактивной броней.
+func reactiveArmorBonus(ecs *entity.ECS, id types.EntityID) int {
+	state := enemyAbility(ecs, id, defs.AbilityReactiveArmor)
+	if state == nil {
+		return 0
+	}
+	return int(float64(state.Stacks) * defs.AbilityDefs[defs.AbilityReactiveArmor].Amount)
+}
+
+// addReactiveArmorStack добавляет стак реактивной брони после попадания и продлевает все стаки.
+func addReactiveArmorStack(ecs *entity.ECS, id types.EntityID) {
+	state := enemyAbility(ecs, id, defs.AbilityReactiveArmor)
+	if state == nil {
+		return
+	}
+	def := defs.AbilityDefs[defs.AbilityReactiveArmor]
+	state.Stacks = min(state.Stacks+1, def.MaxStacks)
+	state.StackTimer = def.Duration
+}
+
+// abilitySpeedMultiplier возвращает множитель скорости от способностей (рывок).
+func abilitySpeedMultiplier(ecs *entity.ECS, id types.EntityID) float64 {
+	if state := enemyAbility(ecs, id, defs.AbilityRush); state != nil && state.Duration > 0 {
+		return defs.AbilityDefs[defs.AbilityRush].Amount
+	}
+	return 1.0
+}
@@ -2,25 +2,29 @@
 package system
 
 import (
+	"go-tower-defense/internal/component"
 	"go-tower-defense/internal/config"
 	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
 	"go-tower-defense/pkg/hexmap"
 )
 
 type EnvironmentalDamageSystem struct {
-	ecs *entity.ECS
+	ecs               *entity.ECS
+	powerSourceFinder func(towerID types.EntityID) []types.EntityID // Источники сети: тип руды меняет урон линий
 }
 
-func NewEnvironmentalDamageSystem(ecs *entity.ECS) *EnvironmentalDamageSystem {
-	return &EnvironmentalDamageSystem{ecs: ecs}
+func NewEnvironmentalDamageSystem(ecs *entity.ECS, finder func(towerID types.EntityID) []types.EntityID) *EnvironmentalDamageSystem {
+	return &EnvironmentalDamageSystem{ecs: ecs, powerSourceFinder: finder}
 }
 
 func (s *EnvironmentalDamageSystem) Update(deltaTime float64) {
 	// --- 1. Собираем информацию об опасных зонах ---
 
-	// Гексы с линиями между добытчиками
-	lineHexes := make(map[hexmap.Hex]bool)
+	// Гексы с линиями между добытчиками и множитель урона от типа руды сети
+	lineHexes := make(map[hexmap.Hex]float64)
+	networkMultiplier := make(map[types.EntityID]float64) // Кэш по первой башне линии
 	for _, line := range s.ecs.LineRenders {
 		tower1, ok1 := s.ecs.Towers[line.Tower1ID]
 		tower2, ok2 := s.ecs.Towers[line.Tower2ID]
@@ -30,9 +34,15 @@ func (s *EnvironmentalDamageSystem) Update(deltaTime float64) {
 		def1, ok1 := defs.TowerDefs[tower1.DefID]
 		def2, ok2 := defs.TowerDefs[tower2.DefID]
 
-		if ok1 && ok2 && def1.Type == defs.TowerTypeMiner && def2.Type == defs.TowerTypeMiner {
+		if ok1 && ok2 && def1.Type.IsEnergyRelay() && def2.Type.IsEnergyRelay() {
+			multiplier, cached := networkMultiplier[line.Tower1ID]
+			if !cached {
+				multiplier = s.lineDamageMultiplier(line.Tower1ID)
+				networkMultiplier[line.Tower1ID] = multiplier
+			}
 			for _, hex := range tower1.Hex.LineTo(tower2.Hex) {
-				lineHexes[hex] = true
+				// На пересечении линий действует самая опасная
+				lineHexes[hex] = max(lineHexes[hex], multiplier)
 			}
 		}
 	}
@@ -40,6 +50,9 @@ func (s *EnvironmentalDamageSystem) Update(deltaTime float64) {
 	// Гексы с рудой и её мощностью
 	oreHexes := make(map[hexmap.Hex]float64)
 	for _, ore := range s.ecs.Ores {
+		if ore.Depleted {
+			continue
+		}
 		hex := hexmap.PixelToHex(ore.Position.X, ore.Position.Y, config.HexSize)
 		oreHexes[hex] = ore.Power
 	}
@@ -47,6 +60,10 @@ func (s *EnvironmentalDamageSystem) Update(deltaTime float64) {
 	// --- 2. Применяем урон к врагам ---
 
 	for id, enemy := range s.ecs.Enemies {
+		// Летающие враги не касаются руды и линий
+		if enemy.Flying {
+			continue
+		}
 		pos, hasPos := s.ecs.Positions[id]
 		if !hasPos {
 			continue
@@ -68,27 +85,58 @@ func (s *EnvironmentalDamageSystem) Update(deltaTime float64) {
 				if damage < 1 {
 					damage = 1 // Минимальный урон - 1
 				}
-				ApplyDamage(s.ecs, id, damage, defs.AttackPure)
+				applyEnvironmentalDamage(s.ecs, id, damage)
 				enemy.OreDamageCooldown = 1.0 / config.OreDamageTicksPerSecond
 			}
 		}
 
 		// --- Логика урона от линий ---
-		if _, isOnLine := lineHexes[enemyHex]; isOnLine {
+		if lineMultiplier, isOnLine := lineHexes[enemyHex]; isOnLine {
 			if enemy.LineDamageCooldown > 0 {
 				enemy.LineDamageCooldown -= deltaTime
 			}
 			if enemy.LineDamageCooldown <= 0 {
 				// ОБНОВЛЕНО: Используем LineDamagePerSecond из конфига
-				damagePerSecond := config.LineDamagePerSecond
+				damagePerSecond := config.LineDamagePerSecond * lineMultiplier
 				damagePerTick := damagePerSecond / config.LineDamageTicksPerSecond
 				damage := int(damagePerTick)
 				if damage < 1 {
 					damage = 1 // Минимальный урон
 				}
-				ApplyDamage(s.ecs, id, damage, defs.AttackPure)
+				applyEnvironmentalDamage(s.ecs, id, damage)
 				enemy.LineDamageCooldown = 1.0 / config.LineDamageTicksPerSecond
 			}
 		}
 	}
 }
+
+// applyEnvironmentalDamage наносит урон руды или линий. Это не попадание башни:
+// урон снимается со здоровья напрямую, не тратит заряды отражения и не копит
+// стаки реактивной брони, иначе тики руды и линий обнуляли бы их до первого выстрела.
+func applyEnvironmentalDamage(ecs *entity.ECS, id types.EntityID, damage int) {
+	health, ok := ecs.Healths[id]
+	if !ok {
+		return
+	}
+	health.Value = max(health.Value-damage, 0)
+	ecs.DamageFlashes[id] = &component.DamageFlashComponent{Timer: config.DamageFlashDuration}
+}
+
+// lineDamageMultiplier возвращает множитель урона линий сети башни: наибольший
+// среди типов руды, которые сейчас питают сеть. Без источников урон не меняется.
+func (s *EnvironmentalDamageSystem) lineDamageMultiplier(towerID types.EntityID) float64 {
+	multiplier := 1.0
+	if s.powerSourceFinder == nil {
+		return multiplier
+	}
+	for _, sourceID := range s.powerSourceFinder(towerID) {
+		ore, ok := s.ecs.Ores[sourceID]
+		if !ok {
+			continue
+		}
+		if oreType, ok := defs.OreTypeDefs[ore.Type]; ok {
+			multiplier = max(multiplier, oreType.LineMultiplier())
+		}
+	}
+	return multiplier
+}
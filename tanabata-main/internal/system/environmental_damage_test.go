@@ -0,0 +1,53 @@
+// internal/system/environmental_damage_test.go
+package system
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/pkg/hexmap"
+	"testing"
+)
+
+// TestLineDamageKeepsReflectionCharges ставит врага с отражением и реактивной
+// броней на линию между двумя добытчиками: линия должна снимать здоровье, но не
+// тратить заряды отражения и не копить стаки брони.
+func TestLineDamageKeepsReflectionCharges(t *testing.T) {
+	const dt = 1.0 / 60
+	if err := defs.LoadAll("../../assets/data"); err != nil {
+		t.Fatalf("load definitions: %v", err)
+	}
+	ecs := entity.NewECS()
+	from, to := hexmap.Hex{Q: 0, R: 0}, hexmap.Hex{Q: 3, R: 0}
+	tower1, tower2 := ecs.NewEntity(), ecs.NewEntity()
+	ecs.Towers[tower1] = &component.Tower{DefID: "TOWER_MINER", Hex: from}
+	ecs.Towers[tower2] = &component.Tower{DefID: "TOWER_MINER", Hex: to}
+	ecs.LineRenders[ecs.NewEntity()] = &component.LineRender{Tower1ID: tower1, Tower2ID: tower2}
+
+	id := ecs.NewEntity()
+	x, y := hexmap.Hex{Q: 1, R: 0}.ToPixel(float64(config.HexSize))
+	ecs.Positions[id] = &component.Position{X: x, Y: y}
+	ecs.Enemies[id] = &component.Enemy{}
+	ecs.Healths[id] = &component.Health{Value: 100000}
+	charges := defs.AbilityDefs[defs.AbilityReflection].MaxStacks
+	ecs.EnemyAbilities[id] = &component.EnemyAbilities{Abilities: []component.AbilityState{
+		{ID: defs.AbilityReflection, Stacks: charges},
+		{ID: defs.AbilityReactiveArmor},
+	}}
+
+	system := NewEnvironmentalDamageSystem(ecs, nil)
+	for tick := 0; tick < 5*60; tick++ {
+		system.Update(dt)
+	}
+
+	if ecs.Healths[id].Value >= 100000 {
+		t.Fatalf("the line dealt no damage")
+	}
+	if got := enemyAbility(ecs, id, defs.AbilityReflection).Stacks; got != charges {
+		t.Errorf("reflection charges = %d after standing on a line, want %d", got, charges)
+	}
+	if got := enemyAbility(ecs, id, defs.AbilityReactiveArmor).Stacks; got != 0 {
+		t.Errorf("reactive armor stacks = %d after standing on a line, want 0", got)
+	}
+}
@@ -18,6 +18,7 @@ type MovementGameContext interface {
 	GetClearedCheckpoints() map[hexmap.Hex]bool
 	GetEnemies() map[types.EntityID]*component.Enemy
 	IsGodMode() bool
+	RouteFlows() *hexmap.RouteFlows
 }
 
 // MovementSystem обновляет позиции сущностей
@@ -57,6 +58,7 @@ func (s *MovementSystem) Update(deltaTime float64) {
 			continue
 		}
 
+		recoverRoute(s.ecs, s.game, id, pos, path)
 		targetHex := path.Hexes[path.CurrentIndex]
 		tx, ty := targetHex.ToPixel(float64(config.HexSize))
 
@@ -80,6 +82,9 @@ func (s *MovementSystem) Update(deltaTime float64) {
 			}
 		}
 
+		currentSpeed *= abilitySpeedMultiplier(s.ecs, id)
+		currentSpeed *= terrainSpeedMultiplier(s.ecs, s.game.GetHexMap(), id, pos)
+
 		moveDistance := currentSpeed * deltaTime
 
 		if dist <= moveDistance {
@@ -106,20 +111,61 @@ func (s *MovementSystem) Update(deltaTime float64) {
 			}
 
 			// Если не конец пути, проверяем чекпоинты
-			hexMap := s.game.GetHexMap()
-			// Обновляем targetHex, так как CurrentIndex мог измениться
-			newTargetHex := path.Hexes[path.CurrentIndex-1]
-			for i, cpHex := range hexMap.Checkpoints {
-				if newTargetHex == cpHex {
-					if enemy, ok := s.ecs.Enemies[id]; ok {
-						enemy.LastCheckpointIndex = i
-					}
-					break
-				}
-			}
+			updateLastCheckpoint(s.ecs, s.game.GetHexMap(), id, path.Hexes[path.CurrentIndex-1])
 		} else {
 			pos.X += (dx / dist) * moveDistance
 			pos.Y += (dy / dist) * moveDistance
 		}
 	}
-}
\ No newline at end of file
+}
+
+// recoverRoute возвращает на маршрут наземного врага, сбитого с пути: если он
+// стоит не на гексе, с которого идет, и не на том, к которому идет, остаток
+// пути заново строится по полям потока от его гекса с текущего участка маршрута.
+// Пройденная часть пути сохраняется, чтобы CurrentIndex продолжал расти.
+func recoverRoute(ecs *entity.ECS, game MovementGameContext, id types.EntityID, pos *component.Position, path *component.Path) {
+	enemy, ok := ecs.Enemies[id]
+	if !ok || enemy.Flying {
+		return
+	}
+	if path.CurrentIndex == 0 {
+		return // Враг еще идет к началу пути от точки появления
+	}
+	hex := hexmap.PixelToHex(pos.X, pos.Y, float64(config.HexSize))
+	if hex == path.Hexes[path.CurrentIndex] || hex == path.Hexes[path.CurrentIndex-1] {
+		return
+	}
+	rest := game.RouteFlows().Gate(enemy.Gate).RouteFrom(game.GetHexMap(), hex, enemy.LastCheckpointIndex+1)
+	if rest == nil {
+		return // Маршрут перекрыт: враг идет по старому пути
+	}
+	// Путь общий для врагов группы, поэтому собирается новый срез
+	hexes := append(append(make([]hexmap.Hex, 0, path.CurrentIndex+len(rest)), path.Hexes[:path.CurrentIndex]...), rest...)
+	path.Hexes = hexes
+	path.CurrentIndex += min(1, len(rest)-1)
+}
+
+// terrainSpeedMultiplier возвращает множитель скорости от местности под врагом.
+// Летающих врагов местность не замедляет и не ускоряет.
+func terrainSpeedMultiplier(ecs *entity.ECS, hexMap *hexmap.HexMap, id types.EntityID, pos *component.Position) float64 {
+	if enemy, ok := ecs.Enemies[id]; !ok || enemy.Flying {
+		return 1
+	}
+	hex := hexmap.PixelToHex(pos.X, pos.Y, float64(config.HexSize))
+	return hexMap.TerrainAt(hex).Info().SpeedMultiplier
+}
+
+// updateLastCheckpoint запоминает чекпоинт, если враг только что прошел гекс
+// следующего по порядку чекпоинта маршрута своего входа. Если путь к чекпоинту
+// проходит через один из следующих, тот не засчитывается раньше времени, и
+// участок маршрута не перескакивает вперед.
+func updateLastCheckpoint(ecs *entity.ECS, hexMap *hexmap.HexMap, id types.EntityID, hex hexmap.Hex) {
+	enemy, ok := ecs.Enemies[id]
+	if !ok {
+		return
+	}
+	checkpoints := hexMap.Gate(enemy.Gate).Checkpoints
+	if next := enemy.LastCheckpointIndex + 1; next < len(checkpoints) && hex == checkpoints[next] {
+		enemy.LastCheckpointIndex = next
+	}
+}
@@ -0,0 +1,144 @@
+// internal/system/movement_test.go
+package system
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/pkg/hexmap"
+	"slices"
+	"testing"
+)
+
+// movementTestGame — минимальный MovementGameContext: карта и ее поля потока.
+type movementTestGame struct {
+	ecs    *entity.ECS
+	hexMap *hexmap.HexMap
+	flows  *hexmap.RouteFlows
+}
+
+func (g *movementTestGame) GetHexMap() *hexmap.HexMap                       { return g.hexMap }
+func (g *movementTestGame) GetClearedCheckpoints() map[hexmap.Hex]bool      { return nil }
+func (g *movementTestGame) GetEnemies() map[types.EntityID]*component.Enemy { return g.ecs.Enemies }
+func (g *movementTestGame) IsGodMode() bool                                 { return true }
+func (g *movementTestGame) RouteFlows() *hexmap.RouteFlows                  { return g.flows }
+
+// newMovementTestGame строит открытую карту радиуса 4 с одним входом: слева
+// направо через чекпоинт в верхней части карты.
+func newMovementTestGame() *movementTestGame {
+	const radius = 4
+	hm := &hexmap.HexMap{
+		Tiles:       make(map[hexmap.Hex]hexmap.Tile),
+		Radius:      radius,
+		Entry:       hexmap.Hex{Q: -4, R: 2},
+		Exit:        hexmap.Hex{Q: 4, R: -2},
+		Checkpoints: []hexmap.Hex{{Q: 1, R: -4}},
+	}
+	for q := -radius; q <= radius; q++ {
+		for r := -radius; r <= radius; r++ {
+			if hex := (hexmap.Hex{Q: q, R: r}); hex.Distance(hexmap.Hex{}) <= radius {
+				hm.Tiles[hex] = hexmap.Tile{Passable: true, CanPlaceTower: true}
+			}
+		}
+	}
+	ecs := entity.NewECS()
+	ecs.PlayerState[ecs.NewEntity()] = &component.PlayerStateComponent{Health: 100}
+	return &movementTestGame{ecs: ecs, hexMap: hm, flows: hexmap.NewRouteFlows(hm)}
+}
+
+// spawn ставит наземного врага на начало маршрута входа.
+func (g *movementTestGame) spawn() types.EntityID {
+	route := g.flows.Gate(0).Route(g.hexMap)
+	id := g.ecs.NewEntity()
+	x, y := route[0].ToPixel(float64(config.HexSize))
+	g.ecs.Positions[id] = &component.Position{X: x, Y: y}
+	g.ecs.Velocities[id] = &component.Velocity{Speed: 80}
+	g.ecs.Paths[id] = &component.Path{Hexes: route}
+	g.ecs.Enemies[id] = &component.Enemy{LastCheckpointIndex: -1}
+	return id
+}
+
+// displace переносит врага на гекс рядом с ним, не лежащий на его пути, —
+// самый дальний от чекпоинта, чтобы путь к выходу не шел через чекпоинт сам собой.
+func (g *movementTestGame) displace(t *testing.T, id types.EntityID) hexmap.Hex {
+	t.Helper()
+	pos, path := g.ecs.Positions[id], g.ecs.Paths[id]
+	from := hexmap.PixelToHex(pos.X, pos.Y, float64(config.HexSize))
+	checkpoint := g.hexMap.Checkpoints[0]
+	best, found := hexmap.Hex{}, false
+	for _, hex := range g.hexMap.GetHexesInRange(from, 2) {
+		if !g.hexMap.IsPassable(hex) || slices.Contains(path.Hexes, hex) || g.hexMap.IsGate(hex) {
+			continue
+		}
+		if !found || hex.Distance(checkpoint) > best.Distance(checkpoint) {
+			best, found = hex, true
+		}
+	}
+	if !found {
+		t.Fatalf("no free hex near %v", from)
+	}
+	pos.X, pos.Y = best.ToPixel(float64(config.HexSize))
+	return best
+}
+
+// TestRecoverRouteRejoinsCurrentSegment сбивает врага с пути до и после
+// чекпоинта и проверяет, что он возвращается на свой участок маршрута: до
+// чекпоинта — через чекпоинт, после — прямо к выходу, и доходит до конца.
+func TestRecoverRouteRejoinsCurrentSegment(t *testing.T) {
+	const dt = 1.0 / 60
+	for _, tc := range []struct {
+		name           string
+		passCheckpoint bool
+	}{
+		{"before checkpoint", false},
+		{"after checkpoint", true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			g := newMovementTestGame()
+			movement := NewMovementSystem(g.ecs, g, nil)
+			id := g.spawn()
+			enemy, path := g.ecs.Enemies[id], g.ecs.Paths[id]
+			checkpoint := g.hexMap.Checkpoints[0]
+
+			// Враг идет до гекса перед чекпоинтом или на два гекса дальше него
+			cpIndex := slices.Index(path.Hexes, checkpoint)
+			stopAt := cpIndex - 1
+			if tc.passCheckpoint {
+				stopAt = cpIndex + 2
+			}
+			for path.CurrentIndex < stopAt {
+				movement.Update(dt)
+			}
+			if passed := enemy.LastCheckpointIndex == 0; passed != tc.passCheckpoint {
+				t.Fatalf("LastCheckpointIndex = %d before the displacement", enemy.LastCheckpointIndex)
+			}
+
+			hex := g.displace(t, id)
+			passed := path.CurrentIndex
+			movement.Update(dt)
+
+			segment := enemy.LastCheckpointIndex + 1
+			want := g.flows.Gate(0).RouteFrom(g.hexMap, hex, segment)
+			if got := path.Hexes[passed:]; !slices.Equal(got, want) {
+				t.Fatalf("route after displacement from %v\n got: %v\nwant: %v", hex, got, want)
+			}
+			if rest := path.Hexes[passed:]; slices.Contains(rest, checkpoint) == tc.passCheckpoint {
+				t.Fatalf("route after displacement %v: checkpoint %v visited = %v", rest, checkpoint, !tc.passCheckpoint)
+			}
+
+			for steps := 0; !enemy.ReachedEnd; steps++ {
+				if steps > 10000 {
+					t.Fatalf("enemy did not reach the exit, stuck at index %d of %v", path.CurrentIndex, path.Hexes)
+				}
+				movement.Update(dt)
+			}
+			if enemy.LastCheckpointIndex != 0 {
+				t.Fatalf("LastCheckpointIndex = %d at the exit, want 0", enemy.LastCheckpointIndex)
+			}
+			if last := path.Hexes[len(path.Hexes)-1]; last != g.hexMap.Exit {
+				t.Fatalf("path ends at %v, want exit %v", last, g.hexMap.Exit)
+			}
+		})
+	}
+}
@@ -5,14 +5,14 @@ import (
 	"fmt"
 	"go-tower-defense/internal/component"
 	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/entity"
 	"go-tower-defense/internal/event"
 	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
 	"image/color"
 	"math"
-	"math/rand"
-	"time"
 )
 
 // EnergyCircle defines a circular area of energy.
@@ -39,25 +39,22 @@ type oreSystemListener struct {
 
 // OnEvent обрабатывает входящие события.
 func (l *oreSystemListener) OnEvent(e event.Event) {
-	if e.Type == event.OreConsumed {
+	switch e.Type {
+	case event.OreConsumed:
 		l.system.handleOreConsumption(e)
+	case event.BuildPhaseStarted:
+		l.system.RegenerateVeins()
 	}
 }
 
-// handleOreConsumption вычитает руду из указанного источника.
+// handleOreConsumption вычитает руду из указанного источника (жилы или батареи).
 func (s *OreSystem) handleOreConsumption(e event.Event) {
 	data, ok := e.Data.(OreConsumptionData)
 	if !ok {
 		return
 	}
 
-	if ore, exists := s.ecs.Ores[data.SourceID]; exists {
-		if ore.CurrentReserve >= data.Amount {
-			ore.CurrentReserve -= data.Amount
-		} else {
-			ore.CurrentReserve = 0
-		}
-	}
+	spendFromPowerSource(s.ecs, data.SourceID, data.Amount)
 }
 
 // --- КОНЕЦ НОВОГО КОДА ---
@@ -74,24 +71,25 @@ func NewOreSystem(ecs *entity.ECS, dispatcher *event.Dispatcher) *OreSystem {
 	// Создаем и подписываем слушателя
 	listener := &oreSystemListener{system: s}
 	s.eventDispatcher.Subscribe(event.OreConsumed, listener)
+	s.eventDispatcher.Subscribe(event.BuildPhaseStarted, listener)
 	// --- КОНЕЦ НОВОГО КОДА ---
 	return s
 }
 
 // Update is called every frame to update the state of ore components.
 func (s *OreSystem) Update() {
-	// Сначала обновим визуал и соберем ID для удаления
-	idsToRemove := make([]types.EntityID, 0)
-	for id, ore := range s.ecs.Ores {
-		if ore.MaxReserve <= 0 {
-			continue // Пропускаем руду без запаса
+	for _, id := range entity.SortedIDs(s.ecs.Ores) {
+		ore := s.ecs.Ores[id]
+		if ore.MaxReserve <= 0 || ore.Depleted {
+			continue // Пропускаем руду без запаса и уже истощенную
 		}
 
-		// Если запас иссяк (или почти иссяк), сообщаем об этом и помечаем на удаление
+		// Если запас иссяк (или почти иссяк), сообщаем об этом один раз. Сущность остается:
+		// жила может восстановиться в начале фазы строительства (RegenerateVeins).
 		if ore.CurrentReserve < config.OreDepletionThreshold {
-			// log.Printf("[Log] OreSystem: Ore %d depleted (Reserve: %.2f). Dispatching event.\n", id, ore.CurrentReserve)
+			ore.Depleted = true
+			delete(s.ecs.Texts, id)
 			s.eventDispatcher.Dispatch(event.Event{Type: event.OreDepleted, Data: id})
-			idsToRemove = append(idsToRemove, id)
 			continue
 		}
 
@@ -128,24 +126,78 @@ func (s *OreSystem) Update() {
 			}
 		}
 	}
+}
 
-	// Теперь удаляем помеченные сущности
-	for _, id := range idsToRemove {
-		delete(s.ecs.Ores, id)
-		delete(s.ecs.Positions, id)
-		delete(s.ecs.Texts, id)
-		// Примечание: Renderable для руды не используется, поэтому его не удаляем.
+// RegenerateVeins восстанавливает запас руды по правилам defs.OreVeinDefs. Вызывается
+// в начале фазы строительства. Истощенный гекс, набравший запас, снова становится
+// источником, о чем сообщает событие OreRestored.
+func (s *OreSystem) RegenerateVeins() {
+	busy := s.busyVeins()
+	for _, id := range entity.SortedIDs(s.ecs.Ores) {
+		ore := s.ecs.Ores[id]
+		refill := s.regenAmount(ore, busy)
+		if refill <= 0 {
+			continue
+		}
+		ore.CurrentReserve += refill
+		if ore.Depleted && ore.CurrentReserve >= config.OreDepletionThreshold {
+			ore.Depleted = false
+			s.eventDispatcher.Dispatch(event.Event{Type: event.OreRestored, Data: id})
+		}
 	}
 }
 
-func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap) {
-	rand.Seed(time.Now().UnixNano())
+// ProjectedRefill возвращает, сколько руды получит гекс в начале следующей фазы
+// строительства, если добытчики на жилах останутся как сейчас.
+func (s *OreSystem) ProjectedRefill(id types.EntityID) float64 {
+	ore, ok := s.ecs.Ores[id]
+	if !ok {
+		return 0
+	}
+	return s.regenAmount(ore, s.busyVeins())
+}
 
-	// Все возможные гексы
-	allHexes := make([]hexmap.Hex, 0, len(hexMap.Tiles))
-	for hex := range hexMap.Tiles {
-		allHexes = append(allHexes, hex)
+// regenAmount считает прирост запаса гекса с учетом предела жилы.
+func (s *OreSystem) regenAmount(ore *component.Ore, busy map[int]bool) float64 {
+	rule, ok := defs.OreVeinDefs[ore.Vein]
+	if !ok || rule.RegenPerWave <= 0 || ore.MaxReserve <= 0 {
+		return 0
+	}
+	if (rule.IdleOnly && busy[ore.Vein]) || (ore.Depleted && !rule.Revive) {
+		return 0
+	}
+	limit := ore.MaxReserve * rule.RegenCap()
+	if ore.CurrentReserve >= limit {
+		return 0
+	}
+	return math.Min(ore.MaxReserve*rule.RegenPerWave, limit-ore.CurrentReserve)
+}
+
+// busyVeins возвращает жилы, из которых сейчас добывают: на непустом гексе стоит
+// включенный добытчик или батарея.
+func (s *OreSystem) busyVeins() map[int]bool {
+	veinAt := make(map[hexmap.Hex]int)
+	for _, ore := range s.ecs.Ores {
+		if !ore.Depleted {
+			veinAt[ore.Hex] = ore.Vein
+		}
+	}
+	busy := make(map[int]bool)
+	for _, tower := range s.ecs.Towers {
+		towerDef, ok := defs.TowerDefs[tower.DefID]
+		if !ok || !towerDef.Type.IsEnergyRelay() || tower.IsManuallyDisabled {
+			continue
+		}
+		if vein, onOre := veinAt[tower.Hex]; onOre {
+			busy[vein] = true
+		}
 	}
+	return busy
+}
+
+func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap, rng *utils.PRNGService) {
+	// Все возможные гексы
+	allHexes := hexMap.SortedHexes()
 
 	// Получаем гексы на границе (в радиусе 3 от края)
 	borderHexes := hexMap.GetBorderHexes(3)
@@ -155,14 +207,11 @@ func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap) {
 
 	// Проверка валидности центра жилы
 	isValidCenter1 := func(hex hexmap.Hex) bool {
-		if hex == hexMap.Entry || hex == hexMap.Exit || hexMap.IsCheckpoint(hex) {
-			return false
-		}
-		if hexMap.Entry.Distance(hex) < 2 || hexMap.Exit.Distance(hex) < 2 {
+		if hexMap.IsGate(hex) || hexMap.IsCheckpoint(hex) {
 			return false
 		}
-		for _, cp := range hexMap.Checkpoints {
-			if cp.Distance(hex) < 1 {
+		for _, gate := range hexMap.Gates() {
+			if gate.Entry.Distance(hex) < 2 || gate.Exit.Distance(hex) < 2 {
 				return false
 			}
 		}
@@ -176,14 +225,11 @@ func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap) {
 	}
 
 	isValidCenter2 := func(hex hexmap.Hex) bool {
-		if hex == hexMap.Entry || hex == hexMap.Exit || hexMap.IsCheckpoint(hex) {
+		if hexMap.IsGate(hex) || hexMap.IsCheckpoint(hex) {
 			return false
 		}
-		if hexMap.Entry.Distance(hex) < 2 || hexMap.Exit.Distance(hex) < 2 {
-			return false
-		}
-		for _, cp := range hexMap.Checkpoints {
-			if cp.Distance(hex) < 1 {
+		for _, gate := range hexMap.Gates() {
+			if gate.Entry.Distance(hex) < 2 || gate.Exit.Distance(hex) < 2 {
 				return false
 			}
 		}
@@ -199,7 +245,7 @@ func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap) {
 	// Выбор двух центров
 	var centers []hexmap.Hex
 	for len(centers) < 1 {
-		candidate := allHexes[rand.Intn(len(allHexes))]
+		candidate := allHexes[rng.Intn(len(allHexes))]
 		if isValidCenter1(candidate) {
 			if len(centers) == 0 || centers[0].Distance(candidate) <= 6 {
 				centers = append(centers, candidate)
@@ -207,7 +253,7 @@ func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap) {
 		}
 	}
 	for len(centers) < 2 {
-		candidate := allHexes[rand.Intn(len(allHexes))]
+		candidate := allHexes[rng.Intn(len(allHexes))]
 		if isValidCenter2(candidate) {
 			if len(centers) == 0 || centers[0].Distance(candidate) <= 10 {
 				centers = append(centers, candidate)
@@ -223,8 +269,8 @@ func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap) {
 
 	// Генерация кружков для каждой жилы
 	for _, area := range veinAreas {
-		totalPower := 100.0 + float64(rand.Intn(21)) // 100-120%
-		circles := s.generateEnergyCircles(area, totalPower, config.HexSize)
+		totalPower := 100.0 + float64(rng.Intn(21)) // 100-120%
+		circles := s.generateEnergyCircles(rng, area, totalPower, config.HexSize)
 		s.EnergyCircles = append(s.EnergyCircles, circles...)
 
 		// Привязка энергии к гексам, исключая чекпоинты
@@ -243,18 +289,18 @@ func (s *OreSystem) GenerateOres(hexMap *hexmap.HexMap) {
 	}
 }
 
-func (s *OreSystem) generateEnergyCircles(area []hexmap.Hex, totalPower float64, hexSize float64) []EnergyCircle {
+func (s *OreSystem) generateEnergyCircles(rng *utils.PRNGService, area []hexmap.Hex, totalPower float64, hexSize float64) []EnergyCircle {
 	var circles []EnergyCircle
 	remainingPower := totalPower
 
 	for remainingPower > 0 {
-		hex := area[rand.Intn(len(area))]
+		hex := area[rng.Intn(len(area))]
 		cx, cy := hex.ToPixel(hexSize)
-		cx += float64(config.ScreenWidth)/2 + (rand.Float64()*2-1)*hexSize/2
-		cy += float64(config.ScreenHeight)/2 + (rand.Float64()*2-1)*hexSize/2
+		cx += float64(config.ScreenWidth)/2 + (rng.Float64()*2-1)*hexSize/2
+		cy += float64(config.ScreenHeight)/2 + (rng.Float64()*2-1)*hexSize/2
 
 		// Ограничиваем энергию до 5-20% для большего количества кружков
-		power := float64((rand.Intn(4) + 1) * 5) // 5, 10, 15, 20%
+		power := float64((rng.Intn(4) + 1) * 5) // 5, 10, 15, 20%
 		if power > remainingPower {
 			power = remainingPower
 		}
@@ -291,7 +337,14 @@ func (s *OreSystem) getHexesInCircle(hexMap *hexmap.HexMap, cx, cy, radius float
 }
 
 func (s *OreSystem) CreateEntities(ecs *entity.ECS) {
-	for hex, power := range s.EnergyVeins {
+	veinHexes := make([]hexmap.Hex, 0, len(s.EnergyVeins))
+	for hex := range s.EnergyVeins {
+		veinHexes = append(veinHexes, hex)
+	}
+	hexmap.SortHexes(veinHexes)
+
+	for _, hex := range veinHexes {
+		power := s.EnergyVeins[hex]
 		id := ecs.NewEntity()
 		px, py := hex.ToPixel(config.HexSize)
 		px += float64(config.ScreenWidth) / 2
@@ -0,0 +1,117 @@
+// internal/system/ore_draw.go
+package system
+
+import (
+	"go-tower-defense/internal/component"
+	"go-tower-defense/internal/entity"
+	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
+)
+
+// OreDrawer выбирает, из какого источника сети потребитель списывает руду.
+// Политика берется у башни, а если у башни стоит OreDrawInherit — из GameState.
+// Общий для CombatSystem, VolcanoSystem и BeaconSystem.
+type OreDrawer struct {
+	ecs        *entity.ECS
+	sourceHops func(towerID types.EntityID) map[types.EntityID]int
+}
+
+func NewOreDrawer(ecs *entity.ECS, sourceHops func(towerID types.EntityID) map[types.EntityID]int) *OreDrawer {
+	return &OreDrawer{
+		ecs:        ecs,
+		sourceHops: sourceHops,
+	}
+}
+
+// PolicyFor возвращает действующую политику башни.
+func (d *OreDrawer) PolicyFor(towerID types.EntityID) component.OreDrawPolicy {
+	if tower, ok := d.ecs.Towers[towerID]; ok && tower.OreDrawPolicy != component.OreDrawInherit {
+		return tower.OreDrawPolicy
+	}
+	if d.ecs.GameState != nil && d.ecs.GameState.OreDrawPolicy != component.OreDrawInherit {
+		return d.ecs.GameState.OreDrawPolicy
+	}
+	return component.OreDrawRandom
+}
+
+// Draw выбирает источник для списания руды из sources (отсортированы по ID).
+// Пустые источники пропускаются; если непустых нет, возвращает false.
+// rng — поток системы-потребителя, чтобы случайные политики не сдвигали чужие потоки.
+func (d *OreDrawer) Draw(towerID types.EntityID, sources []types.EntityID, rng *utils.PRNGService) (types.EntityID, bool) {
+	available := make([]types.EntityID, 0, len(sources))
+	for _, sourceID := range sources {
+		if powerSourceReserve(d.ecs, sourceID) > 0 {
+			available = append(available, sourceID)
+		}
+	}
+	if len(available) == 0 {
+		return 0, false
+	}
+
+	switch d.PolicyFor(towerID) {
+	case component.OreDrawNearest:
+		return d.drawNearest(towerID, available), true
+	case component.OreDrawLargest:
+		return d.drawLargest(available), true
+	case component.OreDrawProportional:
+		return d.drawProportional(available, rng), true
+	case component.OreDrawRoundRobin:
+		return d.drawRoundRobin(towerID, available), true
+	default:
+		return available[rng.Intn(len(available))], true
+	}
+}
+
+// drawNearest берет источник с наименьшим числом переходов по линиям; при равенстве — меньший ID.
+func (d *OreDrawer) drawNearest(towerID types.EntityID, available []types.EntityID) types.EntityID {
+	hops := d.sourceHops(towerID)
+	best := available[0]
+	bestHops, ok := hops[best]
+	for _, sourceID := range available[1:] {
+		h, known := hops[sourceID]
+		if known && (!ok || h < bestHops) {
+			best, bestHops, ok = sourceID, h, true
+		}
+	}
+	return best
+}
+
+// drawLargest берет источник с наибольшим запасом; при равенстве — меньший ID.
+func (d *OreDrawer) drawLargest(available []types.EntityID) types.EntityID {
+	best := available[0]
+	bestReserve := powerSourceReserve(d.ecs, best)
+	for _, sourceID := range available[1:] {
+		if reserve := powerSourceReserve(d.ecs, sourceID); reserve > bestReserve {
+			best, bestReserve = sourceID, reserve
+		}
+	}
+	return best
+}
+
+// drawProportional выбирает источник случайно с весом, равным его запасу,
+// так что жилы истощаются примерно одновременно.
+func (d *OreDrawer) drawProportional(available []types.EntityID, rng *utils.PRNGService) types.EntityID {
+	var total float64
+	for _, sourceID := range available {
+		total += powerSourceReserve(d.ecs, sourceID)
+	}
+	roll := rng.Float64() * total
+	for _, sourceID := range available {
+		roll -= powerSourceReserve(d.ecs, sourceID)
+		if roll < 0 {
+			return sourceID
+		}
+	}
+	return available[len(available)-1]
+}
+
+// drawRoundRobin перебирает источники по очереди; позиция хранится в башне и попадает в сохранение.
+func (d *OreDrawer) drawRoundRobin(towerID types.EntityID, available []types.EntityID) types.EntityID {
+	tower, ok := d.ecs.Towers[towerID]
+	if !ok {
+		return available[0]
+	}
+	chosen := available[tower.OreDrawCursor%len(available)]
+	tower.OreDrawCursor = (tower.OreDrawCursor + 1) % len(available)
+	return chosen
+}
@@ -8,6 +8,7 @@ import (
 	"go-tower-defense/internal/entity"
 	"go-tower-defense/internal/event"
 	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
 	"math"
 )
@@ -18,14 +19,16 @@ type ProjectileSystem struct {
 	eventDispatcher *event.Dispatcher
 	combatSystem    *CombatSystem // Добавляем ссылку на CombatSystem для доступа к predictTargetPosition
 	towerDefs       map[string]*defs.TowerDefinition
+	rng             *utils.PRNGService // Для бросков уклонения
 }
 
-func NewProjectileSystem(ecs *entity.ECS, eventDispatcher *event.Dispatcher, combatSystem *CombatSystem, towerDefs map[string]*defs.TowerDefinition) *ProjectileSystem {
+func NewProjectileSystem(ecs *entity.ECS, eventDispatcher *event.Dispatcher, combatSystem *CombatSystem, towerDefs map[string]*defs.TowerDefinition, rng *utils.PRNGService) *ProjectileSystem {
 	return &ProjectileSystem{
 		ecs:             ecs,
 		eventDispatcher: eventDispatcher,
 		combatSystem:    combatSystem,
 		towerDefs:       towerDefs,
+		rng:             rng,
 	}
 }
 
@@ -37,8 +40,8 @@ func (s *ProjectileSystem) OnEvent(e event.Event) {
 			return
 		}
 		// Проходим по всем снарядам и удаляем те, что летят в мёртвого врага
-		for projID, proj := range s.ecs.Projectiles {
-			if proj.TargetID == deadEnemyID {
+		for _, projID := range entity.SortedIDs(s.ecs.Projectiles) {
+			if s.ecs.Projectiles[projID].TargetID == deadEnemyID {
 				s.removeProjectile(projID)
 			}
 		}
@@ -46,7 +49,11 @@ func (s *ProjectileSystem) OnEvent(e event.Event) {
 }
 
 func (s *ProjectileSystem) Update(deltaTime float64) {
-	for id, proj := range s.ecs.Projectiles {
+	for _, id := range entity.SortedIDs(s.ecs.Projectiles) {
+		proj, ok := s.ecs.Projectiles[id]
+		if !ok {
+			continue // Снаряд удален раньше в этом же кадре
+		}
 		proj.Age += deltaTime // Увеличиваем возраст снаряда
 
 		pos := s.ecs.Positions[id]
@@ -117,6 +124,14 @@ func (s *ProjectileSystem) hitTarget(projectileID types.EntityID, proj *componen
 		return
 	}
 
+	// Враг с шансом уклонения может увернуться: снаряд пропадает без урона и эффектов
+	if enemy, isEnemy := s.ecs.Enemies[proj.TargetID]; isEnemy && enemy.EvasionChance > 0 {
+		if s.rng.Float64() < enemy.EvasionChance {
+			s.removeProjectile(projectileID)
+			return
+		}
+	}
+
 	// Применяем эффекты и урон
 	s.applyEffectsAndDamage(proj)
 
@@ -143,14 +158,15 @@ func (s *ProjectileSystem) applyEffectsAndDamage(proj *component.Projectile) {
 		return
 	}
 
-	// Стандартные эффекты
-	if proj.SlowsTarget {
+	// Стандартные эффекты; враги с иммунитетом (БКБ) их не получают
+	immune := isEffectImmune(s.ecs, proj.TargetID)
+	if proj.SlowsTarget && !immune {
 		s.ecs.SlowEffects[proj.TargetID] = &component.SlowEffect{
 			Timer:      proj.SlowDuration,
 			SlowFactor: proj.SlowFactor,
 		}
 	}
-	if proj.AppliesPoison {
+	if proj.AppliesPoison && !immune {
 		s.ecs.PoisonEffects[proj.TargetID] = &component.PoisonEffect{
 			Timer:        proj.PoisonDuration,
 			DamagePerSec: proj.PoisonDPS,
@@ -159,7 +175,7 @@ func (s *ProjectileSystem) applyEffectsAndDamage(proj *component.Projectile) {
 	}
 
 	// Новый эффект Jade Poison
-	if towerDef.Combat.Attack.Params != nil && towerDef.Combat.Attack.Params.Effect == "JADE_POISON" {
+	if towerDef.Combat.Attack.Params != nil && towerDef.Combat.Attack.Params.Effect == "JADE_POISON" && !immune {
 		container, exists := s.ecs.JadePoisonContainers[proj.TargetID]
 		if !exists {
 			container = &component.JadePoisonContainer{
@@ -199,6 +215,9 @@ func (s *ProjectileSystem) handleImpactBurst(proj *component.Projectile, impactP
 		if enemyID == proj.TargetID {
 			continue // Не стреляем в первоначальную цель
 		}
+		if tower, ok := s.ecs.Towers[sourceID]; ok && !canTowerTarget(s.ecs, tower, enemyID) {
+			continue
+		}
 		if targetsHit >= proj.ImpactBurstTargetCount {
 			break
 		}
@@ -231,4 +250,4 @@ func (s *ProjectileSystem) updateTargetVisuals(targetID types.EntityID) {
 		newRadius := (0.6 + 0.4*(healthf/health_m)) * float32(config.HexSize*def.Visuals.RadiusFactor)
 		renderable.Radius = newRadius
 	}
-}
\ No newline at end of file
+}
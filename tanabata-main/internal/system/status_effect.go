@@ -20,6 +20,11 @@ func NewStatusEffectSystem(ecs *entity.ECS) *StatusEffectSystem {
 func (s *StatusEffectSystem) Update(deltaTime float64) {
 	// Обновление эффектов замедления
 	for id, effect := range s.ecs.SlowEffects {
+		// Иммунитет к эффектам (БКБ) снимает замедление
+		if isEffectImmune(s.ecs, id) {
+			delete(s.ecs.SlowEffects, id)
+			continue
+		}
 		effect.Timer -= deltaTime
 		if effect.Timer <= 0 {
 			delete(s.ecs.SlowEffects, id)
@@ -29,7 +34,7 @@ func (s *StatusEffectSystem) Update(deltaTime float64) {
 	// Обновление эффектов отравления
 	for id, effect := range s.ecs.PoisonEffects {
 		effect.Timer -= deltaTime
-		if effect.Timer <= 0 {
+		if effect.Timer <= 0 || isEffectImmune(s.ecs, id) {
 			delete(s.ecs.PoisonEffects, id)
 			continue
 		}
@@ -42,8 +47,29 @@ func (s *StatusEffectSystem) Update(deltaTime float64) {
 		}
 	}
 
+	// Регенерация врагов: дробные единицы здоровья копятся между кадрами.
+	// К регену волны добавляется бонус от ауры лечения (EnemyAbilitySystem).
+	for _, id := range entity.SortedIDs(s.ecs.Enemies) {
+		enemy := s.ecs.Enemies[id]
+		health, ok := s.ecs.Healths[id]
+		regen := enemy.Regen + enemy.BonusRegen
+		if !ok || regen <= 0 || health.Value <= 0 {
+			continue
+		}
+		enemy.RegenAccumulator += regen * deltaTime
+		heal := int(enemy.RegenAccumulator)
+		if heal > 0 {
+			enemy.RegenAccumulator -= float64(heal)
+			health.Value = min(health.Value+heal, enemy.MaxHealth)
+		}
+	}
+
 	// Обновление эффектов Jade Poison
 	for id, container := range s.ecs.JadePoisonContainers {
+		if isEffectImmune(s.ecs, id) {
+			delete(s.ecs.JadePoisonContainers, id)
+			continue
+		}
 		// Создаем новый срез для хранения только активных стаков
 		activeInstances := container.Instances[:0]
 
@@ -22,15 +22,28 @@ func ApplyDamage(ecs *entity.ECS, entityID types.EntityID, damage int, attackTyp
 		return
 	}
 
+	// Способности врага: БКБ не пропускает магию, заряд отражения гасит попадание целиком
+	if isEnemy {
+		if attackType == defs.AttackMagical && isMagicImmune(ecs, entityID) {
+			return
+		}
+		if absorbByReflection(ecs, entityID) {
+			ecs.DamageFlashes[entityID] = &component.DamageFlashComponent{Timer: config.DamageFlashDuration}
+			return
+		}
+	}
+
 	finalDamage := damage
 
 	// Рассчитываем урон только если это враг с компонентом брони
 	if isEnemy {
+		armorBonus := reactiveArmorBonus(ecs, entityID)
+		addReactiveArmorStack(ecs, entityID)
 		switch attackType {
 		case defs.AttackPhysical:
-			finalDamage -= enemy.PhysicalArmor
+			finalDamage -= enemy.PhysicalArmor + armorBonus
 		case defs.AttackMagical:
-			finalDamage -= enemy.MagicalArmor
+			finalDamage -= enemy.MagicalArmor + armorBonus
 		case defs.AttackPure:
 			// Чистый урон не уменьшается
 		}
@@ -55,3 +68,13 @@ func ApplyDamage(ecs *entity.ECS, entityID types.EntityID, damage int, attackTyp
 		}
 	}
 }
+
+// canTowerTarget сообщает, может ли башня атаковать врага: наземного или летающего.
+func canTowerTarget(ecs *entity.ECS, tower *component.Tower, enemyID types.EntityID) bool {
+	enemy, isEnemy := ecs.Enemies[enemyID]
+	if !isEnemy {
+		return false
+	}
+	towerDef, ok := defs.TowerDefs[tower.DefID]
+	return !ok || towerDef.CanTarget(enemy.Flying)
+}
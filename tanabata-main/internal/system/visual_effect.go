@@ -44,4 +44,25 @@ func (s *VisualEffectSystem) Update(deltaTime float64) {
 			renderable.Radius = float32(progress * aoeEffect.MaxRadius)
 		}
 	}
+
+	// Обновляем таймеры лазеров
+	for id, laser := range s.ecs.Lasers {
+		laser.Timer += deltaTime
+		if laser.Timer >= laser.Duration {
+			delete(s.ecs.Lasers, id)
+		}
+	}
+
+	// Обновляем эффекты извержения вулкана
+	for id, effect := range s.ecs.VolcanoEffects {
+		effect.Timer += deltaTime
+		progress := effect.Timer / effect.Duration
+		if progress > 1.0 {
+			progress = 1.0
+		}
+		effect.Radius = effect.MaxRadius * progress
+		if effect.Timer >= effect.Duration {
+			delete(s.ecs.VolcanoEffects, id)
+		}
+	}
 }
@@ -7,9 +7,11 @@ import (
 	"go-tower-defense/internal/defs"
 	"go-tower-defense/internal/entity"
 	"go-tower-defense/internal/types"
+	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
 	"image/color"
-	"math/rand"
+	"math"
+	"sort"
 )
 
 const volcanoTickRate = 4.0 // 4 тика в секунду
@@ -18,18 +20,23 @@ const volcanoTickRate = 4.0 // 4 тика в секунду
 type VolcanoSystem struct {
 	ecs               *entity.ECS
 	powerSourceFinder func(towerID types.EntityID) []types.EntityID
+	oreDrawer         *OreDrawer
+	rng               *utils.PRNGService
 }
 
-func NewVolcanoSystem(ecs *entity.ECS, finder func(towerID types.EntityID) []types.EntityID) *VolcanoSystem {
+func NewVolcanoSystem(ecs *entity.ECS, finder func(towerID types.EntityID) []types.EntityID, oreDrawer *OreDrawer, rng *utils.PRNGService) *VolcanoSystem {
 	return &VolcanoSystem{
 		ecs:               ecs,
 		powerSourceFinder: finder,
+		oreDrawer:         oreDrawer,
+		rng:               rng,
 	}
 }
 
 func (s *VolcanoSystem) Update(deltaTime float64) {
-	for id, tower := range s.ecs.Towers {
-		if tower.DefID != "TOWER_VOLCANO" || !tower.IsActive {
+	for _, id := range entity.SortedIDs(s.ecs.Towers) {
+		tower := s.ecs.Towers[id]
+		if tower.DefID != "TOWER_VOLCANO" || !tower.IsActive || tower.IsManuallyDisabled {
 			continue
 		}
 
@@ -57,9 +64,7 @@ func (s *VolcanoSystem) Update(deltaTime float64) {
 
 		var totalReserve float64
 		for _, sourceID := range powerSources {
-			if ore, ok := s.ecs.Ores[sourceID]; ok {
-				totalReserve += ore.CurrentReserve
-			}
+			totalReserve += powerSourceReserve(s.ecs, sourceID)
 		}
 
 		tickCost := combat.ShotCost / 4.0
@@ -72,7 +77,7 @@ func (s *VolcanoSystem) Update(deltaTime float64) {
 		towerHex := tower.Hex // Используем гекс башни
 
 		for enemyID, enemyPos := range s.ecs.Positions {
-			if _, isEnemy := s.ecs.Enemies[enemyID]; !isEnemy {
+			if !canTowerTarget(s.ecs, tower, enemyID) {
 				continue
 			}
 			if health, hasHealth := s.ecs.Healths[enemyID]; !hasHealth || health.Value <= 0 {
@@ -86,26 +91,17 @@ func (s *VolcanoSystem) Update(deltaTime float64) {
 			}
 		}
 		// --- КОНЕЦ ИСПРАВЛЕННОЙ ЛОГИКИ ---
+		sort.Slice(targets, func(i, j int) bool { return targets[i] < targets[j] })
 
 		if len(targets) > 0 {
-			availableSources := []types.EntityID{}
-			for _, sourceID := range powerSources {
-				if ore, ok := s.ecs.Ores[sourceID]; ok && ore.CurrentReserve > 0 {
-					availableSources = append(availableSources, sourceID)
-				}
-			}
-			if len(availableSources) > 0 {
-				chosenSourceID := availableSources[rand.Intn(len(availableSources))]
-				chosenOre := s.ecs.Ores[chosenSourceID]
-				if chosenOre.CurrentReserve >= tickCost {
-					chosenOre.CurrentReserve -= tickCost
-				} else {
-					chosenOre.CurrentReserve = 0
-				}
+			oreTypeMultiplier := 1.0
+			if chosenSourceID, ok := s.oreDrawer.Draw(id, powerSources, s.rng); ok {
+				spendFromPowerSource(s.ecs, chosenSourceID, tickCost)
+				oreTypeMultiplier = oreTypeDamageMultiplier(s.ecs, chosenSourceID, combat.Attack.DamageType)
 			}
 
 			towerDef := defs.TowerDefs[tower.DefID]
-			tickDamage := towerDef.Combat.Damage / 4
+			tickDamage := int(math.Round(float64(towerDef.Combat.Damage/4) * oreTypeMultiplier))
 			if tickDamage < 1 {
 				tickDamage = 1
 			}
@@ -10,6 +10,7 @@ import (
 	"go-tower-defense/internal/utils"
 	"go-tower-defense/pkg/hexmap"
 	"log"
+	"slices"
 )
 
 type WaveSystem struct {
@@ -32,16 +33,25 @@ func NewWaveSystem(ecs *entity.ECS, hexMap *hexmap.HexMap, eventDispatcher *even
 	return ws
 }
 
+// Update ведет таймеры всех групп волны. Группы независимы, поэтому их враги
+// перемежаются; в пределах одного кадра группы спавнят в порядке из waves.json.
 func (s *WaveSystem) Update(deltaTime float64, wave *component.Wave) {
 	if wave == nil {
 		return
 	}
 	if wave.EnemiesToSpawn > 0 {
-		wave.SpawnTimer += deltaTime
-		if wave.SpawnTimer >= wave.SpawnInterval {
-			s.spawnEnemy(wave)
-			wave.EnemiesToSpawn--
-			wave.SpawnTimer = 0
+		for i := range wave.Groups {
+			group := &wave.Groups[i]
+			if group.Remaining == 0 {
+				continue
+			}
+			group.Timer += deltaTime
+			if group.Timer >= group.Interval {
+				s.spawnEnemy(wave, group)
+				group.Remaining--
+				wave.EnemiesToSpawn--
+				group.Timer = 0
+			}
 		}
 	} else if wave.EnemiesToSpawn == 0 && s.activeEnemies == 0 {
 		s.eventDispatcher.Dispatch(event.Event{Type: event.WaveEnded})
@@ -52,10 +62,26 @@ func (s *WaveSystem) ResetActiveEnemies() {
 	s.activeEnemies = 0
 }
 
-func (s *WaveSystem) spawnEnemy(wave *component.Wave) {
-	def, ok := defs.EnemyDefs[wave.EnemyID]
+// ActiveEnemies возвращает число врагов текущей волны, еще не убранных с поля.
+func (s *WaveSystem) ActiveEnemies() int {
+	return s.activeEnemies
+}
+
+// SetActiveEnemies восстанавливает счетчик активных врагов (при загрузке сохранения).
+func (s *WaveSystem) SetActiveEnemies(n int) {
+	s.activeEnemies = n
+}
+
+func (s *WaveSystem) spawnEnemy(wave *component.Wave, group *component.SpawnGroup) {
+	def, ok := defs.EnemyDefs[group.EnemyID]
 	if !ok {
-		log.Printf("Error: Enemy definition not found for ID: %s", wave.EnemyID)
+		log.Printf("Error: Enemy definition not found for ID: %s", group.EnemyID)
+		return
+	}
+	// Модификаторы волны (множители здоровья и скорости, броня, реген, способности)
+	waveDef, ok := defs.GetWaveDefinition(wave.Number)
+	if !ok {
+		log.Printf("Error: Wave definition not found for wave %d", wave.Number)
 		return
 	}
 
@@ -66,49 +92,138 @@ func (s *WaveSystem) spawnEnemy(wave *component.Wave) {
 		wave.DamagePerEnemy = wave.DamagePerEnemy[1:]
 	}
 
+	baseHealth := def.Health
+	if waveDef.HealthOverride > 0 {
+		baseHealth = waveDef.HealthOverride
+	}
+	health := max(1, int(float64(baseHealth)*waveDef.HealthMultiplierFor(def.Flying)*waveDef.HealthMultiplierModifier))
+	speed := def.Speed * waveDef.SpeedMultiplier * waveDef.SpeedMultiplierModifier
+	magicalArmor := int(float64(def.MagicalArmor+waveDef.MagicalArmorBonus) * waveDef.MagicalArmorMultiplier)
+	// Способности самого врага (аура хилера) плюс способности волны и группы
+	abilities := append([]string(nil), def.Abilities...)
+	for _, ability := range slices.Concat(waveDef.Abilities, group.Abilities) {
+		if !slices.Contains(abilities, ability) {
+			abilities = append(abilities, ability)
+		}
+	}
+	// Способность "уклонение" дает шанс по умолчанию, если ни группа, ни волна не задали свой
+	evasion := waveDef.EvasionChance
+	if group.Evasion > 0 {
+		evasion = group.Evasion
+	}
+	if evasion == 0 && slices.Contains(abilities, defs.AbilityEvasion) {
+		evasion = defs.AbilityDefs[defs.AbilityEvasion].Amount
+	}
+
+	route := group.Routes[group.Spawned%len(group.Routes)]
+	group.Spawned++
+
 	id := s.ecs.NewEntity()
-	x, y := utils.HexToScreen(s.hexMap.Entry)
+	x, y := utils.HexToScreen(route.Path[0])
 	s.ecs.Positions[id] = &component.Position{X: x, Y: y}
-	s.ecs.Velocities[id] = &component.Velocity{Speed: def.Speed}
-	s.ecs.Paths[id] = &component.Path{Hexes: wave.CurrentPath, CurrentIndex: 0}
-	s.ecs.Healths[id] = &component.Health{Value: def.Health}
+	s.ecs.Velocities[id] = &component.Velocity{Speed: speed}
+	s.ecs.Paths[id] = &component.Path{Hexes: route.Path, CurrentIndex: 0}
+	s.ecs.Healths[id] = &component.Health{Value: health}
 	s.ecs.Renderables[id] = &component.Renderable{
 		Color:     def.Visuals.Color,
 		Radius:    float32(config.HexSize * def.Visuals.RadiusFactor),
 		HasStroke: def.Visuals.StrokeWidth > 0,
 	}
 	s.ecs.Enemies[id] = &component.Enemy{
-		DefID:               wave.EnemyID,
+		DefID:               group.EnemyID,
 		OreDamageCooldown:   0,
 		LineDamageCooldown:  0,
-		PhysicalArmor:       def.PhysicalArmor,
-		MagicalArmor:        def.MagicalArmor,
+		PhysicalArmor:       def.PhysicalArmor + waveDef.PhysicalArmorBonus,
+		MagicalArmor:        magicalArmor,
 		Damage:              damage, // Устанавливаем урон
 		LastCheckpointIndex: -1,
+		MaxHealth:           health,
+		Regen:               max(0, waveDef.Regen*waveDef.RegenMultiplierModifier),
+		EvasionChance:       evasion,
+		Flying:              def.Flying,
+		Gate:                route.Gate,
+	}
+	InitEnemyAbilities(s.ecs, id, abilities)
+	if blink := enemyAbility(s.ecs, id, defs.AbilityBlink); blink != nil {
+		if waveDef.BlinkStartCooldown > 0 {
+			blink.Cooldown = waveDef.BlinkStartCooldown
+		}
+		blink.Amount = float64(waveDef.BlinkHexes)
+		blink.Interval = waveDef.BlinkCooldown
 	}
 	s.activeEnemies++
 }
 
-func (s *WaveSystem) StartWave(waveNumber int) *component.Wave {
-	waveDef, ok := defs.WavePatterns[waveNumber]
+// StartWave готовит волну waveNumber. Пути наземных врагов берутся из полей
+// потока flows, построенных по текущей карте.
+func (s *WaveSystem) StartWave(waveNumber int, flows *hexmap.RouteFlows) *component.Wave {
+	waveDef, ok := defs.GetWaveDefinition(waveNumber)
 	if !ok {
-		repeatingWaveNumber := ((waveNumber - 6) % 5) + 6
-		waveDef, ok = defs.WavePatterns[repeatingWaveNumber]
-		if !ok {
-			log.Printf("Критическая ошибка: не найдено определение для повторяющейся волны %d", repeatingWaveNumber)
-			waveDef = defs.WavePatterns[1]
-		}
+		log.Printf("Критическая ошибка: не найдено определение для волны %d", waveNumber)
+		return nil
 	}
 
-	fullPath := s.calculatePath()
-	if fullPath == nil {
-		log.Println("Не удалось рассчитать путь для волны!")
-		return nil
+	// Группы волны: путь считается один раз на каждый используемый вход,
+	// отдельно для наземных и летающих врагов
+	type pathKey struct {
+		gate   int
+		flying bool
+	}
+	gates := s.hexMap.Gates()
+	paths := make(map[pathKey][]hexmap.Hex)
+	var groups []component.SpawnGroup
+	for i, groupDef := range waveDef.SpawnGroups() {
+		// Группа без entry чередует все входы, начиная со своего, чтобы группы
+		// одной волны на карте с несколькими входами выходили из разных
+		first, alternate := i%len(gates), true
+		if groupDef.Entry != nil {
+			first, alternate = *groupDef.Entry, groupDef.AlternateEntries
+		}
+		if first >= len(gates) {
+			log.Printf("Волна %d: точки входа %d нет на карте, используется основной вход", waveNumber, first)
+			first = 0
+		}
+		gateOrder := []int{first}
+		if alternate {
+			for i := 1; i < len(gates); i++ {
+				gateOrder = append(gateOrder, (first+i)%len(gates))
+			}
+		}
+		var routes []component.SpawnRoute
+		for _, gate := range gateOrder {
+			key := pathKey{gate: gate, flying: defs.EnemyDefs[groupDef.EnemyID].Flying}
+			path, ok := paths[key]
+			if !ok {
+				if key.flying {
+					path = s.calculateFlyingPath(gates[gate])
+				} else {
+					path = flows.Gate(gate).Route(s.hexMap)
+				}
+				if path == nil {
+					log.Printf("Не удалось рассчитать путь для волны: маршрут входа %d перекрыт!", gate)
+					return nil
+				}
+				paths[key] = path
+			}
+			routes = append(routes, component.SpawnRoute{Gate: gate, Path: path})
+		}
+		groups = append(groups, component.SpawnGroup{
+			EnemyID:   groupDef.EnemyID,
+			Remaining: groupDef.Count,
+			Interval:  groupDef.SpawnInterval,
+			Timer:     -groupDef.Delay,
+			Routes:    routes,
+			Abilities: groupDef.Abilities,
+			Evasion:   groupDef.EvasionChance,
+		})
 	}
 
 	// --- Расчет урона для каждого врага ---
-	totalDamage := 100
-	enemyCount := waveDef.Count
+	totalDamage := defs.DefaultTotalWaveDamage
+	if waveDef.TotalWaveDamage > 0 {
+		totalDamage = waveDef.TotalWaveDamage
+	}
+	enemyCount := waveDef.TotalCount()
 	baseDamage := totalDamage / enemyCount
 	remainder := totalDamage % enemyCount
 
@@ -129,48 +244,22 @@ func (s *WaveSystem) StartWave(waveNumber int) *component.Wave {
 
 	return &component.Wave{
 		Number:         waveNumber,
-		EnemiesToSpawn: waveDef.Count,
-		SpawnTimer:     0,
-		SpawnInterval:  waveDef.SpawnInterval.Seconds(),
-		CurrentPath:    fullPath,
-		EnemyID:        waveDef.EnemyID,
+		EnemiesToSpawn: enemyCount,
+		Groups:         groups,
 		DamagePerEnemy: damageList, // Сохраняем список уронов
 	}
 }
 
-func (s *WaveSystem) calculatePath() []hexmap.Hex {
-	// ... (код расчета пути остается без изменений)
-	fullPath := []hexmap.Hex{}
-	if len(s.hexMap.Checkpoints) == 0 {
-		path := hexmap.AStar(s.hexMap.Entry, s.hexMap.Exit, s.hexMap)
-		if path == nil {
-			log.Println("Не удалось найти путь от входа до выхода!")
-			return nil
-		}
-		return path
-	}
-
-	current := s.hexMap.Entry
-	for i, cp := range s.hexMap.Checkpoints {
-		pathSegment := hexmap.AStar(current, cp, s.hexMap)
-		if pathSegment == nil {
-			log.Printf("Не удалось найти путь до чекпоинта %d!", i+1)
-			return nil
-		}
-		if len(fullPath) == 0 {
-			fullPath = pathSegment
-		} else {
-			fullPath = append(fullPath, pathSegment[1:]...)
-		}
-		current = cp
-	}
-
-	pathToExit := hexmap.AStar(current, s.hexMap.Exit, s.hexMap)
-	if pathToExit == nil {
-		log.Println("Не удалось найти путь от последнего чекпоинта до выхода!")
-		return nil
+// calculateFlyingPath строит путь для летающих врагов: прямые отрезки от входа
+// gate через все его чекпоинты до выхода. Стены и башни на пути не учитываются.
+func (s *WaveSystem) calculateFlyingPath(gate hexmap.Gate) []hexmap.Hex {
+	fullPath := []hexmap.Hex{gate.Entry}
+	current := gate.Entry
+	waypoints := append(append([]hexmap.Hex{}, gate.Checkpoints...), gate.Exit)
+	for _, point := range waypoints {
+		fullPath = append(fullPath, current.LineTo(point)[1:]...)
+		current = point
 	}
-	fullPath = append(fullPath, pathToExit[1:]...)
 	return fullPath
 }
 
@@ -39,6 +39,9 @@ type InfoPanelRL struct {
 	targetY         float32
 	SelectButton    ButtonRL
 	CombineButton   ButtonRL
+	BatteryButton   ButtonRL
+	EnableButton    ButtonRL
+	OreDrawButton   ButtonRL
 	eventDispatcher *event.Dispatcher
 }
 
@@ -90,13 +93,74 @@ func (p *InfoPanelRL) Update(ecs *entity.ECS) {
 		if rl.CheckCollisionPointRec(mousePos, p.CombineButton.Rect) {
 			p.handleCombineClick(ecs)
 		}
+		if rl.CheckCollisionPointRec(mousePos, p.BatteryButton.Rect) {
+			p.handleBatteryClick(ecs)
+		}
+		if rl.CheckCollisionPointRec(mousePos, p.EnableButton.Rect) {
+			p.handleEnableClick(ecs)
+		}
+		if rl.CheckCollisionPointRec(mousePos, p.OreDrawButton.Rect) {
+			p.handleOreDrawClick(ecs)
+		}
 	}
 }
 
 // IsClicked пров��ряет, был ли клик внутри одной из кнопок панели.
 func (p *InfoPanelRL) IsClicked(mousePos rl.Vector2) bool {
 	return rl.CheckCollisionPointRec(mousePos, p.SelectButton.Rect) ||
-		rl.CheckCollisionPointRec(mousePos, p.CombineButton.Rect)
+		rl.CheckCollisionPointRec(mousePos, p.CombineButton.Rect) ||
+		rl.CheckCollisionPointRec(mousePos, p.BatteryButton.Rect) ||
+		rl.CheckCollisionPointRec(mousePos, p.EnableButton.Rect) ||
+		rl.CheckCollisionPointRec(mousePos, p.OreDrawButton.Rect)
+}
+
+// handleOreDrawClick переключает политику выбора руды выбранной башни,
+// а с зажатым Shift — политику всей игры.
+func (p *InfoPanelRL) handleOreDrawClick(ecs *entity.ECS) {
+	if _, ok := ecs.Combats[p.TargetEntity]; !ok {
+		return
+	}
+	target := p.TargetEntity
+	if rl.IsKeyDown(rl.KeyLeftShift) || rl.IsKeyDown(rl.KeyRightShift) {
+		target = 0
+	}
+	p.eventDispatcher.Dispatch(event.Event{
+		Type: event.CycleOreDrawPolicyRequest,
+		Data: target,
+	})
+}
+
+func (p *InfoPanelRL) handleEnableClick(ecs *entity.ECS) {
+	if p.canToggleEnabled(ecs) {
+		p.eventDispatcher.Dispatch(event.Event{
+			Type: event.ToggleTowerEnabledRequest,
+			Data: p.TargetEntity,
+		})
+	}
+}
+
+// canToggleEnabled сообщает, можно ли сейчас включить или выключить выбранную башню:
+// только в фазах волны и выбора и только не стену.
+func (p *InfoPanelRL) canToggleEnabled(ecs *entity.ECS) bool {
+	phase := ecs.GameState.Phase
+	if phase != component.WaveState && phase != component.TowerSelectionState {
+		return false
+	}
+	tower, ok := ecs.Towers[p.TargetEntity]
+	if !ok {
+		return false
+	}
+	towerDef, ok := defs.TowerDefs[tower.DefID]
+	return ok && towerDef.Type != defs.TowerTypeWall
+}
+
+func (p *InfoPanelRL) handleBatteryClick(ecs *entity.ECS) {
+	if _, ok := ecs.Batteries[p.TargetEntity]; ok && ecs.GameState.Phase != component.TowerSelectionState {
+		p.eventDispatcher.Dispatch(event.Event{
+			Type: event.ToggleBatteryModeRequest,
+			Data: p.TargetEntity,
+		})
+	}
 }
 
 func (p *InfoPanelRL) handleCombineClick(ecs *entity.ECS) {
@@ -143,19 +207,105 @@ func (p *InfoPanelRL) Draw(ecs *entity.ECS) {
 
 	p.drawEntityInfo(ecs, panelRect.X+15, panelRect.Y+15)
 
+	if p.canToggleEnabled(ecs) {
+		p.drawEnableButton(panelRect, ecs.Towers[p.TargetEntity].IsManuallyDisabled)
+	}
+	if tower, ok := ecs.Towers[p.TargetEntity]; ok {
+		if _, ok := ecs.Combats[p.TargetEntity]; ok {
+			p.drawOreDrawButton(panelRect, tower.OreDrawPolicy)
+		}
+	}
+
 	if ecs.GameState.Phase == component.TowerSelectionState {
 		if tower, ok := ecs.Towers[p.TargetEntity]; ok {
 			if towerDef, ok := defs.TowerDefs[tower.DefID]; ok && tower.IsTemporary && towerDef.Type != defs.TowerTypeMiner {
 				p.drawSelectButton(panelRect, tower.IsSelected)
 			}
 		}
-	} else if ecs.GameState.Phase == component.WaveState {
-		if _, ok := ecs.Combinables[p.TargetEntity]; ok {
+	} else {
+		if _, ok := ecs.Combinables[p.TargetEntity]; ok && ecs.GameState.Phase == component.WaveState {
 			p.drawCombineButton(panelRect)
 		}
+		if battery, ok := ecs.Batteries[p.TargetEntity]; ok {
+			p.drawBatteryButton(panelRect, battery.Mode)
+		}
 	}
 }
 
+func (p *InfoPanelRL) drawEnableButton(panelRect rl.Rectangle, isDisabled bool) {
+	btnWidth := float32(150)
+	btnHeight := float32(40)
+	p.EnableButton.Rect = rl.NewRectangle(
+		panelRect.X+panelRect.Width-btnWidth*3-60,
+		panelRect.Y+panelRect.Height-btnHeight-20,
+		btnWidth,
+		btnHeight,
+	)
+
+	btnColor := config.SelectButtonColorRL
+	p.EnableButton.Text = "Выключить"
+	if isDisabled {
+		btnColor = config.SelectButtonActiveColorRL
+		p.EnableButton.Text = "Включить"
+	}
+
+	rl.DrawRectangleRec(p.EnableButton.Rect, btnColor)
+	textPos := rl.NewVector2(
+		p.EnableButton.Rect.X+(p.EnableButton.Rect.Width-float32(rl.MeasureText(p.EnableButton.Text, regularFontSizeRL)))/2,
+		p.EnableButton.Rect.Y+(p.EnableButton.Rect.Height-regularFontSizeRL)/2,
+	)
+	rl.DrawTextEx(p.font, p.EnableButton.Text, textPos, regularFontSizeRL, 1.0, rl.White)
+}
+
+func (p *InfoPanelRL) drawOreDrawButton(panelRect rl.Rectangle, policy component.OreDrawPolicy) {
+	btnWidth := float32(150)
+	btnHeight := float32(40)
+	p.OreDrawButton.Rect = rl.NewRectangle(
+		panelRect.X+panelRect.Width-btnWidth*4-80,
+		panelRect.Y+panelRect.Height-btnHeight-20,
+		btnWidth,
+		btnHeight,
+	)
+
+	btnColor := config.SelectButtonColorRL
+	if policy != component.OreDrawInherit {
+		btnColor = config.SelectButtonActiveColorRL
+	}
+	p.OreDrawButton.Text = policy.String()
+
+	rl.DrawRectangleRec(p.OreDrawButton.Rect, btnColor)
+	textPos := rl.NewVector2(
+		p.OreDrawButton.Rect.X+(p.OreDrawButton.Rect.Width-float32(rl.MeasureText(p.OreDrawButton.Text, regularFontSizeRL)))/2,
+		p.OreDrawButton.Rect.Y+(p.OreDrawButton.Rect.Height-regularFontSizeRL)/2,
+	)
+	rl.DrawTextEx(p.font, p.OreDrawButton.Text, textPos, regularFontSizeRL, 1.0, rl.White)
+}
+
+func (p *InfoPanelRL) drawBatteryButton(panelRect rl.Rectangle, mode component.BatteryMode) {
+	btnWidth := float32(150)
+	btnHeight := float32(40)
+	p.BatteryButton.Rect = rl.NewRectangle(
+		panelRect.X+panelRect.Width-btnWidth-20,
+		panelRect.Y+panelRect.Height-btnHeight-20,
+		btnWidth,
+		btnHeight,
+	)
+
+	btnColor := config.SelectButtonColorRL
+	p.BatteryButton.Text = "Зарядка"
+	if mode == component.BatteryDischarging {
+		btnColor = config.SelectButtonActiveColorRL
+		p.BatteryButton.Text = "Разрядка"
+	}
+
+	rl.DrawRectangleRec(p.BatteryButton.Rect, btnColor)
+	textPos := rl.NewVector2(
+		p.BatteryButton.Rect.X+(p.BatteryButton.Rect.Width-float32(rl.MeasureText(p.BatteryButton.Text, regularFontSizeRL)))/2,
+		p.BatteryButton.Rect.Y+(p.BatteryButton.Rect.Height-regularFontSizeRL)/2,
+	)
+	rl.DrawTextEx(p.font, p.BatteryButton.Text, textPos, regularFontSizeRL, 1.0, rl.White)
+}
+
 func (p *InfoPanelRL) drawCombineButton(panelRect rl.Rectangle) {
 	btnWidth := float32(150)
 	btnHeight := float32(40)
@@ -239,6 +389,35 @@ func (p *InfoPanelRL) drawTowerInfo(ecs *entity.ECS, towerDef *defs.TowerDefinit
 			rl.DrawTextEx(p.font, fmt.Sprintf("Damage Type: %s", towerDef.Combat.Attack.DamageType), rl.NewVector2(startX, y), regularFontSizeRL, 1.0, config.TextLightColorRL)
 		}
 	}
+
+	if battery, ok := ecs.Batteries[p.TargetEntity]; ok && towerDef.Energy != nil {
+		rl.DrawTextEx(p.font, fmt.Sprintf("Stored: %.1f / %.0f", battery.Stored, towerDef.Energy.StorageMax), rl.NewVector2(startX, y), regularFontSizeRL, 1.0, config.TextLightColorRL)
+	}
+
+	if towerDef.Type != defs.TowerTypeWall {
+		p.drawNetworkInfo(ecs, tower, startX+columnSpacingRL, startY)
+	}
+}
+
+// drawNetworkInfo выводит во второй колонке баланс добычи и потребления сети башни.
+func (p *InfoPanelRL) drawNetworkInfo(ecs *entity.ECS, tower *component.Tower, x, startY float32) {
+	y := startY
+	rl.DrawTextEx(p.font, fmt.Sprintf("Network: %.2f / %.2f ore/s", tower.Flow.Supply, tower.Flow.Demand), rl.NewVector2(x, y), regularFontSizeRL, 1.0, config.TextLightColorRL)
+	y += lineHeightRL
+
+	if _, ok := ecs.Combats[p.TargetEntity]; !ok {
+		return
+	}
+	supplied, throttleColor := "Supplied: no network", config.TextLightColorRL
+	if tower.Flow.Computed {
+		supplied = fmt.Sprintf("Supplied: %.0f%%", tower.Flow.Throttle*100)
+		if tower.Flow.Throttle < 1 {
+			throttleColor = config.OreIndicatorCriticalColor
+		}
+	}
+	rl.DrawTextEx(p.font, supplied, rl.NewVector2(x, y), regularFontSizeRL, 1.0, throttleColor)
+	y += lineHeightRL
+	rl.DrawTextEx(p.font, fmt.Sprintf("Ore draw: %s (game: %s)", tower.OreDrawPolicy, ecs.GameState.OreDrawPolicy), rl.NewVector2(x, y), regularFontSizeRL, 1.0, config.TextLightColorRL)
 }
 
 func (p *InfoPanelRL) drawEnemyInfo(ecs *entity.ECS, enemyDef *defs.EnemyDefinition, startX, startY float32) {
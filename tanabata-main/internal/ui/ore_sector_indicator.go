@@ -2,7 +2,9 @@
 package ui
 
 import (
+	"go-tower-defense/internal/component"
 	"go-tower-defense/internal/config"
+	"math"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
@@ -51,24 +53,31 @@ func getCentralVeinColors(percentage float32) [3]rl.Color {
 	}
 }
 
-// Draw отрисовывает индикатор.
-func (i *OreSectorIndicatorRL) Draw(centralPct, midPct, farPct float32) {
+// Draw отрисовывает индикатор. current — текущая доля руды в жилах (центральная, средняя,
+// дальняя), projected — доля после восстановления в начале следующей фазы строительства.
+// Сегменты, которые заполнятся только восстановлением, рисуются бледным цветом.
+// warnings — прогноз истощения жил: рамка сегментов жилы окрашивается предупреждением.
+// veins — число жил уровня: жилы, которых на уровне нет, рисуются блеклым контуром.
+func (i *OreSectorIndicatorRL) Draw(current, projected [3]float32, warnings [3]component.OreWarningLevel, veins int) {
+	centralPct, midPct, farPct := current[0], current[1], current[2]
 	// --- Верхний ряд: Центральная (3) и Средняя (4) жилы ---
 	topRowSegments := 7
 	segmentWidthTop := (i.TotalWidth - float32(topRowSegments-1)*i.Spacing) / float32(topRowSegments)
 	currentX := i.X
 
 	// Новая логика для центральной жилы (первые 3 сегмента)
-	centralColors := getCentralVeinColors(centralPct)
-	for _, color := range centralColors {
-		rect := rl.NewRectangle(currentX, i.Y, segmentWidthTop, i.SegmentHeight)
-		rl.DrawRectangleRec(rect, color)
-		rl.DrawRectangleLinesEx(rect, 2, config.UIBorderColor)
-		currentX += segmentWidthTop + i.Spacing
+	if veins < 1 {
+		i.drawAbsentVein(&currentX, i.Y, segmentWidthTop, 3)
+	} else {
+		i.drawCentralVein(&currentX, segmentWidthTop, centralPct, projected[0], warnings[0])
 	}
 
 	// Старая логика для средней жилы (следующие 4 сегмента)
-	i.drawVeinSegments(&currentX, i.Y, segmentWidthTop, 4, midPct)
+	if veins < 2 {
+		i.drawAbsentVein(&currentX, i.Y, segmentWidthTop, 4)
+	} else {
+		i.drawVeinSegments(&currentX, i.Y, segmentWidthTop, 4, midPct, projected[1], warnings[1])
+	}
 
 	// --- Нижний ряд: Крайняя (7) жила ---
 	bottomRowSegments := 7
@@ -76,7 +85,36 @@ func (i *OreSectorIndicatorRL) Draw(centralPct, midPct, farPct float32) {
 	currentX = i.X
 	currentY := i.Y + i.SegmentHeight + i.Spacing*2
 
-	i.drawVeinSegments(&currentX, currentY, segmentWidthBottom, 7, farPct)
+	if veins < 3 {
+		i.drawAbsentVein(&currentX, currentY, segmentWidthBottom, 7)
+	} else {
+		i.drawVeinSegments(&currentX, currentY, segmentWidthBottom, 7, farPct, projected[2], warnings[2])
+	}
+}
+
+// drawCentralVein рисует три сегмента центральной жилы по нелинейной шкале.
+func (i *OreSectorIndicatorRL) drawCentralVein(currentX *float32, segmentWidth, centralPct, projectedPct float32, warning component.OreWarningLevel) {
+	centralColors := getCentralVeinColors(centralPct)
+	projectedColors := getCentralVeinColors(projectedPct)
+	for k, color := range centralColors {
+		if color == config.OreIndicatorDepletedColor && projectedColors[k] != config.OreIndicatorDepletedColor {
+			color = config.OreIndicatorRefillColor
+		}
+		rect := rl.NewRectangle(*currentX, i.Y, segmentWidth, i.SegmentHeight)
+		rl.DrawRectangleRec(rect, color)
+		rl.DrawRectangleLinesEx(rect, 2, warningBorderColor(warning))
+		*currentX += segmentWidth + i.Spacing
+	}
+}
+
+// drawAbsentVein рисует сегменты жилы, которой нет на уровне: только блеклый контур,
+// чтобы она не выглядела истощенной.
+func (i *OreSectorIndicatorRL) drawAbsentVein(currentX *float32, currentY, segmentWidth float32, numSegments int) {
+	for j := 0; j < numSegments; j++ {
+		rect := rl.NewRectangle(*currentX, currentY, segmentWidth, i.SegmentHeight)
+		rl.DrawRectangleLinesEx(rect, 1, config.OreIndicatorAbsentColor)
+		*currentX += segmentWidth + i.Spacing
+	}
 }
 
 // getVeinState определяет, сколько сегментов должно быть пустым и какой цвет у активных.
@@ -116,8 +154,9 @@ func (i *OreSectorIndicatorRL) getVeinState(numSegments int, percentage float32)
 	return emptyCount, activeColor
 }
 
-func (i *OreSectorIndicatorRL) drawVeinSegments(currentX *float32, currentY, segmentWidth float32, numSegments int, percentage float32) {
+func (i *OreSectorIndicatorRL) drawVeinSegments(currentX *float32, currentY, segmentWidth float32, numSegments int, percentage, projected float32, warning component.OreWarningLevel) {
 	emptySegments, activeColor := i.getVeinState(numSegments, percentage)
+	projectedEmpty, _ := i.getVeinState(numSegments, projected)
 
 	for j := 0; j < numSegments; j++ {
 		rect := rl.NewRectangle(*currentX, currentY, segmentWidth, i.SegmentHeight)
@@ -134,9 +173,30 @@ func (i *OreSectorIndicatorRL) drawVeinSegments(currentX *float32, currentY, seg
 			fillColor = config.OreIndicatorDepletedColor
 		}
 
+		// Сегмент пуст сейчас, но заполнится восстановлением жилы
+		if projected > percentage && j >= projectedEmpty && (j < emptySegments || percentage <= 0) {
+			fillColor = config.OreIndicatorRefillColor
+		}
+
 		rl.DrawRectangleRec(rect, fillColor)
-		rl.DrawRectangleLinesEx(rect, 2, config.UIBorderColor)
+		rl.DrawRectangleLinesEx(rect, 2, warningBorderColor(warning))
 
 		*currentX += segmentWidth + i.Spacing
 	}
 }
+
+// warningBorderColor возвращает цвет рамки сегментов жилы по прогнозу истощения.
+// Критическое предупреждение мигает, чтобы его было видно посреди волны.
+func warningBorderColor(warning component.OreWarningLevel) rl.Color {
+	switch warning {
+	case component.OreWarningCritical:
+		if math.Mod(rl.GetTime(), 0.6) < 0.3 {
+			return config.OreIndicatorWarningColor
+		}
+		return config.UIBorderColor
+	case component.OreWarningSoon:
+		return config.OreIndicatorCriticalColor
+	default:
+		return config.UIBorderColor
+	}
+}
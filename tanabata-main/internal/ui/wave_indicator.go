@@ -1,13 +1,16 @@
 package ui
 
 import (
+	"fmt"
 	"go-tower-defense/internal/config"
+	"go-tower-defense/internal/defs"
 	"strings"
 
 	rl "github.com/gen2brain/raylib-go/raylib"
 )
 
-// WaveIndicator отображает номер текущей волны римскими цифрами.
+// WaveIndicator отображает номер текущей волны римскими цифрами,
+// а под ним — состав волны по группам: цвет врага и количество.
 type WaveIndicator struct {
 	X, Y             float32
 	FontSize         float32
@@ -46,8 +49,9 @@ func toRoman(num int) string {
 	return roman.String()
 }
 
-// Draw отрисовывает индикатор на экране.
-func (i *WaveIndicator) Draw(waveNumber int, font rl.Font) {
+// Draw отрисовывает индикатор на экране. groups — группы волны waveNumber
+// (может быть пустым, тогда рисуется только номер).
+func (i *WaveIndicator) Draw(waveNumber int, groups []defs.SpawnGroupDefinition, font rl.Font) {
 	if waveNumber <= 0 {
 		return
 	}
@@ -77,4 +81,42 @@ func (i *WaveIndicator) Draw(waveNumber int, font rl.Font) {
 
 	// Рисуем основной текст
 	rl.DrawTextEx(font, text, rl.NewVector2(textX, textY), i.FontSize, 1, textColor)
+
+	i.drawGroups(groups, font, textY+textSize.Y+4)
+}
+
+// drawGroups рисует строку с составом волны: кружок цвета врага и "xN" для каждой группы.
+func (i *WaveIndicator) drawGroups(groups []defs.SpawnGroupDefinition, font rl.Font, y float32) {
+	if len(groups) == 0 {
+		return
+	}
+	const (
+		radius   = float32(5)
+		gap      = float32(3)
+		spacing  = float32(10)
+		fontSize = float32(14)
+	)
+
+	labels := make([]string, len(groups))
+	widths := make([]float32, len(groups))
+	total := float32(0)
+	for idx, group := range groups {
+		labels[idx] = fmt.Sprintf("x%d", group.Count)
+		widths[idx] = radius*2 + gap + rl.MeasureTextEx(font, labels[idx], fontSize, 1).X
+		total += widths[idx]
+	}
+	total += spacing * float32(len(groups)-1)
+
+	x := i.X - total/2
+	for idx, group := range groups {
+		circleColor := rl.Gray
+		if enemyDef, ok := defs.EnemyDefs[group.EnemyID]; ok {
+			c := enemyDef.Visuals.Color
+			circleColor = rl.NewColor(c.R, c.G, c.B, c.A)
+		}
+		rl.DrawCircleV(rl.NewVector2(x+radius, y+fontSize/2), radius, circleColor)
+		rl.DrawCircleLinesV(rl.NewVector2(x+radius, y+fontSize/2), radius, i.OutlineColor)
+		rl.DrawTextEx(font, labels[idx], rl.NewVector2(x+radius*2+gap, y), fontSize, 1, i.OutlineColor)
+		x += widths[idx] + spacing
+	}
 }
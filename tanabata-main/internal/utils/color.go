@@ -1,5 +1,5 @@
-// pkg/render/color.go
-package render
+// internal/utils/color.go
+package utils
 
 import "image/color"
 
@@ -11,4 +11,4 @@ func DarkenColor(c color.RGBA) color.RGBA {
 		B: uint8(float64(c.B) * 0.5),
 		A: c.A,
 	}
-}
\ No newline at end of file
+}
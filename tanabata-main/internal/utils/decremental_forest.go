@@ -0,0 +1,199 @@
+// internal/utils/decremental_forest.go
+package utils
+
+import (
+	"go-tower-defense/internal/types"
+	"math/bits"
+	"sort"
+)
+
+// decrementalForest maintains the minimum spanning forest of a fixed graph while
+// its edges are deleted (Holm, de Lichtenberg and Thorup). Every edge has a
+// level; the forest edges of level >= i form trees of at most n/2^i vertices,
+// and the forest restricted to levels >= i is the minimum spanning forest of the
+// edges of level >= i. A deleted forest edge is replaced by searching its levels
+// from the top down, each time from the smaller of the two trees: the edges that
+// turn out not to cross are moved one level up, so every edge is moved at most
+// log n times and a deletion costs O(log^2 n) amortized over the structure's life.
+type decrementalForest struct {
+	less      func(a, b Edge) bool
+	levels    []*eulerTourForest // levels[i] holds the forest edges of level >= i
+	level     map[Edge]int
+	tree      map[Edge]bool
+	treeAt    []map[types.EntityID][]Edge // forest edges of exactly level i, by vertex
+	nonTreeAt []map[types.EntityID][]Edge // other edges of level i, by vertex, sorted by less
+}
+
+// newDecrementalForest builds the structure for the given graph with all edges
+// on level 0 and the forest found by Kruskal's algorithm.
+func newDecrementalForest(vertices []types.EntityID, edges []Edge, less func(a, b Edge) bool) *decrementalForest {
+	count := bits.Len(uint(len(vertices)))
+	d := &decrementalForest{
+		less:      less,
+		levels:    make([]*eulerTourForest, count),
+		level:     make(map[Edge]int, len(edges)),
+		tree:      make(map[Edge]bool),
+		treeAt:    make([]map[types.EntityID][]Edge, count),
+		nonTreeAt: make([]map[types.EntityID][]Edge, count),
+	}
+	for i := range d.levels {
+		d.levels[i] = newEulerTourForest(less)
+		for _, v := range vertices {
+			d.levels[i].addVertex(v)
+		}
+		d.treeAt[i] = make(map[types.EntityID][]Edge)
+		d.nonTreeAt[i] = make(map[types.EntityID][]Edge)
+	}
+
+	sorted := append([]Edge(nil), edges...)
+	sort.Slice(sorted, func(i, j int) bool { return less(sorted[i], sorted[j]) })
+	components := NewUnionFind()
+	for _, edge := range sorted {
+		d.level[edge] = 0
+		if components.Find(edge.A) != components.Find(edge.B) {
+			components.Union(edge.A, edge.B)
+			d.tree[edge] = true
+			d.levels[0].link(edge.A, edge.B)
+			d.treeAt[0][edge.A] = append(d.treeAt[0][edge.A], edge)
+			d.treeAt[0][edge.B] = append(d.treeAt[0][edge.B], edge)
+			continue
+		}
+		// Edges arrive in order, so the lists stay sorted.
+		d.nonTreeAt[0][edge.A] = append(d.nonTreeAt[0][edge.A], edge)
+		d.nonTreeAt[0][edge.B] = append(d.nonTreeAt[0][edge.B], edge)
+	}
+	for _, v := range vertices {
+		d.refresh(v, 0)
+	}
+	return d
+}
+
+// has reports whether edge is still in the graph.
+func (d *decrementalForest) has(edge Edge) bool {
+	_, ok := d.level[edge]
+	return ok
+}
+
+// inForest reports whether edge is a forest edge.
+func (d *decrementalForest) inForest(edge Edge) bool {
+	return d.tree[edge]
+}
+
+// remove deletes edge. If it was a forest edge, the lightest edge reconnecting
+// the two trees becomes a forest edge and is returned.
+func (d *decrementalForest) remove(edge Edge) (Edge, bool) {
+	lvl, ok := d.level[edge]
+	if !ok {
+		return Edge{}, false
+	}
+	delete(d.level, edge)
+	if !d.tree[edge] {
+		d.removeNonTree(edge, lvl)
+		return Edge{}, false
+	}
+	delete(d.tree, edge)
+	d.removeTree(edge, lvl)
+	for i := 0; i <= lvl; i++ {
+		d.levels[i].cut(edge.A, edge.B)
+	}
+	for i := lvl; i >= 0; i-- {
+		if replacement, found := d.replace(edge.A, edge.B, i); found {
+			return replacement, true
+		}
+	}
+	return Edge{}, false
+}
+
+// replace looks for the lightest level-i edge reconnecting the trees of a and b
+// on level i. The level-i edges that stay inside the smaller tree are moved up a
+// level; the smaller tree has at most n/2^(i+1) vertices, so it fits there.
+func (d *decrementalForest) replace(a, b types.EntityID, i int) (Edge, bool) {
+	forest := d.levels[i]
+	small := a
+	if forest.treeSize(b) < forest.treeSize(a) {
+		small = b
+	}
+	for {
+		v, ok := forest.findMarked(small)
+		if !ok {
+			break
+		}
+		edge := d.treeAt[i][v][0]
+		d.removeTree(edge, i)
+		d.level[edge] = i + 1
+		d.addTree(edge, i+1)
+		d.levels[i+1].link(edge.A, edge.B)
+	}
+	for {
+		edge, ok := forest.minEdge(small)
+		if !ok {
+			return Edge{}, false
+		}
+		d.removeNonTree(edge, i)
+		if forest.connected(edge.A, edge.B) {
+			d.level[edge] = i + 1
+			d.addNonTree(edge, i+1)
+			continue
+		}
+		d.tree[edge] = true
+		d.addTree(edge, i)
+		for j := 0; j <= i; j++ {
+			d.levels[j].link(edge.A, edge.B)
+		}
+		return edge, true
+	}
+}
+
+func (d *decrementalForest) addTree(edge Edge, i int) {
+	for _, v := range [2]types.EntityID{edge.A, edge.B} {
+		d.treeAt[i][v] = append(d.treeAt[i][v], edge)
+		d.refresh(v, i)
+	}
+}
+
+func (d *decrementalForest) removeTree(edge Edge, i int) {
+	for _, v := range [2]types.EntityID{edge.A, edge.B} {
+		d.treeAt[i][v] = removeEdge(d.treeAt[i][v], edge)
+		d.refresh(v, i)
+	}
+}
+
+func (d *decrementalForest) addNonTree(edge Edge, i int) {
+	for _, v := range [2]types.EntityID{edge.A, edge.B} {
+		list := d.nonTreeAt[i][v]
+		at := sort.Search(len(list), func(k int) bool { return d.less(edge, list[k]) })
+		list = append(list, Edge{})
+		copy(list[at+1:], list[at:])
+		list[at] = edge
+		d.nonTreeAt[i][v] = list
+		d.refresh(v, i)
+	}
+}
+
+func (d *decrementalForest) removeNonTree(edge Edge, i int) {
+	for _, v := range [2]types.EntityID{edge.A, edge.B} {
+		d.nonTreeAt[i][v] = removeEdge(d.nonTreeAt[i][v], edge)
+		d.refresh(v, i)
+	}
+}
+
+// refresh copies the level-i edge lists of v into its tour node.
+func (d *decrementalForest) refresh(v types.EntityID, i int) {
+	forest := d.levels[i]
+	forest.setMark(v, len(d.treeAt[i][v]) > 0)
+	if list := d.nonTreeAt[i][v]; len(list) > 0 {
+		forest.setMin(v, list[0], true)
+	} else {
+		forest.setMin(v, Edge{}, false)
+	}
+}
+
+// removeEdge deletes edge from list, keeping the order of the rest.
+func removeEdge(list []Edge, edge Edge) []Edge {
+	for k, e := range list {
+		if e == edge {
+			return append(list[:k], list[k+1:]...)
+		}
+	}
+	return list
+}
@@ -0,0 +1,454 @@
+// internal/utils/dynamic_forest.go
+package utils
+
+import (
+	"go-tower-defense/internal/types"
+	"math"
+	"sort"
+)
+
+// Edge is an undirected edge between two entities. Use MakeEdge to build it,
+// so that A < B and the same pair always maps to the same value.
+type Edge struct {
+	A, B types.EntityID
+}
+
+// MakeEdge returns the normalized edge between a and b.
+func MakeEdge(a, b types.EntityID) Edge {
+	if a > b {
+		a, b = b, a
+	}
+	return Edge{A: a, B: b}
+}
+
+// DynamicForest maintains the minimum spanning forest of a graph under vertex and
+// edge insertions and deletions. Edges are compared with the order passed to
+// NewDynamicForest; it must be a strict total order, which makes the forest unique
+// and equal to what Kruskal's algorithm builds for the same graph.
+//
+// The forest is stored in a link-cut tree where every forest edge is a node of its
+// own, so connectivity and "heaviest edge on a path" queries take O(log n)
+// amortized. Inserting an edge is a single path query plus at most one swap.
+//
+// Deletions rely on the identity MSF(G0 ∪ I) = MSF(MSF(G0) ∪ I). Every epoch
+// edits the graph is snapshotted into a decrementalForest, which keeps
+// MSF(G0) of the snapshot minus the edges deleted since in O(log^2 n) amortized
+// per deletion. The forest is the MSF of that forest plus the edges inserted
+// since the snapshot, so a replacement for a deleted forest edge is either the
+// one decrementalForest reports or one of the spare edges: snapshot forest
+// edges and inserted edges that are not in the forest. There are at most as
+// many spares as insertions since the snapshot, so with epoch ~ sqrt(m) a
+// deletion costs O(sqrt(m) log n) amortized, rebuilds included.
+type DynamicForest struct {
+	less func(a, b Edge) bool
+
+	nodes      []lctNode // index 0 is the nil node
+	free       []int
+	vertexNode map[types.EntityID]int
+	edgeNode   map[Edge]int // forest edges only
+
+	adj     map[types.EntityID]map[types.EntityID]struct{} // all edges
+	treeAdj map[types.EntityID]map[types.EntityID]struct{} // forest edges
+	edges   int
+
+	base     *decrementalForest // snapshot minus the edges deleted since
+	inserted map[Edge]bool      // edges added since the snapshot
+	spare    map[Edge]bool      // base forest and inserted edges outside the forest
+	edits    int                // edits since the snapshot
+}
+
+// minEpoch keeps small graphs from being snapshotted on every few edits.
+const minEpoch = 16
+
+type lctNode struct {
+	child  [2]int
+	parent int
+	flip   bool
+	isEdge bool
+	edge   Edge
+	max    int // node of the heaviest edge in the splay subtree, 0 if none
+}
+
+// NewDynamicForest creates an empty forest. less must not change its answer for
+// edges currently stored in the forest; to reorder an edge, remove and re-add it.
+func NewDynamicForest(less func(a, b Edge) bool) *DynamicForest {
+	return &DynamicForest{
+		less:       less,
+		nodes:      make([]lctNode, 1),
+		vertexNode: make(map[types.EntityID]int),
+		edgeNode:   make(map[Edge]int),
+		adj:        make(map[types.EntityID]map[types.EntityID]struct{}),
+		treeAdj:    make(map[types.EntityID]map[types.EntityID]struct{}),
+		base:       newDecrementalForest(nil, nil, less),
+		inserted:   make(map[Edge]bool),
+		spare:      make(map[Edge]bool),
+	}
+}
+
+// HasVertex reports whether v is in the graph.
+func (f *DynamicForest) HasVertex(v types.EntityID) bool {
+	_, ok := f.vertexNode[v]
+	return ok
+}
+
+// AddVertex adds an isolated vertex. Adding an existing vertex does nothing.
+func (f *DynamicForest) AddVertex(v types.EntityID) {
+	if f.HasVertex(v) {
+		return
+	}
+	f.vertexNode[v] = f.newNode(lctNode{})
+	f.adj[v] = make(map[types.EntityID]struct{})
+	f.treeAdj[v] = make(map[types.EntityID]struct{})
+}
+
+// RemoveVertex removes v with all its edges, reconnecting the forest around it.
+func (f *DynamicForest) RemoveVertex(v types.EntityID) {
+	if !f.HasVertex(v) {
+		return
+	}
+	// Non-forest edges go first, so they are never picked as a replacement
+	// for the forest edges removed next.
+	for _, u := range sortedKeys(f.adj[v]) {
+		if _, inTree := f.treeAdj[v][u]; !inTree {
+			f.RemoveEdge(v, u)
+		}
+	}
+	for _, u := range sortedKeys(f.treeAdj[v]) {
+		f.RemoveEdge(v, u)
+	}
+	f.freeNode(f.vertexNode[v])
+	delete(f.vertexNode, v)
+	delete(f.adj, v)
+	delete(f.treeAdj, v)
+}
+
+// HasEdge reports whether the edge a-b is in the graph.
+func (f *DynamicForest) HasEdge(a, b types.EntityID) bool {
+	_, ok := f.adj[a][b]
+	return ok
+}
+
+// InForest reports whether the edge a-b belongs to the spanning forest.
+func (f *DynamicForest) InForest(a, b types.EntityID) bool {
+	_, ok := f.treeAdj[a][b]
+	return ok
+}
+
+// AddEdge adds the edge a-b. Both vertices must already exist; self-loops and
+// existing edges are ignored.
+func (f *DynamicForest) AddEdge(a, b types.EntityID) {
+	if a == b || !f.HasVertex(a) || !f.HasVertex(b) || f.HasEdge(a, b) {
+		return
+	}
+	f.adj[a][b] = struct{}{}
+	f.adj[b][a] = struct{}{}
+	f.edges++
+
+	edge := MakeEdge(a, b)
+	f.inserted[edge] = true
+	f.offer(edge)
+	f.edited()
+}
+
+// RemoveEdge removes the edge a-b. If it was a forest edge, the lightest edge
+// reconnecting the two halves (if any) takes its place.
+func (f *DynamicForest) RemoveEdge(a, b types.EntityID) {
+	if !f.HasEdge(a, b) {
+		return
+	}
+	delete(f.adj[a], b)
+	delete(f.adj[b], a)
+	f.edges--
+
+	edge := MakeEdge(a, b)
+	var replacement Edge
+	replaced := false
+	if f.inserted[edge] {
+		delete(f.inserted, edge)
+	} else {
+		replacement, replaced = f.base.remove(edge)
+	}
+	switch {
+	case f.InForest(a, b):
+		f.cutEdge(edge)
+		if replaced {
+			f.spare[replacement] = true
+		}
+		f.reconnect()
+	case replaced:
+		delete(f.spare, edge)
+		f.offer(replacement)
+	default:
+		delete(f.spare, edge)
+	}
+	f.edited()
+}
+
+// Connected reports whether a and b are in the same tree of the forest.
+func (f *DynamicForest) Connected(a, b types.EntityID) bool {
+	va, okA := f.vertexNode[a]
+	vb, okB := f.vertexNode[b]
+	return okA && okB && f.findRoot(va) == f.findRoot(vb)
+}
+
+// ForestNeighbors returns the forest neighbors of v in ascending ID order.
+func (f *DynamicForest) ForestNeighbors(v types.EntityID) []types.EntityID {
+	return sortedKeys(f.treeAdj[v])
+}
+
+// Edges returns all forest edges, sorted by A and then B.
+func (f *DynamicForest) Edges() []Edge {
+	edges := make([]Edge, 0, len(f.edgeNode))
+	for edge := range f.edgeNode {
+		edges = append(edges, edge)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].A != edges[j].A {
+			return edges[i].A < edges[j].A
+		}
+		return edges[i].B < edges[j].B
+	})
+	return edges
+}
+
+// offer puts edge into the forest if it is lighter than the heaviest edge on the
+// forest path between its ends; whatever is left out becomes a spare.
+func (f *DynamicForest) offer(edge Edge) {
+	va, vb := f.vertexNode[edge.A], f.vertexNode[edge.B]
+	if f.findRoot(va) != f.findRoot(vb) {
+		f.linkEdge(edge)
+		return
+	}
+	f.makeRoot(va)
+	f.access(vb)
+	heaviest := f.nodes[f.nodes[vb].max].edge
+	if !f.less(edge, heaviest) {
+		f.spare[edge] = true
+		return
+	}
+	f.cutEdge(heaviest)
+	f.linkEdge(edge)
+	f.spare[heaviest] = true
+}
+
+// reconnect links the lightest spare edge whose ends a cut has separated. Every
+// spare joins two vertices of one tree before the cut, so a spare with ends in
+// different trees crosses it.
+func (f *DynamicForest) reconnect() {
+	var best Edge
+	found := false
+	for edge := range f.spare {
+		if f.Connected(edge.A, edge.B) {
+			continue
+		}
+		if !found || f.less(edge, best) {
+			best, found = edge, true
+		}
+	}
+	if found {
+		delete(f.spare, best)
+		f.linkEdge(best)
+	}
+}
+
+// edited counts an edit and snapshots the graph once the epoch is over.
+func (f *DynamicForest) edited() {
+	f.edits++
+	if f.edits < minEpoch+int(math.Sqrt(float64(f.edges))) {
+		return
+	}
+	vertices := make([]types.EntityID, 0, len(f.vertexNode))
+	for v := range f.vertexNode {
+		vertices = append(vertices, v)
+	}
+	sort.Slice(vertices, func(i, j int) bool { return vertices[i] < vertices[j] })
+	edges := make([]Edge, 0, f.edges)
+	for _, v := range vertices {
+		for u := range f.adj[v] {
+			if v < u {
+				edges = append(edges, MakeEdge(v, u))
+			}
+		}
+	}
+	f.base = newDecrementalForest(vertices, edges, f.less)
+	f.inserted = make(map[Edge]bool)
+	f.spare = make(map[Edge]bool)
+	f.edits = 0
+}
+
+func (f *DynamicForest) linkEdge(edge Edge) {
+	x := f.newNode(lctNode{isEdge: true, edge: edge})
+	f.nodes[x].max = x
+	f.edgeNode[edge] = x
+	f.link(f.vertexNode[edge.A], x)
+	f.link(x, f.vertexNode[edge.B])
+	f.treeAdj[edge.A][edge.B] = struct{}{}
+	f.treeAdj[edge.B][edge.A] = struct{}{}
+}
+
+func (f *DynamicForest) cutEdge(edge Edge) {
+	x := f.edgeNode[edge]
+	f.cut(f.vertexNode[edge.A], x)
+	f.cut(x, f.vertexNode[edge.B])
+	f.freeNode(x)
+	delete(f.edgeNode, edge)
+	delete(f.treeAdj[edge.A], edge.B)
+	delete(f.treeAdj[edge.B], edge.A)
+}
+
+func (f *DynamicForest) newNode(n lctNode) int {
+	if len(f.free) > 0 {
+		x := f.free[len(f.free)-1]
+		f.free = f.free[:len(f.free)-1]
+		f.nodes[x] = n
+		return x
+	}
+	f.nodes = append(f.nodes, n)
+	return len(f.nodes) - 1
+}
+
+func (f *DynamicForest) freeNode(x int) {
+	f.nodes[x] = lctNode{}
+	f.free = append(f.free, x)
+}
+
+// --- Link-cut tree primitives ---
+
+func (f *DynamicForest) isSplayRoot(x int) bool {
+	p := f.nodes[x].parent
+	return p == 0 || (f.nodes[p].child[0] != x && f.nodes[p].child[1] != x)
+}
+
+func (f *DynamicForest) push(x int) {
+	n := &f.nodes[x]
+	if !n.flip {
+		return
+	}
+	n.child[0], n.child[1] = n.child[1], n.child[0]
+	for _, c := range n.child {
+		if c != 0 {
+			f.nodes[c].flip = !f.nodes[c].flip
+		}
+	}
+	n.flip = false
+}
+
+func (f *DynamicForest) pull(x int) {
+	n := &f.nodes[x]
+	best := 0
+	if n.isEdge {
+		best = x
+	}
+	for _, c := range n.child {
+		if c == 0 {
+			continue
+		}
+		if m := f.nodes[c].max; m != 0 && (best == 0 || f.less(f.nodes[best].edge, f.nodes[m].edge)) {
+			best = m
+		}
+	}
+	n.max = best
+}
+
+func (f *DynamicForest) rotate(x int) {
+	p := f.nodes[x].parent
+	g := f.nodes[p].parent
+	dir := 0
+	if f.nodes[p].child[1] == x {
+		dir = 1
+	}
+	if !f.isSplayRoot(p) {
+		if f.nodes[g].child[0] == p {
+			f.nodes[g].child[0] = x
+		} else {
+			f.nodes[g].child[1] = x
+		}
+	}
+	f.nodes[x].parent = g
+	inner := f.nodes[x].child[dir^1]
+	f.nodes[p].child[dir] = inner
+	if inner != 0 {
+		f.nodes[inner].parent = p
+	}
+	f.nodes[x].child[dir^1] = p
+	f.nodes[p].parent = x
+	f.pull(p)
+	f.pull(x)
+}
+
+func (f *DynamicForest) splay(x int) {
+	path := []int{x}
+	for y := x; !f.isSplayRoot(y); y = f.nodes[y].parent {
+		path = append(path, f.nodes[y].parent)
+	}
+	for i := len(path) - 1; i >= 0; i-- {
+		f.push(path[i])
+	}
+	for !f.isSplayRoot(x) {
+		p := f.nodes[x].parent
+		if !f.isSplayRoot(p) {
+			g := f.nodes[p].parent
+			if (f.nodes[g].child[0] == p) == (f.nodes[p].child[0] == x) {
+				f.rotate(p)
+			} else {
+				f.rotate(x)
+			}
+		}
+		f.rotate(x)
+	}
+}
+
+// access makes the path from the tree root to x preferred and splays x to the top.
+func (f *DynamicForest) access(x int) {
+	last := 0
+	for y := x; y != 0; y = f.nodes[y].parent {
+		f.splay(y)
+		f.nodes[y].child[1] = last
+		f.pull(y)
+		last = y
+	}
+	f.splay(x)
+}
+
+func (f *DynamicForest) makeRoot(x int) {
+	f.access(x)
+	f.nodes[x].flip = !f.nodes[x].flip
+}
+
+func (f *DynamicForest) findRoot(x int) int {
+	f.access(x)
+	for {
+		f.push(x)
+		left := f.nodes[x].child[0]
+		if left == 0 {
+			break
+		}
+		x = left
+	}
+	f.splay(x)
+	return x
+}
+
+func (f *DynamicForest) link(x, y int) {
+	f.makeRoot(x)
+	f.nodes[x].parent = y
+}
+
+// cut removes the tree edge between adjacent nodes x and y.
+func (f *DynamicForest) cut(x, y int) {
+	f.makeRoot(x)
+	f.access(y)
+	f.push(y)
+	f.nodes[y].child[0] = 0
+	f.nodes[x].parent = 0
+	f.pull(y)
+}
+
+func sortedKeys(set map[types.EntityID]struct{}) []types.EntityID {
+	keys := make([]types.EntityID, 0, len(set))
+	for id := range set {
+		keys = append(keys, id)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
+	return keys
+}
@@ -0,0 +1,87 @@
+// internal/utils/dynamic_forest_test.go
+package utils
+
+import (
+	"go-tower-defense/internal/types"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestDynamicForestMatchesKruskal applies random vertex and edge edits, long
+// enough to span several snapshots, and checks after every edit that the forest
+// equals Kruskal's forest of the same graph.
+func TestDynamicForestMatchesKruskal(t *testing.T) {
+	const (
+		seeds    = 10
+		steps    = 3000
+		vertices = 40
+	)
+	for seed := int64(1); seed <= seeds; seed++ {
+		rng := NewPRNGService(seed)
+		weight := make(map[Edge]int)
+		for a := types.EntityID(1); a <= vertices; a++ {
+			for b := a + 1; b <= vertices; b++ {
+				weight[MakeEdge(a, b)] = rng.Intn(8) // ties fall back to the IDs
+			}
+		}
+		less := func(x, y Edge) bool {
+			if weight[x] != weight[y] {
+				return weight[x] < weight[y]
+			}
+			if x.A != y.A {
+				return x.A < y.A
+			}
+			return x.B < y.B
+		}
+
+		forest := NewDynamicForest(less)
+		for step := 0; step < steps; step++ {
+			a := types.EntityID(rng.Intn(vertices) + 1)
+			b := types.EntityID(rng.Intn(vertices) + 1)
+			switch roll := rng.Intn(100); {
+			case roll < 3:
+				forest.RemoveVertex(a)
+			case roll < 10:
+				forest.AddVertex(a)
+			case roll < 55:
+				forest.AddVertex(a)
+				forest.AddVertex(b)
+				forest.AddEdge(a, b)
+			default:
+				forest.RemoveEdge(a, b)
+			}
+			if got, want := forest.Edges(), kruskalForest(forest, less); !reflect.DeepEqual(got, want) {
+				t.Fatalf("seed %d, step %d: forest differs from Kruskal\n got: %v\nwant: %v", seed, step, got, want)
+			}
+		}
+	}
+}
+
+// kruskalForest rebuilds the spanning forest of the graph stored in forest.
+func kruskalForest(forest *DynamicForest, less func(a, b Edge) bool) []Edge {
+	var edges []Edge
+	for v, neighbors := range forest.adj {
+		for u := range neighbors {
+			if v < u {
+				edges = append(edges, MakeEdge(v, u))
+			}
+		}
+	}
+	sort.Slice(edges, func(i, j int) bool { return less(edges[i], edges[j]) })
+	components := NewUnionFind()
+	result := []Edge{}
+	for _, edge := range edges {
+		if components.Find(edge.A) != components.Find(edge.B) {
+			components.Union(edge.A, edge.B)
+			result = append(result, edge)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].A != result[j].A {
+			return result[i].A < result[j].A
+		}
+		return result[i].B < result[j].B
+	})
+	return result
+}
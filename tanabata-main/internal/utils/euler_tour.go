@@ -0,0 +1,254 @@
+// internal/utils/euler_tour.go
+package utils
+
+import "go-tower-defense/internal/types"
+
+// eulerTourForest stores every tree of a forest as its Euler tour in a treap, so
+// link, cut, connectivity and per-tree aggregates take O(log n) expected. Each
+// vertex has one node in the tour of its tree and each tree edge has two arc
+// nodes. Vertex nodes carry the two values decrementalForest searches a tree for:
+// a mark (the vertex has tree edges of this level) and the lightest non-tree
+// edge of this level at the vertex.
+type eulerTourForest struct {
+	less       func(a, b Edge) bool
+	nodes      []ettNode // index 0 is the nil node
+	free       []int
+	vertexNode map[types.EntityID]int
+	arcNodes   map[Edge][2]int
+	seed       uint64
+}
+
+type ettNode struct {
+	left, right, parent int
+	priority            uint64
+	isVertex            bool
+	vertex              types.EntityID
+
+	size     int // nodes in the subtree
+	vertices int // vertex nodes in the subtree
+
+	mark, anyMark  bool
+	hasMin, hasAgg bool
+	min, agg       Edge // lightest edge at this vertex / in the subtree
+}
+
+func newEulerTourForest(less func(a, b Edge) bool) *eulerTourForest {
+	return &eulerTourForest{
+		less:       less,
+		nodes:      make([]ettNode, 1),
+		vertexNode: make(map[types.EntityID]int),
+		arcNodes:   make(map[Edge][2]int),
+	}
+}
+
+// addVertex adds v as a single-vertex tree.
+func (f *eulerTourForest) addVertex(v types.EntityID) {
+	f.vertexNode[v] = f.newNode(ettNode{isVertex: true, vertex: v})
+}
+
+func (f *eulerTourForest) connected(a, b types.EntityID) bool {
+	return f.root(f.vertexNode[a]) == f.root(f.vertexNode[b])
+}
+
+// treeSize returns the number of vertices in the tree containing v.
+func (f *eulerTourForest) treeSize(v types.EntityID) int {
+	return f.nodes[f.root(f.vertexNode[v])].vertices
+}
+
+// link joins the trees of a and b with the edge a-b.
+func (f *eulerTourForest) link(a, b types.EntityID) {
+	ta, tb := f.reroot(a), f.reroot(b)
+	arcAB, arcBA := f.newNode(ettNode{}), f.newNode(ettNode{})
+	f.arcNodes[MakeEdge(a, b)] = [2]int{arcAB, arcBA}
+	f.merge(f.merge(f.merge(ta, arcAB), tb), arcBA)
+}
+
+// cut removes the tree edge a-b, splitting its tree in two.
+func (f *eulerTourForest) cut(a, b types.EntityID) {
+	edge := MakeEdge(a, b)
+	arcs := f.arcNodes[edge]
+	delete(f.arcNodes, edge)
+	first, second := arcs[0], arcs[1]
+	if f.rank(first) > f.rank(second) {
+		first, second = second, first
+	}
+	// The tour is X first Y second Z; Y is the tour of the cut-off subtree.
+	before, rest := f.split(f.root(first), f.rank(first))
+	_, rest = f.split(rest, 1)
+	_, rest = f.split(rest, f.rank(second))
+	_, after := f.split(rest, 1)
+	f.merge(before, after)
+	f.freeNode(first)
+	f.freeNode(second)
+}
+
+// setMark sets the mark of vertex v.
+func (f *eulerTourForest) setMark(v types.EntityID, mark bool) {
+	x := f.vertexNode[v]
+	f.nodes[x].mark = mark
+	f.pullUp(x)
+}
+
+// setMin sets the lightest edge at vertex v; ok == false clears it.
+func (f *eulerTourForest) setMin(v types.EntityID, edge Edge, ok bool) {
+	x := f.vertexNode[v]
+	f.nodes[x].min, f.nodes[x].hasMin = edge, ok
+	f.pullUp(x)
+}
+
+// findMarked returns a marked vertex in the tree containing v.
+func (f *eulerTourForest) findMarked(v types.EntityID) (types.EntityID, bool) {
+	x := f.root(f.vertexNode[v])
+	if !f.nodes[x].anyMark {
+		return 0, false
+	}
+	for {
+		n := &f.nodes[x]
+		switch {
+		case n.left != 0 && f.nodes[n.left].anyMark:
+			x = n.left
+		case n.mark:
+			return n.vertex, true
+		default:
+			x = n.right
+		}
+	}
+}
+
+// minEdge returns the lightest edge stored at the vertices of v's tree.
+func (f *eulerTourForest) minEdge(v types.EntityID) (Edge, bool) {
+	n := &f.nodes[f.root(f.vertexNode[v])]
+	return n.agg, n.hasAgg
+}
+
+// reroot rotates the tour of v's tree so that it starts at v and returns the
+// treap root.
+func (f *eulerTourForest) reroot(v types.EntityID) int {
+	x := f.vertexNode[v]
+	before, after := f.split(f.root(x), f.rank(x))
+	return f.merge(after, before)
+}
+
+func (f *eulerTourForest) root(x int) int {
+	for f.nodes[x].parent != 0 {
+		x = f.nodes[x].parent
+	}
+	return x
+}
+
+// rank returns the position of x in its tour.
+func (f *eulerTourForest) rank(x int) int {
+	r := f.size(f.nodes[x].left)
+	for p := f.nodes[x].parent; p != 0; x, p = p, f.nodes[p].parent {
+		if f.nodes[p].right == x {
+			r += f.size(f.nodes[p].left) + 1
+		}
+	}
+	return r
+}
+
+func (f *eulerTourForest) size(x int) int {
+	if x == 0 {
+		return 0
+	}
+	return f.nodes[x].size
+}
+
+// split cuts treap t into its first k nodes and the rest.
+func (f *eulerTourForest) split(t, k int) (int, int) {
+	if t == 0 {
+		return 0, 0
+	}
+	f.nodes[t].parent = 0
+	if left := f.nodes[t].left; k <= f.size(left) {
+		l, r := f.split(left, k)
+		f.setLeft(t, r)
+		return l, t
+	}
+	l, r := f.split(f.nodes[t].right, k-f.size(f.nodes[t].left)-1)
+	f.setRight(t, l)
+	return t, r
+}
+
+// merge concatenates treaps a and b.
+func (f *eulerTourForest) merge(a, b int) int {
+	if a == 0 || b == 0 {
+		return a + b
+	}
+	if f.nodes[a].priority > f.nodes[b].priority {
+		f.setRight(a, f.merge(f.nodes[a].right, b))
+		return a
+	}
+	f.setLeft(b, f.merge(a, f.nodes[b].left))
+	return b
+}
+
+func (f *eulerTourForest) setLeft(x, c int) {
+	f.nodes[x].left = c
+	if c != 0 {
+		f.nodes[c].parent = x
+	}
+	f.pull(x)
+}
+
+func (f *eulerTourForest) setRight(x, c int) {
+	f.nodes[x].right = c
+	if c != 0 {
+		f.nodes[c].parent = x
+	}
+	f.pull(x)
+}
+
+func (f *eulerTourForest) pull(x int) {
+	n := &f.nodes[x]
+	n.size, n.vertices = 1, 0
+	if n.isVertex {
+		n.vertices = 1
+	}
+	n.anyMark = n.mark
+	n.hasAgg, n.agg = n.hasMin, n.min
+	for _, c := range [2]int{n.left, n.right} {
+		if c == 0 {
+			continue
+		}
+		child := &f.nodes[c]
+		n.size += child.size
+		n.vertices += child.vertices
+		n.anyMark = n.anyMark || child.anyMark
+		if child.hasAgg && (!n.hasAgg || f.less(child.agg, n.agg)) {
+			n.hasAgg, n.agg = true, child.agg
+		}
+	}
+}
+
+func (f *eulerTourForest) pullUp(x int) {
+	for ; x != 0; x = f.nodes[x].parent {
+		f.pull(x)
+	}
+}
+
+func (f *eulerTourForest) newNode(n ettNode) int {
+	// splitmix64 keeps the treap shapes, and so the run time, reproducible.
+	f.seed += 0x9e3779b97f4a7c15
+	z := f.seed
+	z = (z ^ z>>30) * 0xbf58476d1ce4e5b9
+	z = (z ^ z>>27) * 0x94d049bb133111eb
+	n.priority = z ^ z>>31
+
+	var x int
+	if len(f.free) > 0 {
+		x = f.free[len(f.free)-1]
+		f.free = f.free[:len(f.free)-1]
+		f.nodes[x] = n
+	} else {
+		f.nodes = append(f.nodes, n)
+		x = len(f.nodes) - 1
+	}
+	f.pull(x)
+	return x
+}
+
+func (f *eulerTourForest) freeNode(x int) {
+	f.nodes[x] = ettNode{}
+	f.free = append(f.free, x)
+}
@@ -3,6 +3,7 @@ package utils
 
 import (
 	"go-tower-defense/internal/defs"
+	"hash/fnv"
 	"math/rand"
 	"time"
 )
@@ -10,7 +11,38 @@ import (
 // PRNGService — это обертка над стандартным генератором случайных чисел Go,
 // которая позволяет использовать предсказуемый (seeded) рандом во всей игре.
 type PRNGService struct {
-	rng *rand.Rand
+	rng  *rand.Rand
+	src  *countingSource
+	seed int64
+}
+
+// PRNGState — сериализуемое состояние потока: сид и число выбранных из источника значений.
+// Этого достаточно, чтобы восстановить поток в точности с того же места.
+type PRNGState struct {
+	Seed  int64
+	Draws uint64
+}
+
+// countingSource оборачивает стандартный источник и считает выборки из него.
+// Каждый вызов Int63 или Uint64 сдвигает внутренний генератор ровно на один шаг.
+type countingSource struct {
+	src   rand.Source64
+	draws uint64
+}
+
+func (c *countingSource) Int63() int64 {
+	c.draws++
+	return c.src.Int63()
+}
+
+func (c *countingSource) Uint64() uint64 {
+	c.draws++
+	return c.src.Uint64()
+}
+
+func (c *countingSource) Seed(seed int64) {
+	c.src.Seed(seed)
+	c.draws = 0
 }
 
 // NewPRNGService создает новый экземпляр сервиса с указанным сидом.
@@ -19,12 +51,57 @@ func NewPRNGService(seed int64) *PRNGService {
 	if seed == 0 {
 		seed = time.Now().UnixNano()
 	}
-	source := rand.NewSource(seed)
+	return newSeededPRNG(seed)
+}
+
+// newSeededPRNG создает сервис строго с указанным сидом (0 — тоже валидный сид).
+func newSeededPRNG(seed int64) *PRNGService {
+	src := &countingSource{src: rand.NewSource(seed).(rand.Source64)}
 	return &PRNGService{
-		rng: rand.New(source),
+		rng:  rand.New(src),
+		src:  src,
+		seed: seed,
 	}
 }
 
+// Seed возвращает фактический сид генератора (после подстановки времени для 0).
+func (s *PRNGService) Seed() int64 {
+	return s.seed
+}
+
+// State возвращает текущее состояние потока для сохранения.
+func (s *PRNGService) State() PRNGState {
+	return PRNGState{Seed: s.seed, Draws: s.src.draws}
+}
+
+// Restore возвращает поток в сохраненное состояние: пересевает источник
+// и прокручивает его на нужное число выборок.
+func (s *PRNGService) Restore(state PRNGState) {
+	s.seed = state.Seed
+	s.src.Seed(state.Seed)
+	for s.src.draws < state.Draws {
+		s.src.Uint64()
+	}
+}
+
+// Fork создает независимый дочерний поток с именем name.
+// Сид потомка зависит только от сида родителя и имени, а не от того, сколько
+// чисел уже было выбрано, поэтому добавление нового потока или лишний вызов
+// в одной подсистеме не сдвигает случайность в остальных.
+func (s *PRNGService) Fork(name string) *PRNGService {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return newSeededPRNG(int64(splitmix64(uint64(s.seed) ^ h.Sum64())))
+}
+
+// splitmix64 — финализатор SplitMix64, хорошо перемешивающий биты сида.
+func splitmix64(x uint64) uint64 {
+	x += 0x9E3779B97F4A7C15
+	x = (x ^ (x >> 30)) * 0xBF58476D1CE4E5B9
+	x = (x ^ (x >> 27)) * 0x94D049BB133111EB
+	return x ^ (x >> 31)
+}
+
 // Intn возвращает случайное целое число в диапазоне [0, n).
 func (s *PRNGService) Intn(n int) int {
 	return s.rng.Intn(n)
@@ -47,7 +124,6 @@ func (s *PRNGService) Shuffle(n int, swap func(i, j int)) {
 	s.rng.Shuffle(n, swap)
 }
 
-
 // ChooseWeighted выполняет взвешенный случайный выбор из таблицы выпадения.
 // Он суммирует все веса, выбирает случайное число в этом диапазо-не,
 // а затем находит элемент, которому соответствует это число.
@@ -77,4 +153,4 @@ func (s *PRNGService) ChooseWeighted(entries []defs.LootEntry) string {
 
 	// Этот код не должен быть достижим, но на всякий случай
 	return entries[len(entries)-1].TowerID
-}
\ No newline at end of file
+}
@@ -0,0 +1,92 @@
+// pkg/hexmap/articulation.go
+package hexmap
+
+// SeparatingHexes возвращает гексы, без которых от start нельзя дойти до goal
+// по проходимым гексам: саму цель и точки сочленения графа проходимых гексов,
+// лежащие на каждом пути start → goal. Сам start считается проходимым и в
+// результат не входит. Если пути нет, возвращает nil; если start и есть цель —
+// пустой срез.
+//
+// Точки сочленения ищутся одним обходом в глубину (алгоритм Тарьяна) от start:
+// вершина v отделяет goal, если goal лежит в поддереве ее ребенка c и из этого
+// поддерева нет обратного ребра выше v (low[c] >= disc[v]).
+func SeparatingHexes(hm *HexMap, start, goal Hex) []Hex {
+	neighbors := func(hex Hex) []Hex {
+		var result []Hex
+		for _, n := range hex.Neighbors(hm) {
+			if n == start || hm.IsPassable(n) {
+				result = append(result, n)
+			}
+		}
+		return result
+	}
+
+	type frame struct {
+		hex       Hex
+		neighbors []Hex
+		next      int
+	}
+	disc := map[Hex]int{start: 0}
+	low := map[Hex]int{start: 0}
+	parent := make(map[Hex]Hex)
+	timer := 1
+	stack := []frame{{hex: start, neighbors: neighbors(start)}}
+	for len(stack) > 0 {
+		top := &stack[len(stack)-1]
+		if top.next < len(top.neighbors) {
+			hex, n := top.hex, top.neighbors[top.next]
+			top.next++
+			if d, seen := disc[n]; seen {
+				low[hex] = min(low[hex], d)
+				continue
+			}
+			disc[n], low[n] = timer, timer
+			timer++
+			parent[n] = hex
+			stack = append(stack, frame{hex: n, neighbors: neighbors(n)})
+			continue
+		}
+		hex := top.hex
+		stack = stack[:len(stack)-1]
+		if len(stack) > 0 {
+			p := stack[len(stack)-1].hex
+			low[p] = min(low[p], low[hex])
+		}
+	}
+
+	if goal == start {
+		return []Hex{}
+	}
+	if _, reached := disc[goal]; !reached {
+		return nil
+	}
+	result := []Hex{goal}
+	for child := goal; child != start; child = parent[child] {
+		if v := parent[child]; v != start && low[child] >= disc[v] {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+// RouteCriticalHexes возвращает гексы, препятствие на которых перекрыло бы
+// маршрут какого-либо входа: разделяющие гексы каждого участка маршрута
+// (от входа или чекпоинта до следующего чекпоинта или выхода).
+// false — какой-то маршрут уже перекрыт.
+func (hm *HexMap) RouteCriticalHexes() (map[Hex]bool, bool) {
+	critical := make(map[Hex]bool)
+	for _, gate := range hm.Gates() {
+		start := gate.Entry
+		for _, goal := range append(append([]Hex(nil), gate.Checkpoints...), gate.Exit) {
+			hexes := SeparatingHexes(hm, start, goal)
+			if hexes == nil {
+				return nil, false
+			}
+			for _, hex := range hexes {
+				critical[hex] = true
+			}
+			start = goal
+		}
+	}
+	return critical, true
+}
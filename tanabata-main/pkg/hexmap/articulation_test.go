@@ -0,0 +1,84 @@
+// pkg/hexmap/articulation_test.go
+package hexmap
+
+import (
+	"math/rand"
+	"testing"
+)
+
+// TestRouteCriticalHexesMatchesBlockAndRecheck сравнивает критичные гексы со
+// сплошной проверкой: каждый проходимый гекс по очереди перекрывается, и поля
+// потока всех входов строятся заново.
+func TestRouteCriticalHexesMatchesBlockAndRecheck(t *testing.T) {
+	const (
+		maps   = 300
+		radius = 4
+	)
+	for seed := int64(1); seed <= maps; seed++ {
+		rng := rand.New(rand.NewSource(seed))
+		hm := randomRouteMap(rng, radius, 1+int(seed%3))
+
+		critical, open := hm.RouteCriticalHexes()
+		if want := NewRouteFlows(hm).Open(hm); open != want {
+			t.Fatalf("map %d: open = %v, want %v", seed, open, want)
+		}
+		if !open {
+			continue
+		}
+		for _, hex := range hm.SortedHexes() {
+			if !hm.IsPassable(hex) {
+				continue
+			}
+			hm.SetPassable(hex, false)
+			blocks := !NewRouteFlows(hm).Open(hm)
+			hm.SetPassable(hex, true)
+			if critical[hex] != blocks {
+				t.Fatalf("map %d (%d gates): hex %v critical = %v, but blocking it closes a route: %v",
+					seed, len(hm.Gates()), hex, critical[hex], blocks)
+			}
+		}
+	}
+}
+
+// randomRouteMap строит шестиугольную карту со случайными стенами и gates
+// входами; у каждого входа до двух чекпоинтов. Гексы маршрутов не повторяются,
+// кроме выхода, который входы могут делить.
+func randomRouteMap(rng *rand.Rand, radius, gates int) *HexMap {
+	hm := &HexMap{Tiles: make(map[Hex]Tile), Radius: radius}
+	for q := -radius; q <= radius; q++ {
+		for r := -radius; r <= radius; r++ {
+			if hex := (Hex{Q: q, R: r}); hex.Distance(Hex{}) <= radius {
+				hm.Tiles[hex] = Tile{Passable: rng.Intn(100) >= 40, CanPlaceTower: true}
+			}
+		}
+	}
+	hexes := hm.SortedHexes()
+	used := make(map[Hex]bool)
+	pick := func() Hex {
+		for {
+			hex := hexes[rng.Intn(len(hexes))]
+			if !used[hex] {
+				used[hex] = true
+				hm.SetPassable(hex, true)
+				return hex
+			}
+		}
+	}
+
+	exit := pick()
+	for g := 0; g < gates; g++ {
+		gate := Gate{Entry: pick(), Exit: exit, Checkpoints: []Hex{}}
+		if g > 0 && rng.Intn(2) == 0 {
+			gate.Exit = pick()
+		}
+		for n := rng.Intn(3); n > 0; n-- {
+			gate.Checkpoints = append(gate.Checkpoints, pick())
+		}
+		if g == 0 {
+			hm.Entry, hm.Exit, hm.Checkpoints = gate.Entry, gate.Exit, gate.Checkpoints
+		} else {
+			hm.ExtraGates = append(hm.ExtraGates, gate)
+		}
+	}
+	return hm
+}
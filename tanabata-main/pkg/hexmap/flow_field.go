@@ -0,0 +1,194 @@
+// pkg/hexmap/flow_field.go
+package hexmap
+
+import (
+	"container/heap"
+)
+
+// FlowField — поле расстояний до цели: для каждого проходимого гекса хранится
+// стоимость самого быстрого пути до Goal с учетом местности (HexMap.MoveCost).
+// Поле строится одним обратным проходом Дейкстры от цели, после чего шаг
+// к цели из любого гекса находится без поиска, по соседям.
+type FlowField struct {
+	Goal Hex
+	dist map[Hex]int
+}
+
+// NewFlowField строит поле расстояний до goal по проходимым гексам карты.
+// Непроходимая цель дает пустое поле: к ней нет пути ни из одного гекса.
+func NewFlowField(hm *HexMap, goal Hex) *FlowField {
+	field := &FlowField{Goal: goal, dist: make(map[Hex]int)}
+	if !hm.IsPassable(goal) {
+		return field
+	}
+	field.dist[goal] = 0
+	pq := &PriorityQueue{}
+	heap.Init(pq)
+	heap.Push(pq, &Node{Hex: goal, Cost: 0})
+	for pq.Len() > 0 {
+		current := heap.Pop(pq).(*Node)
+		if current.Cost > field.dist[current.Hex] {
+			continue // Устаревшая запись очереди
+		}
+		// Шаг с соседа на current стоит столько же, сколько вход на current
+		stepCost := hm.MoveCost(current.Hex)
+		for _, neighbor := range current.Hex.Neighbors(hm) {
+			if !hm.IsPassable(neighbor) {
+				continue
+			}
+			newCost := current.Cost + stepCost
+			if old, seen := field.dist[neighbor]; !seen || newCost < old {
+				field.dist[neighbor] = newCost
+				heap.Push(pq, &Node{Hex: neighbor, Cost: newCost})
+			}
+		}
+	}
+	return field
+}
+
+// Distance возвращает стоимость пути от hex до цели; false — цель недостижима.
+func (f *FlowField) Distance(hex Hex) (int, bool) {
+	d, ok := f.dist[hex]
+	return d, ok
+}
+
+// Reaches сообщает, можно ли дойти от hex до цели. Сам hex, как и в Next,
+// может быть непроходимым.
+func (f *FlowField) Reaches(hm *HexMap, hex Hex) bool {
+	if _, ok := f.dist[hex]; ok || hex == f.Goal {
+		return ok
+	}
+	_, ok := f.Next(hm, hex)
+	return ok
+}
+
+// Next возвращает следующий гекс на пути от hex к цели: проходимого соседа
+// с наименьшей суммой стоимости шага и его расстояния. При равенстве выбирается
+// первый сосед в порядке Neighbors, поэтому шаг детерминирован. Сам hex может
+// быть непроходимым (враг, сбитый с пути). false — hex уже цель или пути нет.
+func (f *FlowField) Next(hm *HexMap, hex Hex) (Hex, bool) {
+	if hex == f.Goal {
+		return Hex{}, false
+	}
+	best, bestCost, found := Hex{}, 0, false
+	for _, neighbor := range hex.Neighbors(hm) {
+		d, ok := f.dist[neighbor]
+		if !ok {
+			continue
+		}
+		if cost := hm.MoveCost(neighbor) + d; !found || cost < bestCost {
+			best, bestCost, found = neighbor, cost, true
+		}
+	}
+	return best, found
+}
+
+// PathFrom строит путь от start до цели по полю, включая оба конца.
+// Возвращает nil, если цель недостижима.
+func (f *FlowField) PathFrom(hm *HexMap, start Hex) []Hex {
+	path := []Hex{start}
+	for current := start; current != f.Goal; {
+		next, ok := f.Next(hm, current)
+		if !ok {
+			return nil
+		}
+		path = append(path, next)
+		current = next
+	}
+	return path
+}
+
+// GateFlow — поля потока маршрута одного входа, по одному на каждый участок:
+// i-й участок ведет к i-му чекпоинту, последний — к выходу.
+type GateFlow struct {
+	Gate     Gate
+	Segments []*FlowField
+}
+
+// NewGateFlow строит поля всех участков маршрута входа gate.
+func NewGateFlow(hm *HexMap, gate Gate) *GateFlow {
+	gf := &GateFlow{Gate: gate}
+	for _, goal := range gate.Checkpoints {
+		gf.Segments = append(gf.Segments, NewFlowField(hm, goal))
+	}
+	gf.Segments = append(gf.Segments, NewFlowField(hm, gate.Exit))
+	return gf
+}
+
+// segmentStart возвращает гекс, с которого начинается участок segment.
+func (gf *GateFlow) segmentStart(segment int) Hex {
+	if segment == 0 {
+		return gf.Gate.Entry
+	}
+	return gf.Gate.Checkpoints[segment-1]
+}
+
+// Open сообщает, проходим ли маршрут входа целиком.
+func (gf *GateFlow) Open(hm *HexMap) bool {
+	for i, field := range gf.Segments {
+		if !field.Reaches(hm, gf.segmentStart(i)) {
+			return false
+		}
+	}
+	return true
+}
+
+// Route строит маршрут входа от Entry через все чекпоинты до Exit.
+// Возвращает nil, если какой-то участок перекрыт.
+func (gf *GateFlow) Route(hm *HexMap) []Hex {
+	return gf.RouteFrom(hm, gf.Gate.Entry, 0)
+}
+
+// RouteFrom строит остаток маршрута от гекса hex, находящегося на участке
+// segment: до цели участка, затем по всем следующим участкам до выхода.
+// По нему возвращается на маршрут враг, сбитый с пути. Номер участка за
+// пределами маршрута считается последним участком.
+func (gf *GateFlow) RouteFrom(hm *HexMap, hex Hex, segment int) []Hex {
+	segment = max(0, min(segment, len(gf.Segments)-1))
+	route := []Hex{hex}
+	current := hex
+	for _, field := range gf.Segments[segment:] {
+		part := field.PathFrom(hm, current)
+		if part == nil {
+			return nil
+		}
+		route = append(route, part[1:]...)
+		current = field.Goal
+	}
+	return route
+}
+
+// RouteFlows — поля потока маршрутов всех входов карты (индекс — номер входа,
+// см. HexMap.Gates). Строятся один раз после изменения проходимости карты
+// и используются всеми врагами вместо поиска пути для каждого.
+type RouteFlows struct {
+	Gates []*GateFlow
+}
+
+// NewRouteFlows строит поля потока для всех входов карты.
+func NewRouteFlows(hm *HexMap) *RouteFlows {
+	rf := &RouteFlows{}
+	for _, gate := range hm.Gates() {
+		rf.Gates = append(rf.Gates, NewGateFlow(hm, gate))
+	}
+	return rf
+}
+
+// Gate возвращает поля входа i; неизвестный номер — основной вход,
+// как и HexMap.Gate.
+func (rf *RouteFlows) Gate(i int) *GateFlow {
+	if i < 0 || i >= len(rf.Gates) {
+		return rf.Gates[0]
+	}
+	return rf.Gates[i]
+}
+
+// Open сообщает, открыты ли маршруты всех входов.
+func (rf *RouteFlows) Open(hm *HexMap) bool {
+	for _, gf := range rf.Gates {
+		if !gf.Open(hm) {
+			return false
+		}
+	}
+	return true
+}
@@ -3,12 +3,19 @@ package hexmap
 
 import (
 	"go-tower-defense/internal/config"
-	"math/rand"
+	"sort"
 )
 
+// RandomSource — минимальный источник случайности для генерации карты.
+// Позволяет передавать сидированный генератор, не завися от internal/utils.
+type RandomSource interface {
+	Intn(n int) int
+}
+
 type Tile struct {
 	Passable      bool
 	CanPlaceTower bool
+	Terrain       Terrain // Местность (terrain.go); влияет на стоимость пути и скорость врагов
 }
 
 type HexMap struct {
@@ -17,9 +24,20 @@ type HexMap struct {
 	Entry       Hex
 	Exit        Hex
 	Checkpoints []Hex
+	ExtraGates  []Gate // Дополнительные входы; основной маршрут — Entry, Checkpoints, Exit
+}
+
+// Gate — вход врагов со своим маршрутом: чекпоинты по порядку и выход.
+// Несколько входов могут вести к одному выходу.
+type Gate struct {
+	Entry       Hex
+	Exit        Hex
+	Checkpoints []Hex
 }
 
-func NewHexMap() *HexMap {
+// NewHexMap генерирует карту, беря всю случайность из rng.
+// Одинаковый rng (сид) дает одинаковую карту.
+func NewHexMap(rng RandomSource) *HexMap {
 	tiles := make(map[Hex]Tile)
 	radius := config.MapRadius
 
@@ -54,7 +72,7 @@ func NewHexMap() *HexMap {
 			{Q: -D, R: D}, {Q: D, R: -D}, {Q: 0, R: -D},
 			{Q: 0, R: D}, {Q: D, R: 0}, {Q: -D, R: 0},
 		}
-		k := rand.Intn(6)
+		k := rng.Intn(6)
 		hm.Checkpoints = append(baseCheckpoints[k:], baseCheckpoints[:k]...)
 	}
 
@@ -68,14 +86,14 @@ func NewHexMap() *HexMap {
 		if hm.sectionIntersectsExclusion(section, exclusion) {
 			continue
 		}
-		action := rand.Intn(10)
+		action := rng.Intn(10)
 		if action < 3 {
 			hm.addOuterSection(section)
 		} else if action < 6 {
 			hm.removeInnerSection(section)
 		}
 	}
-	hm.processCorners(exclusion)
+	hm.processCorners(exclusion, rng)
 	hm.postProcessMap()
 
 	return hm
@@ -93,10 +111,53 @@ func (hm *HexMap) GetBorderHexes(borderRadius int) map[Hex]struct{} {
 	return border
 }
 
-// Остальные функции остаются ��ез изменений
+// IsCheckpoint сообщает, является ли гекс чекпоинтом маршрута любого входа.
 func (hm *HexMap) IsCheckpoint(hex Hex) bool {
-	for _, cp := range hm.Checkpoints {
-		if cp == hex {
+	for _, gate := range hm.Gates() {
+		for _, cp := range gate.Checkpoints {
+			if cp == hex {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Gates возвращает все входы карты с их маршрутами; первый — основной
+// (Entry, Checkpoints, Exit), за ним ExtraGates.
+func (hm *HexMap) Gates() []Gate {
+	gates := make([]Gate, 0, 1+len(hm.ExtraGates))
+	gates = append(gates, Gate{Entry: hm.Entry, Exit: hm.Exit, Checkpoints: hm.Checkpoints})
+	return append(gates, hm.ExtraGates...)
+}
+
+// Gate возвращает вход с индексом i (см. Gates); неизвестный индекс дает основной вход.
+func (hm *HexMap) Gate(i int) Gate {
+	if i > 0 && i <= len(hm.ExtraGates) {
+		return hm.ExtraGates[i-1]
+	}
+	return Gate{Entry: hm.Entry, Exit: hm.Exit, Checkpoints: hm.Checkpoints}
+}
+
+// GateHexes возвращает входы и выходы всех маршрутов без повторов.
+func (hm *HexMap) GateHexes() []Hex {
+	var hexes []Hex
+	seen := make(map[Hex]bool)
+	for _, gate := range hm.Gates() {
+		for _, hex := range []Hex{gate.Entry, gate.Exit} {
+			if !seen[hex] {
+				seen[hex] = true
+				hexes = append(hexes, hex)
+			}
+		}
+	}
+	return hexes
+}
+
+// IsGate сообщает, является ли гекс входом или выходом какого-либо маршрута.
+func (hm *HexMap) IsGate(hex Hex) bool {
+	for _, gate := range hm.Gates() {
+		if gate.Entry == hex || gate.Exit == hex {
 			return true
 		}
 	}
@@ -194,7 +255,7 @@ func (hm *HexMap) removeInnerSection(section []Hex) {
 	}
 }
 
-func (hm *HexMap) processCorners(exclusion map[Hex]struct{}) {
+func (hm *HexMap) processCorners(exclusion map[Hex]struct{}, rng RandomSource) {
 	corners := []Hex{
 		{hm.Radius, 0}, {0, hm.Radius}, {-hm.Radius, hm.Radius},
 		{-hm.Radius, 0}, {0, -hm.Radius}, {hm.Radius, -hm.Radius},
@@ -204,7 +265,7 @@ func (hm *HexMap) processCorners(exclusion map[Hex]struct{}) {
 		if _, excluded := exclusion[corner]; excluded {
 			continue
 		}
-		action := rand.Intn(10)
+		action := rng.Intn(10)
 		if action < 3 {
 			var additions []Hex
 			switch corner {
@@ -315,6 +376,28 @@ func (hm *HexMap) GetHexesInRange(center Hex, radius int) []Hex {
 	return result
 }
 
+// SortedHexes возвращает все гексы карты в детерминированном порядке (по Q, затем по R).
+// Обход map в Go случаен, поэтому любой код, тратящий случайность или создающий
+// сущности при обходе карты, должен использовать этот порядок.
+func (hm *HexMap) SortedHexes() []Hex {
+	hexes := make([]Hex, 0, len(hm.Tiles))
+	for hex := range hm.Tiles {
+		hexes = append(hexes, hex)
+	}
+	SortHexes(hexes)
+	return hexes
+}
+
+// SortHexes сортирует срез гексов по Q, затем по R.
+func SortHexes(hexes []Hex) {
+	sort.Slice(hexes, func(i, j int) bool {
+		if hexes[i].Q != hexes[j].Q {
+			return hexes[i].Q < hexes[j].Q
+		}
+		return hexes[i].R < hexes[j].R
+	})
+}
+
 func (hm *HexMap) Contains(hex Hex) bool {
 	_, exists := hm.Tiles[hex]
 	return exists
@@ -325,6 +408,17 @@ func (hm *HexMap) GetNeighbors(h Hex) []Hex {
 	return h.Neighbors(hm)
 }
 
+// EntryPoints возвращает точки входа врагов; группы волн ссылаются на них по индексу.
+// Первая точка — основной вход, индексы совпадают с Gates.
+func (hm *HexMap) EntryPoints() []Hex {
+	gates := hm.Gates()
+	entries := make([]Hex, len(gates))
+	for i, gate := range gates {
+		entries[i] = gate.Entry
+	}
+	return entries
+}
+
 // Clone создает глубокую копию HexMap.
 func (hm *HexMap) Clone() *HexMap {
 	newTiles := make(map[Hex]Tile, len(hm.Tiles))
@@ -335,11 +429,18 @@ func (hm *HexMap) Clone() *HexMap {
 	newCheckpoints := make([]Hex, len(hm.Checkpoints))
 	copy(newCheckpoints, hm.Checkpoints)
 
+	var newGates []Gate
+	for _, gate := range hm.ExtraGates {
+		gate.Checkpoints = append([]Hex(nil), gate.Checkpoints...)
+		newGates = append(newGates, gate)
+	}
+
 	return &HexMap{
 		Tiles:       newTiles,
 		Radius:      hm.Radius,
 		Entry:       hm.Entry,
 		Exit:        hm.Exit,
 		Checkpoints: newCheckpoints,
+		ExtraGates:  newGates,
 	}
 }
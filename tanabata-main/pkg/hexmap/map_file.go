@@ -0,0 +1,457 @@
+// pkg/hexmap/map_file.go
+package hexmap
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// MapFileVersion — версия формата файла карты. Увеличивается при несовместимых изменениях.
+const MapFileVersion = 1
+
+// MapFile — карта, нарисованная вручную: тайлы, вход, выход, чекпоинты,
+// рудные жилы и заранее поставленные стены.
+//
+// На диске это JSON, в котором гекс записывается парой [q, r]:
+//
+//	{
+//	  "version": 1,
+//	  "name": "Пример",
+//	  "radius": 10,
+//	  "entry": [-11, 6], "exit": [11, -6],
+//	  "checkpoints": [[-7, 7], [7, -7]],
+//	  "gates": [{"entry": [0, -11], "exit": [11, -6], "checkpoints": [[0, -7]]}],
+//	  "tiles": [[0, 0], [0, 1], ...],   // Пусто — полный шестиугольник радиуса radius
+//	  "blocked": [[2, 3]],              // Непроходимые тайлы
+//	  "no_build": [[4, -1]],            // Тайлы, где нельзя строить
+//	  "terrain": {"mud": [[1, 2]], "water": [[-3, 0]]}, // Местность по именам из Terrains
+//	  "walls": [[3, 3]],                // Стены, стоящие с начала партии
+//	  "ore_veins": [{"hexes": [{"hex": [1, 1], "power": 35}]}]
+//	}
+//
+// entry, exit и checkpoints задают основной маршрут, gates — дополнительные входы
+// со своими маршрутами. Входы и выходы всегда добавляются как проходимые тайлы
+// без строительства. Вода сама делает тайл непроходимым и закрытым для строительства,
+// поэтому в blocked и no_build ее гексы не повторяются.
+type MapFile struct {
+	Name     string
+	Map      *HexMap
+	Walls    []Hex
+	OreVeins [][]OreHex // Жилы по номеру; номер выбирает правила жилы из ore_veins.json
+}
+
+// OreHex — гекс рудной жилы и его запас в процентах (как на подписи руды).
+type OreHex struct {
+	Hex   Hex
+	Power float64
+}
+
+// mapFileJSON — представление MapFile на диске.
+type mapFileJSON struct {
+	Version     int                 `json:"version"`
+	Name        string              `json:"name,omitempty"`
+	Radius      int                 `json:"radius"`
+	Entry       [2]int              `json:"entry"`
+	Exit        [2]int              `json:"exit"`
+	Checkpoints [][2]int            `json:"checkpoints"`
+	Gates       []gateJSON          `json:"gates,omitempty"`
+	Tiles       [][2]int            `json:"tiles,omitempty"`
+	Blocked     [][2]int            `json:"blocked,omitempty"`
+	NoBuild     [][2]int            `json:"no_build,omitempty"`
+	Terrain     map[string][][2]int `json:"terrain,omitempty"`
+	Walls       [][2]int            `json:"walls,omitempty"`
+	OreVeins    []oreVeinJSON       `json:"ore_veins,omitempty"`
+}
+
+type gateJSON struct {
+	Entry       [2]int   `json:"entry"`
+	Exit        [2]int   `json:"exit"`
+	Checkpoints [][2]int `json:"checkpoints"`
+}
+
+type oreVeinJSON struct {
+	Hexes []oreHexJSON `json:"hexes"`
+}
+
+type oreHexJSON struct {
+	Hex   [2]int  `json:"hex"`
+	Power float64 `json:"power"`
+}
+
+func hexFromPair(p [2]int) Hex {
+	return Hex{Q: p[0], R: p[1]}
+}
+
+func pairFromHex(h Hex) [2]int {
+	return [2]int{h.Q, h.R}
+}
+
+// LoadMapFile читает и проверяет файл карты.
+func LoadMapFile(path string) (*MapFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mf, err := ParseMapFile(data)
+	if err != nil {
+		return nil, fmt.Errorf("map %s: %w", path, err)
+	}
+	return mf, nil
+}
+
+// ParseMapFile разбирает JSON карты и проверяет ее (см. Validate).
+func ParseMapFile(data []byte) (*MapFile, error) {
+	mf, err := UnmarshalMapFile(data)
+	if err != nil {
+		return nil, err
+	}
+	if err := mf.Validate(); err != nil {
+		return nil, err
+	}
+	return mf, nil
+}
+
+// UnmarshalMapFile разбирает JSON карты, не проверяя, что на ней можно играть.
+// Так редактор открывает незаконченные карты.
+func UnmarshalMapFile(data []byte) (*MapFile, error) {
+	var raw mapFileJSON
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	if raw.Version != MapFileVersion {
+		return nil, fmt.Errorf("unsupported map version %d (want %d)", raw.Version, MapFileVersion)
+	}
+	if raw.Radius <= 0 {
+		return nil, fmt.Errorf("radius must be positive")
+	}
+
+	tiles := make(map[Hex]Tile)
+	if len(raw.Tiles) == 0 {
+		for q := -raw.Radius; q <= raw.Radius; q++ {
+			for r := max(-raw.Radius, -q-raw.Radius); r <= min(raw.Radius, -q+raw.Radius); r++ {
+				tiles[Hex{q, r}] = Tile{Passable: true, CanPlaceTower: true}
+			}
+		}
+	}
+	for _, p := range raw.Tiles {
+		hex := hexFromPair(p)
+		if _, dup := tiles[hex]; dup {
+			return nil, fmt.Errorf("tile %v is listed twice", p)
+		}
+		tiles[hex] = Tile{Passable: true, CanPlaceTower: true}
+	}
+	setFlag := func(list [][2]int, name string, apply func(*Tile)) error {
+		for _, p := range list {
+			tile, ok := tiles[hexFromPair(p)]
+			if !ok {
+				return fmt.Errorf("%s hex %v is not a tile", name, p)
+			}
+			apply(&tile)
+			tiles[hexFromPair(p)] = tile
+		}
+		return nil
+	}
+	if err := setFlag(raw.Blocked, "blocked", func(t *Tile) { t.Passable = false }); err != nil {
+		return nil, err
+	}
+	if err := setFlag(raw.NoBuild, "no_build", func(t *Tile) { t.CanPlaceTower = false }); err != nil {
+		return nil, err
+	}
+	for name, list := range raw.Terrain {
+		terrain, err := ParseTerrain(name)
+		if err != nil {
+			return nil, err
+		}
+		err = setFlag(list, name, func(t *Tile) {
+			if terrain == TerrainWater {
+				t.Passable, t.CanPlaceTower = false, false
+			}
+			t.Terrain = terrain
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	gates := []Gate{gateFromJSON(gateJSON{Entry: raw.Entry, Exit: raw.Exit, Checkpoints: raw.Checkpoints})}
+	for _, g := range raw.Gates {
+		gates = append(gates, gateFromJSON(g))
+	}
+	for _, gate := range gates {
+		for _, hex := range []Hex{gate.Entry, gate.Exit} {
+			tile, ok := tiles[hex]
+			if ok && !tile.Passable {
+				return nil, fmt.Errorf("entry and exit %v must not be blocked", hex)
+			}
+			tiles[hex] = Tile{Passable: true, CanPlaceTower: false, Terrain: tile.Terrain}
+		}
+	}
+
+	mf := &MapFile{
+		Name: raw.Name,
+		Map: &HexMap{
+			Tiles:       tiles,
+			Radius:      raw.Radius,
+			Entry:       gates[0].Entry,
+			Exit:        gates[0].Exit,
+			Checkpoints: gates[0].Checkpoints,
+			ExtraGates:  gates[1:],
+		},
+	}
+	for _, p := range raw.Walls {
+		mf.Walls = append(mf.Walls, hexFromPair(p))
+	}
+	for _, vein := range raw.OreVeins {
+		hexes := make([]OreHex, 0, len(vein.Hexes))
+		for _, h := range vein.Hexes {
+			hexes = append(hexes, OreHex{Hex: hexFromPair(h.Hex), Power: h.Power})
+		}
+		mf.OreVeins = append(mf.OreVeins, hexes)
+	}
+	return mf, nil
+}
+
+func gateFromJSON(g gateJSON) Gate {
+	gate := Gate{Entry: hexFromPair(g.Entry), Exit: hexFromPair(g.Exit), Checkpoints: []Hex{}}
+	for _, p := range g.Checkpoints {
+		gate.Checkpoints = append(gate.Checkpoints, hexFromPair(p))
+	}
+	return gate
+}
+
+func gateToJSON(gate Gate) gateJSON {
+	g := gateJSON{Entry: pairFromHex(gate.Entry), Exit: pairFromHex(gate.Exit)}
+	for _, cp := range gate.Checkpoints {
+		g.Checkpoints = append(g.Checkpoints, pairFromHex(cp))
+	}
+	return g
+}
+
+// Validate проверяет, что на карте можно играть: входы, выходы, чекпоинты, стены
+// и руда стоят на тайлах карты, и враги каждого входа проходят через все его
+// чекпоинты к выходу, даже когда стены уже стоят.
+func (mf *MapFile) Validate() error {
+	hm := mf.Map
+	if hm == nil {
+		return fmt.Errorf("map has no tiles")
+	}
+	for g, gate := range hm.Gates() {
+		if gate.Entry == gate.Exit {
+			return fmt.Errorf("gate %d: entry and exit must differ", g)
+		}
+		if !hm.IsPassable(gate.Entry) || !hm.IsPassable(gate.Exit) {
+			return fmt.Errorf("gate %d: entry and exit must be passable tiles", g)
+		}
+		seen := make(map[Hex]bool)
+		for i, cp := range gate.Checkpoints {
+			if !hm.IsPassable(cp) {
+				return fmt.Errorf("gate %d: checkpoint %d %v is not a passable tile", g, i+1, cp)
+			}
+			if hm.IsGate(cp) || seen[cp] {
+				return fmt.Errorf("gate %d: checkpoint %d %v repeats an entry, an exit or another checkpoint", g, i+1, cp)
+			}
+			seen[cp] = true
+		}
+	}
+
+	walled := hm.Clone()
+	for _, wall := range mf.Walls {
+		if !walled.IsPassable(wall) {
+			return fmt.Errorf("wall %v is not on a free passable tile", wall)
+		}
+		if hm.IsGate(wall) || hm.IsCheckpoint(wall) {
+			return fmt.Errorf("wall %v stands on an entry, an exit or a checkpoint", wall)
+		}
+		walled.SetPassable(wall, false)
+	}
+
+	oreVein := make(map[Hex]int)
+	for i, vein := range mf.OreVeins {
+		for _, ore := range vein {
+			if !hm.Contains(ore.Hex) {
+				return fmt.Errorf("ore vein %d: hex %v is not a tile", i, ore.Hex)
+			}
+			if ore.Power <= 0 {
+				return fmt.Errorf("ore vein %d: hex %v must have positive power", i, ore.Hex)
+			}
+			if other, dup := oreVein[ore.Hex]; dup {
+				return fmt.Errorf("ore hex %v belongs to veins %d and %d", ore.Hex, other, i)
+			}
+			oreVein[ore.Hex] = i
+		}
+	}
+
+	for g, gate := range walled.Gates() {
+		if walled.GateRoute(gate) == nil {
+			return fmt.Errorf("gate %d: no route from the entry through every checkpoint to the exit", g)
+		}
+	}
+	return nil
+}
+
+// Marshal записывает карту в JSON. Списки гексов пишутся плотно, по нескольку
+// гексов в строке, чтобы файл оставался удобным для правки руками.
+func (mf *MapFile) Marshal() ([]byte, error) {
+	hm := mf.Map
+	if hm == nil {
+		return nil, fmt.Errorf("map has no tiles")
+	}
+	raw := mapFileJSON{
+		Version: MapFileVersion,
+		Name:    mf.Name,
+		Radius:  hm.Radius,
+		Entry:   pairFromHex(hm.Entry),
+		Exit:    pairFromHex(hm.Exit),
+	}
+	for _, cp := range hm.Checkpoints {
+		raw.Checkpoints = append(raw.Checkpoints, pairFromHex(cp))
+	}
+	for _, gate := range hm.ExtraGates {
+		raw.Gates = append(raw.Gates, gateToJSON(gate))
+	}
+	for _, hex := range hm.SortedHexes() {
+		tile := hm.Tiles[hex]
+		raw.Tiles = append(raw.Tiles, pairFromHex(hex))
+		if tile.Terrain != TerrainPlain {
+			if raw.Terrain == nil {
+				raw.Terrain = make(map[string][][2]int)
+			}
+			raw.Terrain[tile.Terrain.String()] = append(raw.Terrain[tile.Terrain.String()], pairFromHex(hex))
+		}
+		if hm.IsGate(hex) || tile.Terrain == TerrainWater {
+			continue // Входы и выходы загрузчик добавляет сам, вода закрыта и так
+		}
+		if !tile.Passable {
+			raw.Blocked = append(raw.Blocked, pairFromHex(hex))
+		}
+		if !tile.CanPlaceTower {
+			raw.NoBuild = append(raw.NoBuild, pairFromHex(hex))
+		}
+	}
+	walls := append([]Hex(nil), mf.Walls...)
+	SortHexes(walls)
+	for _, wall := range walls {
+		raw.Walls = append(raw.Walls, pairFromHex(wall))
+	}
+	for _, vein := range mf.OreVeins {
+		hexes := make([]oreHexJSON, 0, len(vein))
+		for _, ore := range vein {
+			hexes = append(hexes, oreHexJSON{Hex: pairFromHex(ore.Hex), Power: ore.Power})
+		}
+		raw.OreVeins = append(raw.OreVeins, oreVeinJSON{Hexes: hexes})
+	}
+
+	name, err := json.Marshal(raw.Name)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	indentedHexList := func(indent, key string, hexes [][2]int, last bool) {
+		fmt.Fprintf(&buf, "%s%q: [", indent, key)
+		for i, p := range hexes {
+			if i > 0 {
+				buf.WriteString(",")
+			}
+			if i%12 == 0 {
+				buf.WriteString("\n" + indent + "  ")
+			} else {
+				buf.WriteString(" ")
+			}
+			fmt.Fprintf(&buf, "[%d, %d]", p[0], p[1])
+		}
+		if len(hexes) > 0 {
+			buf.WriteString("\n" + indent)
+		}
+		if last {
+			buf.WriteString("]\n")
+		} else {
+			buf.WriteString("],\n")
+		}
+	}
+	hexList := func(key string, hexes [][2]int) {
+		indentedHexList("  ", key, hexes, false)
+	}
+
+	fmt.Fprintf(&buf, "{\n  \"version\": %d,\n  \"name\": %s,\n  \"radius\": %d,\n", raw.Version, name, raw.Radius)
+	fmt.Fprintf(&buf, "  \"entry\": [%d, %d],\n  \"exit\": [%d, %d],\n", raw.Entry[0], raw.Entry[1], raw.Exit[0], raw.Exit[1])
+	hexList("checkpoints", raw.Checkpoints)
+	buf.WriteString("  \"gates\": [")
+	for i, gate := range raw.Gates {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		fmt.Fprintf(&buf, "\n    {\"entry\": [%d, %d], \"exit\": [%d, %d], \"checkpoints\": [", gate.Entry[0], gate.Entry[1], gate.Exit[0], gate.Exit[1])
+		for j, p := range gate.Checkpoints {
+			if j > 0 {
+				buf.WriteString(", ")
+			}
+			fmt.Fprintf(&buf, "[%d, %d]", p[0], p[1])
+		}
+		buf.WriteString("]}")
+	}
+	if len(raw.Gates) > 0 {
+		buf.WriteString("\n  ")
+	}
+	buf.WriteString("],\n")
+	hexList("tiles", raw.Tiles)
+	hexList("blocked", raw.Blocked)
+	hexList("no_build", raw.NoBuild)
+	buf.WriteString("  \"terrain\": {")
+	var terrainNames []string
+	for t := Terrain(0); t < TerrainCount; t++ {
+		if _, ok := raw.Terrain[t.String()]; ok {
+			terrainNames = append(terrainNames, t.String())
+		}
+	}
+	if len(terrainNames) > 0 {
+		buf.WriteString("\n")
+	}
+	for i, name := range terrainNames {
+		indentedHexList("    ", name, raw.Terrain[name], i == len(terrainNames)-1)
+	}
+	if len(terrainNames) > 0 {
+		buf.WriteString("  ")
+	}
+	buf.WriteString("},\n")
+	hexList("walls", raw.Walls)
+	buf.WriteString("  \"ore_veins\": [")
+	for i, vein := range raw.OreVeins {
+		if i > 0 {
+			buf.WriteString(",")
+		}
+		buf.WriteString("\n    {\"hexes\": [")
+		for j, ore := range vein.Hexes {
+			if j > 0 {
+				buf.WriteString(",")
+			}
+			if j%4 == 0 {
+				buf.WriteString("\n      ")
+			} else {
+				buf.WriteString(" ")
+			}
+			data, err := json.Marshal(ore)
+			if err != nil {
+				return nil, err
+			}
+			buf.Write(data)
+		}
+		buf.WriteString("\n    ]}")
+	}
+	if len(raw.OreVeins) > 0 {
+		buf.WriteString("\n  ")
+	}
+	buf.WriteString("]\n}\n")
+	return buf.Bytes(), nil
+}
+
+// Save записывает карту в файл. Карта не проверяется, чтобы редактор мог
+// сохранить незаконченную работу; игра проверит ее при загрузке.
+func (mf *MapFile) Save(path string) error {
+	data, err := mf.Marshal()
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
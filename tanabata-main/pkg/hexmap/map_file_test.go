@@ -0,0 +1,183 @@
+// pkg/hexmap/map_file_test.go
+package hexmap
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// newTestMapFile строит небольшую карту радиуса 3 со всем, что умеет формат:
+// вторым входом, местностью, закрытыми тайлами, стенами и двумя жилами руды.
+func newTestMapFile() *MapFile {
+	const radius = 3
+	hm := &HexMap{
+		Tiles:       make(map[Hex]Tile),
+		Radius:      radius,
+		Entry:       Hex{Q: -3, R: 0},
+		Exit:        Hex{Q: 3, R: 0},
+		Checkpoints: []Hex{{Q: 0, R: -2}},
+		ExtraGates:  []Gate{{Entry: Hex{Q: 0, R: 3}, Exit: Hex{Q: 3, R: 0}, Checkpoints: []Hex{}}},
+	}
+	for q := -radius; q <= radius; q++ {
+		for r := -radius; r <= radius; r++ {
+			if hex := (Hex{Q: q, R: r}); hex.Distance(Hex{}) <= radius {
+				hm.Tiles[hex] = Tile{Passable: true, CanPlaceTower: true}
+			}
+		}
+	}
+	for _, gate := range hm.Gates() {
+		hm.Tiles[gate.Entry] = Tile{Passable: true}
+		hm.Tiles[gate.Exit] = Tile{Passable: true}
+	}
+	hm.Tiles[Hex{Q: 1, R: 1}] = Tile{Passable: true, CanPlaceTower: true, Terrain: TerrainMud}
+	hm.Tiles[Hex{Q: -1, R: 1}] = Tile{Terrain: TerrainWater}
+	hm.Tiles[Hex{Q: 2, R: -1}] = Tile{Passable: true, CanPlaceTower: true, Terrain: TerrainHighGround}
+	hm.Tiles[Hex{Q: -2, R: 2}] = Tile{CanPlaceTower: true}
+	hm.Tiles[Hex{Q: 1, R: -2}] = Tile{Passable: true}
+	return &MapFile{
+		Name:  "Тест",
+		Map:   hm,
+		Walls: []Hex{{Q: 2, R: 0}, {Q: -1, R: 2}},
+		OreVeins: [][]OreHex{
+			{{Hex: Hex{Q: 0, R: 0}, Power: 40}, {Hex: Hex{Q: 1, R: 0}, Power: 25.5}},
+			{{Hex: Hex{Q: -2, R: 1}, Power: 60}},
+		},
+	}
+}
+
+// TestMapFileRoundTrip записывает карты в JSON и читает обратно: тайлы, входы,
+// чекпоинты, стены и руда должны совпасть, а повторная запись — дать те же байты.
+func TestMapFileRoundTrip(t *testing.T) {
+	files := map[string]*MapFile{"generated": newTestMapFile()}
+	paths, err := filepath.Glob("../../assets/maps/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(paths) == 0 {
+		t.Fatal("no map files in assets/maps")
+	}
+	for _, path := range paths {
+		mf, err := LoadMapFile(path)
+		if err != nil {
+			t.Fatalf("load %s: %v", path, err)
+		}
+		files[filepath.Base(path)] = mf
+	}
+
+	for name, mf := range files {
+		t.Run(name, func(t *testing.T) {
+			if err := mf.Validate(); err != nil {
+				t.Fatalf("source map is invalid: %v", err)
+			}
+			data, err := mf.Marshal()
+			if err != nil {
+				t.Fatalf("marshal: %v", err)
+			}
+			got, err := ParseMapFile(data)
+			if err != nil {
+				t.Fatalf("parse marshaled map: %v\n%s", err, data)
+			}
+			if got.Name != mf.Name {
+				t.Errorf("name = %q, want %q", got.Name, mf.Name)
+			}
+			if !reflect.DeepEqual(got.Map.Tiles, mf.Map.Tiles) {
+				for hex, tile := range mf.Map.Tiles {
+					if got.Map.Tiles[hex] != tile {
+						t.Errorf("tile %v = %+v, want %+v", hex, got.Map.Tiles[hex], tile)
+					}
+				}
+				t.Fatalf("tiles differ: %d tiles, want %d", len(got.Map.Tiles), len(mf.Map.Tiles))
+			}
+			if got.Map.Radius != mf.Map.Radius {
+				t.Errorf("radius = %d, want %d", got.Map.Radius, mf.Map.Radius)
+			}
+			if !reflect.DeepEqual(got.Map.Gates(), mf.Map.Gates()) {
+				t.Errorf("gates = %v, want %v", got.Map.Gates(), mf.Map.Gates())
+			}
+			wantWalls := append([]Hex(nil), mf.Walls...)
+			SortHexes(wantWalls)
+			if !reflect.DeepEqual(got.Walls, wantWalls) {
+				t.Errorf("walls = %v, want %v", got.Walls, wantWalls)
+			}
+			if !reflect.DeepEqual(got.OreVeins, mf.OreVeins) {
+				t.Errorf("ore veins = %v, want %v", got.OreVeins, mf.OreVeins)
+			}
+
+			again, err := got.Marshal()
+			if err != nil {
+				t.Fatalf("marshal again: %v", err)
+			}
+			if !bytes.Equal(again, data) {
+				t.Errorf("second marshal differs from the first:\n%s\n---\n%s", data, again)
+			}
+		})
+	}
+}
+
+// TestMapFileValidateRejects портит корректную карту по одному и проверяет,
+// что Validate отклоняет каждую поломку с понятной ошибкой.
+func TestMapFileValidateRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		corrupt func(mf *MapFile)
+		want    string
+	}{
+		{
+			name:    "blocked entry",
+			corrupt: func(mf *MapFile) { mf.Map.SetPassable(mf.Map.Entry, false) },
+			want:    "entry and exit must be passable",
+		},
+		{
+			name:    "blocked exit of the second gate",
+			corrupt: func(mf *MapFile) { mf.Map.SetPassable(mf.Map.ExtraGates[0].Exit, false) },
+			want:    "entry and exit must be passable",
+		},
+		{
+			name: "ore hex in two veins",
+			corrupt: func(mf *MapFile) {
+				mf.OreVeins[1] = append(mf.OreVeins[1], mf.OreVeins[0][0])
+			},
+			want: "belongs to veins 0 and 1",
+		},
+		{
+			name: "walls around the entry",
+			corrupt: func(mf *MapFile) {
+				for _, hex := range mf.Map.GetNeighbors(mf.Map.Entry) {
+					if mf.Map.IsPassable(hex) {
+						mf.Walls = append(mf.Walls, hex)
+					}
+				}
+			},
+			want: "gate 0: no route",
+		},
+		{
+			name: "walls around the checkpoint",
+			corrupt: func(mf *MapFile) {
+				for _, hex := range mf.Map.GetNeighbors(mf.Map.Checkpoints[0]) {
+					if mf.Map.IsPassable(hex) && !mf.Map.IsGate(hex) {
+						mf.Walls = append(mf.Walls, hex)
+					}
+				}
+			},
+			want: "gate 0: no route",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mf := newTestMapFile()
+			if err := mf.Validate(); err != nil {
+				t.Fatalf("map is invalid before the change: %v", err)
+			}
+			tc.corrupt(mf)
+			err := mf.Validate()
+			if err == nil {
+				t.Fatalf("Validate accepted the map")
+			}
+			if !strings.Contains(err.Error(), tc.want) {
+				t.Fatalf("Validate error %q does not mention %q", err, tc.want)
+			}
+		})
+	}
+}
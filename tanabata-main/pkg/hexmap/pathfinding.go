@@ -5,7 +5,8 @@ import (
 	"container/heap"
 )
 
-// AStar находит кратчайший путь от start до goal
+// AStar находит самый быстрый путь от start до goal: стоимость шага зависит
+// от местности тайла (HexMap.MoveCost).
 func AStar(start, goal Hex, hm *HexMap) []Hex {
 	pq := &PriorityQueue{}
 	heap.Init(pq)
@@ -22,10 +23,10 @@ func AStar(start, goal Hex, hm *HexMap) []Hex {
 			if !hm.IsPassable(neighbor) {
 				continue
 			}
-			newCost := costSoFar[current.Hex] + 1
+			newCost := costSoFar[current.Hex] + hm.MoveCost(neighbor)
 			if _, exists := costSoFar[neighbor]; !exists || newCost < costSoFar[neighbor] {
 				costSoFar[neighbor] = newCost
-				priority := newCost + neighbor.Distance(goal)
+				priority := newCost + neighbor.Distance(goal)*cheapestMoveCost
 				heap.Push(pq, &Node{Hex: neighbor, Cost: priority, Parent: current})
 				cameFrom[neighbor] = current
 			}
@@ -34,6 +35,24 @@ func AStar(start, goal Hex, hm *HexMap) []Hex {
 	return nil // Нет пути
 }
 
+// CheckpointRoute строит маршрут врагов основного входа: от Entry через все
+// чекпоинты по порядку до Exit. Возвращает nil, если какой-то участок перекрыт.
+func (hm *HexMap) CheckpointRoute() []Hex {
+	return hm.GateRoute(hm.Gate(0))
+}
+
+// GateRoute строит маршрут врагов входа gate: от входа через его чекпоинты
+// по порядку до выхода, по полям потока (см. GateFlow). Возвращает nil,
+// если какой-то участок перекрыт.
+func (hm *HexMap) GateRoute(gate Gate) []Hex {
+	return NewGateFlow(hm, gate).Route(hm)
+}
+
+// RoutesOpen сообщает, открыты ли маршруты всех входов карты.
+func (hm *HexMap) RoutesOpen() bool {
+	return NewRouteFlows(hm).Open(hm)
+}
+
 // PriorityQueue для A*
 type PriorityQueue []*Node
 
@@ -0,0 +1,98 @@
+// pkg/hexmap/terrain.go
+package hexmap
+
+import "fmt"
+
+// Terrain — тип местности тайла. Нулевое значение — обычная земля,
+// поэтому тайлы без местности ведут себя как раньше.
+type Terrain uint8
+
+const (
+	TerrainPlain      Terrain = iota // Обычная земля
+	TerrainMud                       // Грязь: наземные враги идут медленнее
+	TerrainRoad                      // Дорога: наземные враги идут быстрее
+	TerrainWater                     // Вода: непроходима для наземных врагов, летающие ее пересекают; строить нельзя
+	TerrainHighGround                // Возвышенность: башни на ней бьют дальше
+	TerrainCount
+)
+
+// PlainMoveCost — стоимость шага по обычной земле в A*. Стоимости остальных
+// местностей обратны их множителю скорости, поэтому A* выбирает самый быстрый путь.
+const PlainMoveCost = 6
+
+// TerrainInfo — свойства типа местности.
+type TerrainInfo struct {
+	Name            string  // Имя в файле карты
+	MoveCost        int     // Стоимость шага на тайл в A*
+	SpeedMultiplier float64 // Множитель скорости наземных врагов на тайле
+	RangeBonus      int     // Прибавка к дальности башни, стоящей на тайле
+}
+
+// Terrains — свойства местностей, индекс — Terrain.
+var Terrains = [TerrainCount]TerrainInfo{
+	TerrainPlain:      {Name: "plain", MoveCost: PlainMoveCost, SpeedMultiplier: 1},
+	TerrainMud:        {Name: "mud", MoveCost: 12, SpeedMultiplier: 0.5},
+	TerrainRoad:       {Name: "road", MoveCost: 4, SpeedMultiplier: 1.5},
+	TerrainWater:      {Name: "water", MoveCost: PlainMoveCost, SpeedMultiplier: 1},
+	TerrainHighGround: {Name: "high_ground", MoveCost: 8, SpeedMultiplier: 0.75, RangeBonus: 1},
+}
+
+// Info возвращает свойства местности; неизвестное значение считается обычной землей.
+func (t Terrain) Info() TerrainInfo {
+	if t >= TerrainCount {
+		return Terrains[TerrainPlain]
+	}
+	return Terrains[t]
+}
+
+func (t Terrain) String() string {
+	return t.Info().Name
+}
+
+// ParseTerrain находит местность по имени из файла карты.
+func ParseTerrain(name string) (Terrain, error) {
+	for t := Terrain(0); t < TerrainCount; t++ {
+		if Terrains[t].Name == name {
+			return t, nil
+		}
+	}
+	return TerrainPlain, fmt.Errorf("unknown terrain %q", name)
+}
+
+// TerrainAt возвращает местность гекса; гекс вне карты — обычная земля.
+func (hm *HexMap) TerrainAt(hex Hex) Terrain {
+	return hm.Tiles[hex].Terrain
+}
+
+// SetTerrain меняет местность существующего тайла. Вода делает тайл непроходимым
+// и закрытым для строительства; тайл, переставший быть водой, снова открыт.
+func (hm *HexMap) SetTerrain(hex Hex, terrain Terrain) {
+	tile, ok := hm.Tiles[hex]
+	if !ok {
+		return
+	}
+	switch {
+	case terrain == TerrainWater:
+		tile.Passable, tile.CanPlaceTower = false, false
+	case tile.Terrain == TerrainWater:
+		tile.Passable, tile.CanPlaceTower = true, true
+	}
+	tile.Terrain = terrain
+	hm.Tiles[hex] = tile
+}
+
+// MoveCost возвращает стоимость шага на гекс для A*.
+func (hm *HexMap) MoveCost(hex Hex) int {
+	return hm.TerrainAt(hex).Info().MoveCost
+}
+
+// cheapestMoveCost — наименьшая стоимость шага среди всех местностей.
+// Эвристика A* умножает на нее расстояние, чтобы не переоценивать путь по дорогам.
+// Она не зависит от карты, поэтому A* не обходит тайлы, чтобы ее найти.
+var cheapestMoveCost = func() int {
+	cheapest := PlainMoveCost
+	for _, info := range Terrains {
+		cheapest = min(cheapest, info.MoveCost)
+	}
+	return cheapest
+}()
@@ -39,28 +39,36 @@ func NewHexRenderer(hexMap *hexmap.HexMap, customColors map[hexmap.Hex]rl.Color)
 	}
 }
 
+// terrainColors — цвета тайлов с местностью; обычная земля рисуется цветом по умолчанию.
+var terrainColors = map[hexmap.Terrain]rl.Color{
+	hexmap.TerrainMud:        config.TerrainMudColorRL,
+	hexmap.TerrainRoad:       config.TerrainRoadColorRL,
+	hexmap.TerrainWater:      config.TerrainWaterColorRL,
+	hexmap.TerrainHighGround: config.TerrainHighGroundColorRL,
+}
+
 // Draw рендерит всю карту, используя предварительно созданные модели.
 func (r *HexRenderer) Draw() {
 	// Рисуем все гексы
 	for hex := range r.hexMap.Tiles {
 		var color rl.Color
-		// Приоритет цветов: чекпоинты > вход/выход > руда > по умолчанию
-		isCheckpoint := false
-		for _, cp := range r.hexMap.Checkpoints {
-			if hex == cp {
-				isCheckpoint = true
-				break
-			}
+		// Приоритет цветов: чекпоинты > вход/выход > руда > местность > по умолчанию
+		isEntry, isExit := false, false
+		for _, gate := range r.hexMap.Gates() {
+			isEntry = isEntry || hex == gate.Entry
+			isExit = isExit || hex == gate.Exit
 		}
 
-		if isCheckpoint {
+		if r.hexMap.IsCheckpoint(hex) {
 			color = config.CheckpointColorRL
-		} else if hex == r.hexMap.Entry {
+		} else if isEntry {
 			color = config.EntryColorRL
-		} else if hex == r.hexMap.Exit {
+		} else if isExit {
 			color = config.ExitColorRL
 		} else if customColor, ok := r.customColors[hex]; ok {
 			color = customColor
+		} else if terrainColor, ok := terrainColors[r.hexMap.TerrainAt(hex)]; ok {
+			color = terrainColor
 		} else {
 			color = config.PassableColorRL // Цвет по умолчанию
 		}
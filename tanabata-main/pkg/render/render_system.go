@@ -1,5 +1,5 @@
-// internal/system/render.go
-package system
+// pkg/render/render_system.go
+package render
 
 import (
 	"go-tower-defense/internal/assets"
@@ -92,8 +92,9 @@ func (s *RenderSystemRL) SetCamera(camera *rl.Camera3D) {
 	s.camera = camera
 }
 
-// Update кэширует данные и выполняет frustum culling
-func (s *RenderSystemRL) Update(deltaTime float64) {
+// Update кэширует данные и выполняет frustum culling.
+// Таймеры лазеров и эффектов вулкана обновляет VisualEffectSystem.
+func (s *RenderSystemRL) Update() {
 	if s.camera == nil {
 		return
 	}
@@ -125,25 +126,6 @@ func (s *RenderSystemRL) Update(deltaTime float64) {
 			IsOnScreen: true,
 		}
 	}
-
-	// Обновление таймеров лазеров и эффектов вулкана
-	for id, laser := range s.ecs.Lasers {
-		laser.Timer += deltaTime
-		if laser.Timer >= laser.Duration {
-			delete(s.ecs.Lasers, id)
-		}
-	}
-	for id, effect := range s.ecs.VolcanoEffects {
-		effect.Timer += deltaTime
-		progress := effect.Timer / effect.Duration
-		if progress > 1.0 {
-			progress = 1.0
-		}
-		effect.Radius = effect.MaxRadius * progress
-		if effect.Timer >= effect.Duration {
-			delete(s.ecs.VolcanoEffects, id)
-		}
-	}
 }
 
 // Draw использует кэшированные данные для отрисовки
@@ -264,9 +246,16 @@ func (s *RenderSystemRL) drawSolidEntities() {
 
 		scaledRadius := data.Radius * float32(config.CoordScale)
 
-		if _, isEnemy := s.ecs.Enemies[id]; isEnemy {
+		if enemy, isEnemy := s.ecs.Enemies[id]; isEnemy {
 			pos := data.WorldPos
 			pos.Y = scaledRadius
+			if enemy.Flying {
+				// Летающий враг висит над землей, под ним рисуется тень
+				shadowPos := data.WorldPos
+				shadowPos.Y = 0.05
+				rl.DrawCylinder(shadowPos, scaledRadius, scaledRadius, 0.05, 12, rl.NewColor(0, 0, 0, 90))
+				pos.Y += config.FlyingEnemyHeight
+			}
 			rl.DrawSphere(pos, scaledRadius, finalColor)
 			if renderable.HasStroke {
 				rl.DrawSphereWires(pos, scaledRadius, 8, 8, rl.White)
@@ -514,12 +503,18 @@ func colorToRL(c color.Color) rl.Color {
 
 func (s *RenderSystemRL) drawPulsingOres(gameTime float64) {
 	for id, ore := range s.ecs.Ores {
+		if ore.Depleted {
+			continue // Истощенная руда не видна, пока жила не восстановится
+		}
 		if pos, hasPos := s.ecs.Positions[id]; hasPos {
 			worldPos := s.pixelToWorld(*pos)
 			scaledRadius := float32(ore.Radius * config.CoordScale)
 			pulseRadius := scaledRadius * float32(1+0.1*math.Sin(gameTime*ore.PulseRate*math.Pi/5))
 			pulseAlpha := uint8(128 + 64*math.Sin(gameTime*ore.PulseRate*math.Pi/5))
 			oreColor := config.OreColorRL
+			if oreType, ok := defs.OreTypeDefs[ore.Type]; ok {
+				oreColor = oreType.Color // Цвет по типу руды
+			}
 			oreColor.A = pulseAlpha
 			scaledHeight := float32(0.1 * config.CoordScale)
 			worldPos.Y = scaledHeight/2 + 2.0
@@ -688,3 +683,73 @@ func (s *RenderSystemRL) drawDebugTurretLines() {
 		rl.DrawLine3D(startPos, endPosZ, rl.Blue)
 	}
 }
+
+// networkInspectorPalette — цвета сетей в инспекторе энергосети; повторяются по кругу.
+var networkInspectorPalette = []rl.Color{
+	rl.NewColor(230, 25, 75, 200),
+	rl.NewColor(60, 180, 75, 200),
+	rl.NewColor(0, 130, 200, 200),
+	rl.NewColor(245, 130, 48, 200),
+	rl.NewColor(145, 30, 180, 200),
+	rl.NewColor(70, 240, 240, 200),
+	rl.NewColor(240, 50, 230, 200),
+	rl.NewColor(210, 245, 60, 200),
+}
+
+// NetworkInspectorColor возвращает цвет сети с номером index.
+func NetworkInspectorColor(index int) rl.Color {
+	if index < 0 {
+		return rl.Gray
+	}
+	return networkInspectorPalette[index%len(networkInspectorPalette)]
+}
+
+// DrawNetworkInspector подсвечивает каждую сеть своим цветом (кольцо под башней и
+// линии) и рисует путь выбранной башни до источника энергии.
+func (s *RenderSystemRL) DrawNetworkInspector(networkOf map[types.EntityID]int, path []types.EntityID) {
+	radius := float32(config.HexSize*config.CoordScale) * 0.9
+	for id, network := range networkOf {
+		tower, ok := s.ecs.Towers[id]
+		if !ok {
+			continue
+		}
+		pos := s.hexToWorld(tower.Hex)
+		pos.Y = 0.8
+		rl.DrawCylinder(pos, radius, radius, 0.3, 12, NetworkInspectorColor(network))
+	}
+
+	for _, line := range s.ecs.LineRenders {
+		tower1, ok1 := s.ecs.Towers[line.Tower1ID]
+		tower2, ok2 := s.ecs.Towers[line.Tower2ID]
+		if !ok1 || !ok2 {
+			continue
+		}
+		start, end := s.hexToWorld(tower1.Hex), s.hexToWorld(tower2.Hex)
+		start.Y, end.Y = 1.0, 1.0
+		rl.DrawCapsule(start, end, 0.8, 6, 6, NetworkInspectorColor(networkOf[line.Tower1ID]))
+	}
+
+	rl.DisableDepthTest()
+	defer rl.EnableDepthTest()
+	for i := 1; i < len(path); i++ {
+		tower1, ok1 := s.ecs.Towers[path[i-1]]
+		tower2, ok2 := s.ecs.Towers[path[i]]
+		if !ok1 || !ok2 {
+			continue
+		}
+		start, end := s.hexToWorld(tower1.Hex), s.hexToWorld(tower2.Hex)
+		start.Y, end.Y = 12.0, 12.0
+		rl.DrawCapsule(start, end, 1.2, 6, 6, rl.White)
+	}
+}
+
+// DrawBlockingHexes затеняет гексы, где башня или стена перекрыли бы маршрут врагов.
+func (s *RenderSystemRL) DrawBlockingHexes(hexes []hexmap.Hex) {
+	color := rl.NewColor(170, 30, 30, 60)
+	radius := float32(config.HexSize*config.CoordScale) * 1.05
+	for _, hex := range hexes {
+		pos := s.hexToWorld(hex)
+		pos.Y += 0.7
+		rl.DrawCylinder(pos, radius, radius, 1.2, 6, color)
+	}
+}